@@ -0,0 +1,308 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const unixPrefix = "unix:"
+
+// ErrInvalidServerAddress is returned when a server address can't be parsed.
+var ErrInvalidServerAddress = errors.New("invalid server address")
+
+// ServerAddressKind describes the form of a server address.
+type ServerAddressKind int
+
+const (
+	// ServerAddressHostname is a domain name with an optional port, for example "backend.example.com:8080".
+	ServerAddressHostname ServerAddressKind = iota + 1
+	// ServerAddressIP is an IPv4 or IPv6 address with an optional port, for example "10.0.0.1:8080" or "[::1]:8080".
+	ServerAddressIP
+	// ServerAddressUnix is a UNIX-domain socket path, for example "unix:/tmp/backend.sock".
+	ServerAddressUnix
+	// ServerAddressSRV is a hostname that NGINX resolves via the SRV records of a service,
+	// for example "backend.example.com" with the service "http". SRV addresses never carry a port.
+	ServerAddressSRV
+)
+
+// String returns the name of the kind.
+func (k ServerAddressKind) String() string {
+	switch k {
+	case ServerAddressHostname:
+		return "hostname"
+	case ServerAddressIP:
+		return "ip"
+	case ServerAddressUnix:
+		return "unix"
+	case ServerAddressSRV:
+		return "srv"
+	default:
+		return "unknown"
+	}
+}
+
+// ServerAddress is a parsed and validated address of an upstream server.
+// The zero value is not a valid address; use ParseServerAddress to create one.
+// More info about addresses http://nginx.org/en/docs/http/ngx_http_upstream_module.html#server
+type ServerAddress struct {
+	addr    netip.Addr
+	host    string
+	path    string
+	service string
+	kind    ServerAddressKind
+	port    uint16
+	hasPort bool
+}
+
+// ParseServerAddress parses an address in any of the forms accepted by NGINX:
+// a hostname or an IP address with an optional port, or a UNIX-domain socket prefixed with "unix:".
+// IPv6 addresses may be given with or without square brackets.
+// Use ParseSRVServerAddress for the address of a server with a service parameter.
+func ParseServerAddress(address string) (ServerAddress, error) {
+	if address == "" {
+		return ServerAddress{}, fmt.Errorf("%w: address is empty", ErrInvalidServerAddress)
+	}
+
+	if path, ok := strings.CutPrefix(address, unixPrefix); ok {
+		if path == "" {
+			return ServerAddress{}, fmt.Errorf("%w %q: socket path is empty", ErrInvalidServerAddress, address)
+		}
+		return ServerAddress{kind: ServerAddressUnix, path: path}, nil
+	}
+
+	// A bare IPv6 address such as "::1" can't be told apart from a host and port by the colons,
+	// so try it as an address first.
+	if addr, err := netip.ParseAddr(address); err == nil {
+		return ServerAddress{kind: ServerAddressIP, addr: addr}, nil
+	}
+
+	host, portStr, hasPort, err := splitHostPort(address)
+	if err != nil {
+		return ServerAddress{}, err
+	}
+
+	a := ServerAddress{hasPort: hasPort}
+	if hasPort {
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || port == 0 {
+			return ServerAddress{}, fmt.Errorf("%w %q: invalid port %q", ErrInvalidServerAddress, address, portStr)
+		}
+		a.port = uint16(port)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		a.kind = ServerAddressIP
+		a.addr = addr
+		return a, nil
+	}
+
+	if strings.HasPrefix(host, "[") {
+		return ServerAddress{}, fmt.Errorf("%w %q: invalid IPv6 address", ErrInvalidServerAddress, address)
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if !isValidHostname(host) {
+		return ServerAddress{}, fmt.Errorf("%w %q: invalid hostname", ErrInvalidServerAddress, address)
+	}
+	a.host = host
+	a.kind = ServerAddressHostname
+
+	return a, nil
+}
+
+// ParseSRVServerAddress parses the address of a server with the service parameter, which NGINX resolves via SRV records.
+// Like NGINX, it requires the address to be a hostname without a port.
+// The service is either a plain name such as "http" or a full name such as "_http._tcp".
+func ParseSRVServerAddress(address, service string) (ServerAddress, error) {
+	if !isValidServiceName(service) {
+		return ServerAddress{}, fmt.Errorf("%w %q: invalid service name %q", ErrInvalidServerAddress, address, service)
+	}
+	a, err := ParseServerAddress(address)
+	if err != nil {
+		return ServerAddress{}, err
+	}
+	if a.kind != ServerAddressHostname {
+		return ServerAddress{}, fmt.Errorf("%w %q: must be a hostname when service is set", ErrInvalidServerAddress, address)
+	}
+	if a.hasPort {
+		return ServerAddress{}, fmt.Errorf("%w %q: can't have a port when service is set", ErrInvalidServerAddress, address)
+	}
+	a.kind = ServerAddressSRV
+	a.service = strings.ToLower(service)
+	return a, nil
+}
+
+// splitHostPort splits the address into the host and the optional port.
+// Unlike net.SplitHostPort, the port is optional and the brackets around an IPv6 host are removed.
+func splitHostPort(address string) (host, port string, hasPort bool, err error) {
+	if rest, ok := strings.CutPrefix(address, "["); ok {
+		end := strings.Index(rest, "]")
+		if end == -1 {
+			return "", "", false, fmt.Errorf("%w %q: missing ']'", ErrInvalidServerAddress, address)
+		}
+		host, rest = rest[:end], rest[end+1:]
+		if _, err := netip.ParseAddr(host); err != nil || !strings.Contains(host, ":") {
+			return "", "", false, fmt.Errorf("%w %q: invalid IPv6 address", ErrInvalidServerAddress, address)
+		}
+		if rest == "" {
+			return host, "", false, nil
+		}
+		port, ok = strings.CutPrefix(rest, ":")
+		if !ok {
+			return "", "", false, fmt.Errorf("%w %q: unexpected %q after IPv6 address", ErrInvalidServerAddress, address, rest)
+		}
+		return host, port, true, nil
+	}
+
+	switch strings.Count(address, ":") {
+	case 0:
+		return address, "", false, nil
+	case 1:
+		host, port, _ = strings.Cut(address, ":")
+		return host, port, true, nil
+	default:
+		return "", "", false, fmt.Errorf("%w %q: too many colons", ErrInvalidServerAddress, address)
+	}
+}
+
+// isValidHostname reports whether the name is a valid domain name.
+// Underscores are allowed so that names such as "_http._tcp.example.com" pass.
+func isValidHostname(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for label := range strings.SplitSeq(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Kind returns the form of the address.
+func (a ServerAddress) Kind() ServerAddressKind {
+	return a.kind
+}
+
+// Addr returns the IP address. It is only valid for addresses of kind ServerAddressIP.
+func (a ServerAddress) Addr() netip.Addr {
+	return a.addr
+}
+
+// Host returns the host part of the address: the hostname or the IP address without brackets.
+// It returns an empty string for UNIX-domain sockets.
+func (a ServerAddress) Host() string {
+	if a.kind == ServerAddressIP {
+		return a.addr.String()
+	}
+	return a.host
+}
+
+// Port returns the port and whether the address has one.
+func (a ServerAddress) Port() (uint16, bool) {
+	return a.port, a.hasPort
+}
+
+// Service returns the lowercased service name. It is only valid for addresses of kind ServerAddressSRV.
+func (a ServerAddress) Service() string {
+	return a.service
+}
+
+// Path returns the socket path. It is only valid for addresses of kind ServerAddressUnix.
+func (a ServerAddress) Path() string {
+	return a.path
+}
+
+// WithDefaultPort returns the address with the port set if the address is a hostname or an IP address without a port.
+// UNIX-domain sockets and SRV names are returned unchanged.
+func (a ServerAddress) WithDefaultPort(port uint16) ServerAddress {
+	if a.hasPort || (a.kind != ServerAddressHostname && a.kind != ServerAddressIP) {
+		return a
+	}
+	a.port = port
+	a.hasPort = true
+	return a
+}
+
+// String returns the canonical form of the address as NGINX accepts it.
+// IPv6 addresses are always enclosed in square brackets and hostnames are lowercased.
+func (a ServerAddress) String() string {
+	var host string
+	switch a.kind {
+	case ServerAddressUnix:
+		return unixPrefix + a.path
+	case ServerAddressIP:
+		if a.addr.Is6() {
+			host = "[" + a.addr.String() + "]"
+		} else {
+			host = a.addr.String()
+		}
+	case ServerAddressHostname, ServerAddressSRV:
+		host = a.host
+	default:
+		return ""
+	}
+	if !a.hasPort {
+		return host
+	}
+	return host + ":" + strconv.FormatUint(uint64(a.port), 10)
+}
+
+// Equal reports whether both addresses refer to the same server.
+// Port 80 is assumed for hostnames and IP addresses without a port, as NGINX does.
+func (a ServerAddress) Equal(b ServerAddress) bool {
+	return a.WithDefaultPort(defaultServerPort) == b.WithDefaultPort(defaultServerPort)
+}
+
+// normalizeServerAddress returns the canonical form of the address of a server, assuming port 80 if no port is set.
+// Servers with a service parameter are resolved via SRV records and must not have a port.
+func normalizeServerAddress(server, service string) (string, error) {
+	if service != "" {
+		a, err := ParseSRVServerAddress(server, service)
+		return a.String(), err
+	}
+	a, err := ParseServerAddress(server)
+	if err != nil {
+		return "", err
+	}
+	return a.WithDefaultPort(defaultServerPort).String(), nil
+}
+
+// ServerKey returns a key that is the same for all the servers that UpdateHTTPServers and UpdateStreamServers
+// treat as the same server: the canonical address, with port 80 if no port is set, and the service parameter if set.
+// Addresses that can't be parsed are used as is.
+func ServerKey[S Server](server S) string {
+	var service string
+	switch s := any(server).(type) {
+	case UpstreamServer:
+		service = s.Service
+	case StreamUpstreamServer:
+		service = s.Service
+	}
+	if service == "" {
+		return serverKey(server.Address())
+	}
+	a, err := ParseSRVServerAddress(server.Address(), service)
+	if err != nil {
+		return server.Address() + " service=" + service
+	}
+	return a.String() + " service=" + a.Service()
+}
+
+// serverKey returns a key that is the same for all equivalent spellings of a server address.
+// Addresses that can't be parsed are used as is.
+func serverKey(server string) string {
+	a, err := ParseServerAddress(server)
+	if err != nil {
+		return server
+	}
+	return a.WithDefaultPort(defaultServerPort).String()
+}
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseServerAddress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		address   string
+		canonical string
+		msg       string
+		kind      ServerAddressKind
+	}{
+		{
+			address:   "example.com:8080",
+			canonical: "example.com:8080",
+			kind:      ServerAddressHostname,
+			msg:       "host and port",
+		},
+		{
+			address:   "Example.COM.",
+			canonical: "example.com",
+			kind:      ServerAddressHostname,
+			msg:       "host with uppercase letters and trailing dot",
+		},
+		{
+			address:   "127.0.0.1:8080",
+			canonical: "127.0.0.1:8080",
+			kind:      ServerAddressIP,
+			msg:       "ipv4 and port",
+		},
+		{
+			address:   "[::1]:8080",
+			canonical: "[::1]:8080",
+			kind:      ServerAddressIP,
+			msg:       "ipv6 and port",
+		},
+		{
+			address:   "::1",
+			canonical: "[::1]",
+			kind:      ServerAddressIP,
+			msg:       "bare ipv6",
+		},
+		{
+			address:   "[2001:DB8:0:0::1]",
+			canonical: "[2001:db8::1]",
+			kind:      ServerAddressIP,
+			msg:       "non-canonical ipv6",
+		},
+		{
+			address:   "unix:/path/to/socket",
+			canonical: "unix:/path/to/socket",
+			kind:      ServerAddressUnix,
+			msg:       "unix socket",
+		},
+		{
+			address:   "_http._tcp.example.com",
+			canonical: "_http._tcp.example.com",
+			kind:      ServerAddressHostname,
+			msg:       "hostname with underscores",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			a, err := ParseServerAddress(test.address)
+			if err != nil {
+				t.Fatalf("ParseServerAddress(%v) returned an unexpected error: %v", test.address, err)
+			}
+			if a.Kind() != test.kind {
+				t.Errorf("ParseServerAddress(%v) returned kind %v but expected %v", test.address, a.Kind(), test.kind)
+			}
+			if a.String() != test.canonical {
+				t.Errorf("ParseServerAddress(%v) returned %v but expected %v", test.address, a.String(), test.canonical)
+			}
+		})
+	}
+}
+
+func TestParseServerAddressInvalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		address string
+		msg     string
+	}{
+		{address: "", msg: "empty"},
+		{address: "unix:", msg: "empty socket path"},
+		{address: "example.com:0", msg: "port zero"},
+		{address: "example.com:65536", msg: "port out of range"},
+		{address: "example.com:http", msg: "named port"},
+		{address: "[::1", msg: "missing bracket"},
+		{address: "[127.0.0.1]:80", msg: "ipv4 in brackets"},
+		{address: "[::1]80", msg: "missing colon after bracket"},
+		{address: "exa mple.com", msg: "space in hostname"},
+		{address: "-example.com", msg: "label starts with hyphen"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseServerAddress(test.address)
+			if !errors.Is(err, ErrInvalidServerAddress) {
+				t.Errorf("ParseServerAddress(%q) returned %v but expected %v", test.address, err, ErrInvalidServerAddress)
+			}
+		})
+	}
+}
+
+func TestParseSRVServerAddress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		address   string
+		service   string
+		canonical string
+		msg       string
+		wantErr   bool
+	}{
+		{address: "Backend.example.com", service: "HTTP", canonical: "backend.example.com", msg: "plain service name"},
+		{address: "backend.example.com", service: "_http._tcp", canonical: "backend.example.com", msg: "full service name"},
+		{address: "backend.example.com:80", service: "http", msg: "port", wantErr: true},
+		{address: "10.0.0.1", service: "http", msg: "ip address", wantErr: true},
+		{address: "unix:/path/to/socket", service: "http", msg: "unix socket", wantErr: true},
+		{address: "backend.example.com", service: "_http.tcp", msg: "invalid service name", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			a, err := ParseSRVServerAddress(test.address, test.service)
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidServerAddress) {
+					t.Errorf("ParseSRVServerAddress(%q, %q) returned %v but expected %v", test.address, test.service, err, ErrInvalidServerAddress)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSRVServerAddress(%q, %q) returned an unexpected error: %v", test.address, test.service, err)
+			}
+			if a.Kind() != ServerAddressSRV || a.String() != test.canonical {
+				t.Errorf("ParseSRVServerAddress(%q, %q) returned %v of kind %v but expected %v", test.address, test.service, a, a.Kind(), test.canonical)
+			}
+		})
+	}
+}
+
+func TestServerAddressEqual(t *testing.T) {
+	t.Parallel()
+	parse := func(address, service string) ServerAddress {
+		if service != "" {
+			a, _ := ParseSRVServerAddress(address, service)
+			return a
+		}
+		a, _ := ParseServerAddress(address)
+		return a
+	}
+	tests := []struct {
+		a, b       string
+		aSvc, bSvc string
+		msg        string
+		expected   bool
+	}{
+		{a: "10.0.0.1", b: "10.0.0.1:80", msg: "ipv4 with and without default port", expected: true},
+		{a: "::1", b: "[::1]:80", msg: "ipv6 with and without default port", expected: true},
+		{a: "Backend.example.com", b: "backend.example.com:80", msg: "hostname case", expected: true},
+		{a: "10.0.0.1", b: "10.0.0.1:8080", msg: "different ports"},
+		{a: "backend.example.com", aSvc: "http", b: "backend.example.com", bSvc: "HTTP", msg: "same service", expected: true},
+		{a: "backend.example.com", aSvc: "http", b: "backend.example.com", bSvc: "https", msg: "different services"},
+		{a: "backend.example.com", aSvc: "http", b: "backend.example.com", msg: "service and hostname"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			a, b := parse(test.a, test.aSvc), parse(test.b, test.bSvc)
+			if a.Equal(b) != test.expected || b.Equal(a) != test.expected {
+				t.Errorf("%v.Equal(%v) returned %v but expected %v", test.a, test.b, a.Equal(b), test.expected)
+			}
+		})
+	}
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	t.Parallel()
+	// More info about addresses http://nginx.org/en/docs/http/ngx_http_upstream_module.html#server
+	tests := []struct {
+		address  string
+		expected string
+		msg      string
+	}{
+		{
+			address:  "example.com:8080",
+			expected: "example.com:8080",
+			msg:      "host and port",
+		},
+		{
+			address:  "127.0.0.1:8080",
+			expected: "127.0.0.1:8080",
+			msg:      "ipv4 and port",
+		},
+		{
+			address:  "[::]:8080",
+			expected: "[::]:8080",
+			msg:      "ipv6 and port",
+		},
+		{
+			address:  "unix:/path/to/socket",
+			expected: "unix:/path/to/socket",
+			msg:      "unix socket",
+		},
+		{
+			address:  "example.com",
+			expected: "example.com:80",
+			msg:      "host without port",
+		},
+		{
+			address:  "127.0.0.1",
+			expected: "127.0.0.1:80",
+			msg:      "ipv4 without port",
+		},
+		{
+			address:  "[::]",
+			expected: "[::]:80",
+			msg:      "ipv6 without port",
+		},
+		{
+			address:  "::1",
+			expected: "[::1]:80",
+			msg:      "ipv6 without brackets and port",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			a, err := ParseServerAddress(test.address)
+			if err != nil {
+				t.Fatalf("ParseServerAddress(%v) returned an unexpected error: %v", test.address, err)
+			}
+			if result := a.WithDefaultPort(80).String(); result != test.expected {
+				t.Errorf("WithDefaultPort(80) of %v returned %v but expected %v", test.address, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestServerKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b string
+		msg  string
+	}{
+		{a: "[::1]:80", b: "::1", msg: "ipv6 with and without default port"},
+		{a: "10.0.0.1", b: "10.0.0.1:80", msg: "ipv4 with and without default port"},
+		{a: "Backend.example.com", b: "backend.example.com:80", msg: "hostname case"},
+		{a: "[2001:db8::1]:8080", b: "[2001:DB8:0::1]:8080", msg: "ipv6 spelling"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			if serverKey(test.a) != serverKey(test.b) {
+				t.Errorf("serverKey(%v) = %v and serverKey(%v) = %v are not equal", test.a, serverKey(test.a), test.b, serverKey(test.b))
+			}
+		})
+	}
+}
+
+func TestServerKeyWithService(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b     UpstreamServer
+		msg      string
+		expected bool
+	}{
+		{
+			a:        UpstreamServer{Server: "backend.example.com", Service: "http"},
+			b:        UpstreamServer{Server: "Backend.example.com.", Service: "HTTP"},
+			msg:      "same service",
+			expected: true,
+		},
+		{
+			a:   UpstreamServer{Server: "backend.example.com", Service: "http"},
+			b:   UpstreamServer{Server: "backend.example.com", Service: "https"},
+			msg: "different services",
+		},
+		{
+			a:   UpstreamServer{Server: "backend.example.com", Service: "http"},
+			b:   UpstreamServer{Server: "backend.example.com"},
+			msg: "service and no service",
+		},
+		{
+			a:        UpstreamServer{Server: "10.0.0.1"},
+			b:        UpstreamServer{Server: "10.0.0.1:80"},
+			msg:      "no service",
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			if equal := ServerKey(test.a) == ServerKey(test.b); equal != test.expected {
+				t.Errorf("ServerKey(%+v) = %v and ServerKey(%+v) = %v, expected equal %v", test.a, ServerKey(test.a), test.b, ServerKey(test.b), test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package client
+
+import (
+	"reflect"
+	"slices"
+)
+
+// peerStateSeverity orders the states of peers from the healthiest to the least healthy.
+var peerStateSeverity = map[string]int{
+	"up":        0,
+	"draining":  1,
+	"down":      2,
+	"checking":  3,
+	"unhealthy": 4,
+	"unavail":   5,
+}
+
+// AggregatedStats are the Stats of many NGINX Plus instances merged by MergeStats.
+// The embedded Stats are the merged stats and Instances are the stats of each instance.
+type AggregatedStats struct {
+	Instances map[string]*Stats
+	Stats
+}
+
+// MergeStats merges the stats of NGINX Plus instances by instance name into the stats of all of them.
+// Counters of the zones, upstreams, caches, limits, resolvers, connections and requests are added.
+// Zones are merged by name and the peers of upstreams by upstream, Server and Name.
+//
+// Fields that can't be added are merged as follows:
+//   - State of a peer is the state of the least healthy instance, and LastPassed of its health checks is true only if it is on all instances.
+//   - Weight, MaxConns, Backup and ID of a peer are the values of the first instance, by instance name, that has the peer.
+//   - HeaderTime, ResponseTime, ConnectTime and FirstByteTime, which are averages, are weighted by the requests or connections of the instances.
+//   - Downtime is the longest downtime of the peer, Selected the latest and Downstart the earliest.
+//   - RecordsTotal and NodesOnline of zone sync are the highest values, because every node has the records of the zones.
+//   - Cold of a cache is true if the cache is cold on any instance.
+//   - Version and Build of NginxInfo are set only if all the instances have the same ones, and the other fields of NginxInfo are not set.
+//   - Workers are the workers of all the instances.
+//
+// Instances that are nil are ignored.
+func MergeStats(stats map[string]*Stats) *AggregatedStats {
+	names := make([]string, 0, len(stats))
+	for name, s := range stats {
+		if s != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	instances := make([]*Stats, len(names))
+	aggregated := &AggregatedStats{Instances: make(map[string]*Stats, len(names))}
+	for i, name := range names {
+		instances[i] = stats[name]
+		aggregated.Instances[name] = stats[name]
+	}
+
+	merged := &aggregated.Stats
+	merged.Upstreams = mergeZones(instances, func(s *Stats) Upstreams { return s.Upstreams }, mergeUpstreams)
+	merged.StreamUpstreams = mergeZones(instances, func(s *Stats) StreamUpstreams { return s.StreamUpstreams }, mergeStreamUpstreams)
+	merged.ServerZones = mergeZones(instances, func(s *Stats) ServerZones { return s.ServerZones }, sumCounters[ServerZone])
+	merged.StreamServerZones = mergeZones(instances, func(s *Stats) StreamServerZones { return s.StreamServerZones }, sumCounters[StreamServerZone])
+	merged.LocationZones = mergeZones(instances, func(s *Stats) LocationZones { return s.LocationZones }, sumCounters[LocationZone])
+	merged.Slabs = mergeZones(instances, func(s *Stats) Slabs { return s.Slabs }, mergeSlabs)
+	merged.Caches = mergeZones(instances, func(s *Stats) Caches { return s.Caches }, mergeCaches)
+	merged.HTTPLimitConnections = mergeZones(instances, func(s *Stats) HTTPLimitConnections { return s.HTTPLimitConnections }, sumCounters[LimitConnection])
+	merged.StreamLimitConnections = mergeZones(instances, func(s *Stats) StreamLimitConnections { return s.StreamLimitConnections }, sumCounters[LimitConnection])
+	merged.HTTPLimitRequests = mergeZones(instances, func(s *Stats) HTTPLimitRequests { return s.HTTPLimitRequests }, sumCounters[HTTPLimitRequest])
+	merged.Resolvers = mergeZones(instances, func(s *Stats) Resolvers { return s.Resolvers }, sumCounters[Resolver])
+	merged.StreamZoneSync = mergeZoneSync(instances)
+	merged.Workers = []*Workers{}
+	var infos []NginxInfo
+	var ssl []SSL
+	var connections []Connections
+	var requests []HTTPRequests
+	var processes []Processes
+	for _, s := range instances {
+		merged.Workers = append(merged.Workers, s.Workers...)
+		infos = append(infos, s.NginxInfo)
+		ssl = append(ssl, s.SSL)
+		connections = append(connections, s.Connections)
+		requests = append(requests, s.HTTPRequests)
+		processes = append(processes, s.Processes)
+	}
+	merged.NginxInfo = mergeNginxInfo(infos)
+	merged.SSL = sumCounters(ssl)
+	merged.Connections = sumCounters(connections)
+	merged.HTTPRequests = sumCounters(requests)
+	merged.Processes = sumCounters(processes)
+	return aggregated
+}
+
+// Peers returns the stats of the peer of the HTTP upstream with the server and name on each instance that has it.
+func (a *AggregatedStats) Peers(upstream, server, name string) map[string]Peer {
+	peers := make(map[string]Peer)
+	for instance, s := range a.Instances {
+		for _, p := range s.Upstreams[upstream].Peers {
+			if p.Server == server && p.Name == name {
+				peers[instance] = p
+			}
+		}
+	}
+	return peers
+}
+
+// StreamPeers returns the stats of the peer of the stream upstream with the server and name on each instance that has it.
+func (a *AggregatedStats) StreamPeers(upstream, server, name string) map[string]StreamPeer {
+	peers := make(map[string]StreamPeer)
+	for instance, s := range a.Instances {
+		for _, p := range s.StreamUpstreams[upstream].Peers {
+			if p.Server == server && p.Name == name {
+				peers[instance] = p
+			}
+		}
+	}
+	return peers
+}
+
+// mergeZones merges the zones of the instances with the same name.
+func mergeZones[M ~map[string]V, V any](instances []*Stats, zones func(*Stats) M, merge func([]V) V) M {
+	byName := make(map[string][]V)
+	for _, s := range instances {
+		for name, zone := range zones(s) {
+			byName[name] = append(byName[name], zone)
+		}
+	}
+	merged := make(M, len(byName))
+	for name, values := range byName {
+		merged[name] = merge(values)
+	}
+	return merged
+}
+
+type peerKey struct {
+	server string
+	name   string
+}
+
+// mergePeers merges the peers of the instances with the same key, in the order the peers are first seen.
+func mergePeers[P any](peers [][]P, key func(P) peerKey, merge func([]P) P) []P {
+	var keys []peerKey
+	byKey := make(map[peerKey][]P)
+	for _, instancePeers := range peers {
+		for _, p := range instancePeers {
+			k := key(p)
+			if _, ok := byKey[k]; !ok {
+				keys = append(keys, k)
+			}
+			byKey[k] = append(byKey[k], p)
+		}
+	}
+	merged := make([]P, 0, len(keys))
+	for _, k := range keys {
+		merged = append(merged, merge(byKey[k]))
+	}
+	return merged
+}
+
+func mergeUpstreams(upstreams []Upstream) Upstream {
+	merged := sumCounters(upstreams)
+	peers := make([][]Peer, len(upstreams))
+	for i, u := range upstreams {
+		peers[i] = u.Peers
+	}
+	merged.Peers = mergePeers(peers, func(p Peer) peerKey { return peerKey{server: p.Server, name: p.Name} }, mergePeer)
+	return merged
+}
+
+func mergeStreamUpstreams(upstreams []StreamUpstream) StreamUpstream {
+	merged := sumCounters(upstreams)
+	peers := make([][]StreamPeer, len(upstreams))
+	for i, u := range upstreams {
+		peers[i] = u.Peers
+	}
+	merged.Peers = mergePeers(peers, func(p StreamPeer) peerKey { return peerKey{server: p.Server, name: p.Name} }, mergeStreamPeer)
+	return merged
+}
+
+func mergePeer(peers []Peer) Peer {
+	merged := sumCounters(peers)
+	first := peers[0]
+	merged.ID, merged.Weight, merged.MaxConns, merged.Backup = first.ID, first.Weight, first.MaxConns, first.Backup
+	merged.Downtime = 0
+	merged.HeaderTime = weightedAverage(peers, func(p Peer) (uint64, uint64) { return p.HeaderTime, p.Requests })
+	merged.ResponseTime = weightedAverage(peers, func(p Peer) (uint64, uint64) { return p.ResponseTime, p.Requests })
+	for _, p := range peers {
+		merged.State = leastHealthy(merged.State, p.State)
+		merged.Downtime = max(merged.Downtime, p.Downtime)
+		merged.HealthChecks.LastPassed = merged.HealthChecks.LastPassed && p.HealthChecks.LastPassed
+		merged.Selected = max(merged.Selected, p.Selected)
+		merged.Downstart = earliest(merged.Downstart, p.Downstart)
+	}
+	return merged
+}
+
+func mergeStreamPeer(peers []StreamPeer) StreamPeer {
+	merged := sumCounters(peers)
+	first := peers[0]
+	merged.ID, merged.Weight, merged.MaxConns, merged.Backup = first.ID, first.Weight, first.MaxConns, first.Backup
+	merged.Downtime = 0
+	merged.ConnectTime = int(weightedAverage(peers, func(p StreamPeer) (uint64, uint64) { return uint64(max(p.ConnectTime, 0)), p.Connections }))
+	merged.FirstByteTime = int(weightedAverage(peers, func(p StreamPeer) (uint64, uint64) { return uint64(max(p.FirstByteTime, 0)), p.Connections }))
+	merged.ResponseTime = weightedAverage(peers, func(p StreamPeer) (uint64, uint64) { return p.ResponseTime, p.Connections })
+	for _, p := range peers {
+		merged.State = leastHealthy(merged.State, p.State)
+		merged.Downtime = max(merged.Downtime, p.Downtime)
+		merged.HealthChecks.LastPassed = merged.HealthChecks.LastPassed && p.HealthChecks.LastPassed
+		merged.Selected = max(merged.Selected, p.Selected)
+		merged.Downstart = earliest(merged.Downstart, p.Downstart)
+	}
+	return merged
+}
+
+func mergeSlabs(slabs []Slab) Slab {
+	merged := sumCounters(slabs)
+	merged.Slots = make(Slots)
+	for _, slab := range slabs {
+		for size, slot := range slab.Slots {
+			merged.Slots[size] = sumCounters([]Slot{merged.Slots[size], slot})
+		}
+	}
+	return merged
+}
+
+func mergeCaches(caches []HTTPCache) HTTPCache {
+	merged := sumCounters(caches)
+	for _, c := range caches {
+		merged.Cold = merged.Cold || c.Cold
+	}
+	return merged
+}
+
+func mergeZoneSync(instances []*Stats) *StreamZoneSync {
+	var merged *StreamZoneSync
+	for _, s := range instances {
+		if s.StreamZoneSync == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &StreamZoneSync{Zones: make(map[string]SyncZone)}
+		}
+		for name, zone := range s.StreamZoneSync.Zones {
+			m := merged.Zones[name]
+			m.RecordsPending += zone.RecordsPending
+			m.RecordsTotal = max(m.RecordsTotal, zone.RecordsTotal)
+			merged.Zones[name] = m
+		}
+		nodesOnline := max(merged.Status.NodesOnline, s.StreamZoneSync.Status.NodesOnline)
+		merged.Status = sumCounters([]StreamZoneSyncStatus{merged.Status, s.StreamZoneSync.Status})
+		merged.Status.NodesOnline = nodesOnline
+	}
+	return merged
+}
+
+func mergeNginxInfo(infos []NginxInfo) NginxInfo {
+	var merged NginxInfo
+	if len(infos) == 0 {
+		return merged
+	}
+	merged.Version, merged.Build = infos[0].Version, infos[0].Build
+	for _, info := range infos {
+		if info.Version != merged.Version {
+			merged.Version = ""
+		}
+		if info.Build != merged.Build {
+			merged.Build = ""
+		}
+	}
+	return merged
+}
+
+// sumCounters returns the first value with the integer fields, including those of nested structs, added up over all the values.
+// The other fields are those of the first value.
+func sumCounters[T any](values []T) T {
+	var total T
+	if len(values) == 0 {
+		return total
+	}
+	total = values[0]
+	dst := reflect.ValueOf(&total).Elem()
+	for _, v := range values[1:] {
+		addCounters(dst, reflect.ValueOf(v))
+	}
+	return total
+}
+
+func addCounters(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		for i := range dst.NumField() {
+			if dst.Field(i).CanSet() {
+				addCounters(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(dst.Int() + src.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dst.SetUint(dst.Uint() + src.Uint())
+	default:
+	}
+}
+
+// weightedAverage returns the average of the values weighted by the weights.
+func weightedAverage[T any](items []T, valueAndWeight func(T) (uint64, uint64)) uint64 {
+	var sum, weights uint64
+	for _, item := range items {
+		value, weight := valueAndWeight(item)
+		sum += value * weight
+		weights += weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+func leastHealthy(a, b string) string {
+	if peerStateSeverity[b] > peerStateSeverity[a] {
+		return b
+	}
+	return a
+}
+
+// earliest returns the earliest of the timestamps, ignoring empty ones.
+func earliest(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return min(a, b)
+}
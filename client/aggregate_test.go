@@ -0,0 +1,96 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeStats(t *testing.T) {
+	t.Parallel()
+	a := &Stats{
+		NginxInfo:   NginxInfo{Version: "1.27.4", Build: "nginx-plus-r34", Generation: 3},
+		Connections: Connections{Accepted: 10, Active: 2},
+		ServerZones: ServerZones{"zone": {Requests: 5, Responses: Responses{Responses5xx: 1, Codes: HTTPCodes{HTTPInternalServerError: 1}}}},
+		Upstreams: Upstreams{"backend": {
+			Zone:  "backend",
+			Queue: Queue{MaxSize: 10},
+			Peers: []Peer{
+				{ID: 0, Server: "10.0.0.1:80", Name: "10.0.0.1:80", State: "up", Weight: 2, Requests: 30, ResponseTime: 10, Downtime: 5, HealthChecks: HealthChecks{Checks: 1, LastPassed: true}},
+			},
+		}},
+		Slabs:          Slabs{"zone": {Pages: Pages{Used: 1}, Slots: Slots{"8": {Used: 1}}}},
+		Caches:         Caches{"cache": {Size: 100, Hit: CacheStats{Responses: 1}}},
+		StreamZoneSync: &StreamZoneSync{Zones: map[string]SyncZone{"zone": {RecordsPending: 1, RecordsTotal: 10}}, Status: StreamZoneSyncStatus{MsgsIn: 1, NodesOnline: 2}},
+		Workers:        []*Workers{{ID: 0}},
+	}
+	b := &Stats{
+		NginxInfo:   NginxInfo{Version: "1.27.4", Build: "nginx-plus-r35", Generation: 1},
+		Connections: Connections{Accepted: 5, Active: 1},
+		ServerZones: ServerZones{"zone": {Requests: 2, Responses: Responses{Responses5xx: 2, Codes: HTTPCodes{HTTPInternalServerError: 2}}}, "other": {Requests: 1}},
+		Upstreams: Upstreams{"backend": {
+			Zone:  "backend",
+			Queue: Queue{MaxSize: 10},
+			Peers: []Peer{
+				{ID: 3, Server: "10.0.0.2:80", Name: "10.0.0.2:80", State: "up"},
+				{ID: 4, Server: "10.0.0.1:80", Name: "10.0.0.1:80", State: "unhealthy", Weight: 5, Requests: 10, ResponseTime: 50, Downtime: 7, HealthChecks: HealthChecks{Checks: 2}},
+			},
+		}},
+		Slabs:          Slabs{"zone": {Pages: Pages{Used: 2}, Slots: Slots{"8": {Used: 2}, "16": {Used: 1}}}},
+		Caches:         Caches{"cache": {Size: 50, Cold: true, Hit: CacheStats{Responses: 2}}},
+		StreamZoneSync: &StreamZoneSync{Zones: map[string]SyncZone{"zone": {RecordsPending: 2, RecordsTotal: 10}}, Status: StreamZoneSyncStatus{MsgsIn: 2, NodesOnline: 2}},
+		Workers:        []*Workers{{ID: 0}},
+	}
+
+	merged := MergeStats(map[string]*Stats{"b": b, "a": a, "c": nil})
+
+	if len(merged.Instances) != 2 || merged.Instances["a"] != a || merged.Instances["b"] != b {
+		t.Fatalf("unexpected instances %v", merged.Instances)
+	}
+	if expected := (NginxInfo{Version: "1.27.4"}); merged.NginxInfo != expected {
+		t.Errorf("expected NginxInfo %+v, got %+v", expected, merged.NginxInfo)
+	}
+	if expected := (Connections{Accepted: 15, Active: 3}); merged.Connections != expected {
+		t.Errorf("expected connections %+v, got %+v", expected, merged.Connections)
+	}
+	zone := merged.ServerZones["zone"]
+	if zone.Requests != 7 || zone.Responses.Responses5xx != 3 || zone.Responses.Codes.HTTPInternalServerError != 3 || merged.ServerZones["other"].Requests != 1 {
+		t.Errorf("unexpected server zones %+v", merged.ServerZones)
+	}
+
+	upstream := merged.Upstreams["backend"]
+	if upstream.Zone != "backend" || upstream.Queue.MaxSize != 20 || len(upstream.Peers) != 2 {
+		t.Fatalf("unexpected upstream %+v", upstream)
+	}
+	expectedPeer := Peer{
+		ID: 0, Server: "10.0.0.1:80", Name: "10.0.0.1:80", State: "unhealthy", Weight: 2, Requests: 40,
+		ResponseTime: 20, Downtime: 7, HealthChecks: HealthChecks{Checks: 3, LastPassed: false},
+	}
+	if !reflect.DeepEqual(upstream.Peers[0], expectedPeer) {
+		t.Errorf("expected peer %+v, got %+v", expectedPeer, upstream.Peers[0])
+	}
+	if upstream.Peers[1].Server != "10.0.0.2:80" || upstream.Peers[1].ID != 3 {
+		t.Errorf("unexpected peer %+v", upstream.Peers[1])
+	}
+	if peers := merged.Peers("backend", "10.0.0.1:80", "10.0.0.1:80"); len(peers) != 2 || peers["b"].Weight != 5 {
+		t.Errorf("unexpected peers of instances %+v", peers)
+	}
+
+	if expected := (Slab{Pages: Pages{Used: 3}, Slots: Slots{"8": {Used: 3}, "16": {Used: 1}}}); !reflect.DeepEqual(merged.Slabs["zone"], expected) {
+		t.Errorf("expected slab %+v, got %+v", expected, merged.Slabs["zone"])
+	}
+	if cache := merged.Caches["cache"]; cache.Size != 150 || !cache.Cold || cache.Hit.Responses != 3 {
+		t.Errorf("unexpected cache %+v", cache)
+	}
+	expectedSync := &StreamZoneSync{Zones: map[string]SyncZone{"zone": {RecordsPending: 3, RecordsTotal: 10}}, Status: StreamZoneSyncStatus{MsgsIn: 3, NodesOnline: 2}}
+	if !reflect.DeepEqual(merged.StreamZoneSync, expectedSync) {
+		t.Errorf("expected zone sync %+v, got %+v", expectedSync, merged.StreamZoneSync)
+	}
+	if len(merged.Workers) != 2 {
+		t.Errorf("expected the workers of both instances, got %v", len(merged.Workers))
+	}
+
+	// The stats of the instances are not changed.
+	if a.Connections.Accepted != 10 || a.Upstreams["backend"].Peers[0].Requests != 30 || a.Slabs["zone"].Slots["8"].Used != 1 {
+		t.Errorf("the stats of an instance were changed: %+v", a)
+	}
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditAction is the kind of change recorded in an AuditRecord.
+type AuditAction string
+
+const (
+	// AuditAddServer is recorded when a server is added to an upstream.
+	AuditAddServer AuditAction = "add_server"
+	// AuditUpdateServer is recorded when the parameters of a server are updated.
+	AuditUpdateServer AuditAction = "update_server"
+	// AuditDeleteServer is recorded when a server is removed from an upstream.
+	AuditDeleteServer AuditAction = "delete_server"
+	// AuditAddKeyVal is recorded when a key-value pair is added to a zone.
+	AuditAddKeyVal AuditAction = "add_keyval"
+	// AuditModifyKeyVal is recorded when the value of a key is modified.
+	AuditModifyKeyVal AuditAction = "modify_keyval"
+	// AuditDeleteKeyVal is recorded when a key-value pair is deleted.
+	AuditDeleteKeyVal AuditAction = "delete_keyval"
+	// AuditDeleteKeyVals is recorded when all the key-value pairs of a zone are deleted.
+	AuditDeleteKeyVals AuditAction = "delete_keyvals"
+	// AuditUpdateServers is recorded when the client rejects an update of all the servers of an upstream
+	// before changing any server. The servers that are changed are recorded with the other actions.
+	AuditUpdateServers AuditAction = "update_servers"
+)
+
+// AuditRecord describes a change the client made or attempted to make to NGINX.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Before is the state before the change, if the client fetched it, for example the UpstreamServer returned by GetHTTPServers.
+	Before any `json:"before,omitempty"`
+	// After is the state requested by the change. It is empty for deletions.
+	After any `json:"after,omitempty"`
+	// Operation is the NginxClient method that made the change, for example UpdateHTTPServers.
+	Operation string      `json:"operation"`
+	Action    AuditAction `json:"action"`
+	// Context is either "http" or "stream".
+	Context  string `json:"context"`
+	Caller   string `json:"caller,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
+	Server   string `json:"server,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+	Key      string `json:"key,omitempty"`
+	// Error is the error returned by the API, or by the client if it rejected the change before sending it.
+	// It is empty for successful changes.
+	Error   string `json:"error,omitempty"`
+	Success bool   `json:"success"`
+}
+
+// AuditSink receives a record of every change the client makes or attempts to make:
+// servers added, updated and deleted, and key-value pairs added, modified and deleted.
+// Changes the client rejects without sending them to NGINX are recorded too, for example changes that are not permitted,
+// invalid servers and changes that violate the update guards.
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// WithAuditSink sets the sink that receives a record of every change made by the client.
+// Errors returned by the sink are logged and don't fail the change.
+func WithAuditSink(sink AuditSink) Option {
+	return func(o *NginxClient) {
+		o.auditSink = sink
+	}
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context that identifies the caller in the audit records of the changes made with it.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller set with WithCaller.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// audit completes the record and passes it to the audit sink, if there is one.
+func (client *NginxClient) audit(ctx context.Context, record AuditRecord, err error) {
+	if client.auditSink == nil {
+		return
+	}
+
+	record.Time = time.Now().UTC()
+	record.Caller = CallerFromContext(ctx)
+	if op, ok := OperationFromContext(ctx); ok {
+		record.Operation = op.Name
+	}
+	record.Success = err == nil
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if sinkErr := client.auditSink.Record(ctx, record); sinkErr != nil {
+		client.logger.ErrorContext(ctx, "failed to record audit record",
+			slog.String("action", string(record.Action)), slog.Any("error", sinkErr))
+	}
+}
+
+// rejectedActions are the actions of the operations that change NGINX, by operation name.
+var rejectedActions = map[string]AuditAction{
+	"AddHTTPServer":            AuditAddServer,
+	"AddStreamServer":          AuditAddServer,
+	"UpdateHTTPServer":         AuditUpdateServer,
+	"UpdateStreamServer":       AuditUpdateServer,
+	"DeleteHTTPServer":         AuditDeleteServer,
+	"DeleteHTTPServerByID":     AuditDeleteServer,
+	"DeleteStreamServer":       AuditDeleteServer,
+	"DeleteStreamServerByID":   AuditDeleteServer,
+	"UpdateHTTPServers":        AuditUpdateServers,
+	"UpdateStreamServers":      AuditUpdateServers,
+	"ReconcileHTTPServers":     AuditUpdateServers,
+	"ReconcileStreamServers":   AuditUpdateServers,
+	"AddKeyValPair":            AuditAddKeyVal,
+	"AddStreamKeyValPair":      AuditAddKeyVal,
+	"ModifyKeyValPair":         AuditModifyKeyVal,
+	"ModifyStreamKeyValPair":   AuditModifyKeyVal,
+	"DeleteKeyValuePair":       AuditDeleteKeyVal,
+	"DeleteStreamKeyValuePair": AuditDeleteKeyVal,
+	"DeleteKeyValPairs":        AuditDeleteKeyVals,
+	"DeleteStreamKeyValPairs":  AuditDeleteKeyVals,
+}
+
+// auditRejected records an operation that the client rejected before running it, for example because it's not permitted.
+func (client *NginxClient) auditRejected(ctx context.Context, op *Operation, err error) {
+	action, ok := rejectedActions[op.Name]
+	if !ok {
+		return
+	}
+	record := AuditRecord{
+		Action:   action,
+		Context:  contextName(strings.Contains(op.Name, "Stream")),
+		Upstream: op.Upstream,
+		Server:   op.Server,
+		Zone:     op.Zone,
+		Key:      op.Key,
+		After:    op.Request,
+	}
+	if pairs, ok := op.Request.(KeyValPairs); ok {
+		record.After = pairs[op.Key]
+	}
+	client.audit(context.WithValue(ctx, operationKey{}, op), record, err)
+}
+
+// auditKeyVals records a change of key-value pairs, one record per key.
+func (client *NginxClient) auditKeyVals(ctx context.Context, action AuditAction, zone string, keyvals KeyValPairs, stream bool, err error) {
+	for key, val := range keyvals {
+		client.audit(ctx, AuditRecord{Action: action, Context: contextName(stream), Zone: zone, Key: key, After: val}, err)
+	}
+}
+
+func contextName(stream bool) string {
+	if stream {
+		return "stream"
+	}
+	return "http"
+}
+
+// JSONLinesAuditSink writes audit records as JSON objects, one per line.
+type JSONLinesAuditSink struct {
+	w      io.Writer
+	closer io.Closer
+	mu     sync.Mutex
+}
+
+// NewJSONLinesAuditSink creates a JSONLinesAuditSink that writes to w.
+func NewJSONLinesAuditSink(w io.Writer) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{w: w}
+}
+
+// OpenJSONLinesAuditFile creates a JSONLinesAuditSink that appends to the file, creating it if needed.
+func OpenJSONLinesAuditFile(name string) (*JSONLinesAuditSink, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &JSONLinesAuditSink{w: f, closer: f}, nil
+}
+
+// Record writes the record.
+func (s *JSONLinesAuditSink) Record(_ context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file opened by OpenJSONLinesAuditFile. It does nothing for other writers.
+func (s *JSONLinesAuditSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	if err := s.closer.Close(); err != nil {
+		return fmt.Errorf("failed to close audit file: %w", err)
+	}
+	return nil
+}
+
+// MemoryAuditSink keeps audit records in memory, for example for tests.
+type MemoryAuditSink struct {
+	records []AuditRecord
+	mu      sync.Mutex
+}
+
+// Record keeps the record.
+func (s *MemoryAuditSink) Record(_ context.Context, record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// Records returns the records kept so far.
+func (s *MemoryAuditSink) Records() []AuditRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.records)
+}
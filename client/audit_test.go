@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`[{"id": 1, "server": "10.0.0.1:80"}, {"id": 2, "server": "10.0.0.3:80"}]`))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"status":400,"text":"invalid","code":"UpstreamConfFormatError"}}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	sink := &MemoryAuditSink{}
+	c, err := NewNginxClient(ts.URL, WithAuditSink(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weight := 5
+	servers := []UpstreamServer{{Server: "10.0.0.2"}, {Server: "10.0.0.3", Weight: &weight}}
+	ctx := WithCaller(context.Background(), "alice")
+	_, _, _, err = c.UpdateHTTPServers(ctx, "foo", servers)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	records := sink.Records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+
+	tests := []struct {
+		before  any
+		after   any
+		action  AuditAction
+		server  string
+		success bool
+	}{
+		{
+			action:  AuditAddServer,
+			server:  "10.0.0.2:80",
+			after:   UpstreamServer{Server: "10.0.0.2:80"},
+			success: false,
+		},
+		{
+			action:  AuditDeleteServer,
+			server:  "10.0.0.1:80",
+			before:  UpstreamServer{ID: 1, Server: "10.0.0.1:80"},
+			success: true,
+		},
+		{
+			action:  AuditUpdateServer,
+			server:  "10.0.0.3:80",
+			before:  UpstreamServer{ID: 2, Server: "10.0.0.3:80"},
+			after:   UpstreamServer{ID: 2, Server: "10.0.0.3:80", Weight: &weight},
+			success: true,
+		},
+	}
+	for i, test := range tests {
+		record := records[i]
+		if record.Action != test.action || record.Server != test.server || record.Success != test.success {
+			t.Errorf("unexpected record %+v, expected %+v", record, test)
+		}
+		if record.Caller != "alice" || record.Operation != "UpdateHTTPServers" || record.Context != "http" || record.Upstream != "foo" {
+			t.Errorf("unexpected record %+v", record)
+		}
+		if !equalJSON(t, record.Before, test.before) || !equalJSON(t, record.After, test.after) {
+			t.Errorf("unexpected state in record %+v, expected %+v", record, test)
+		}
+		if !test.success && !strings.Contains(record.Error, "UpstreamConfFormatError") {
+			t.Errorf("expected the API error in record %+v", record)
+		}
+	}
+}
+
+func TestAuditKeyVals(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	sink := &MemoryAuditSink{}
+	c, err := NewNginxClient(ts.URL, WithAuditSink(sink))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := c.ModifyStreamKeyValPair(ctx, "zone", "key", "val"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteKeyValuePair(ctx, "zone", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteKeyValPairs(ctx, ""); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	records := sink.Records()
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	if r := records[0]; r.Action != AuditModifyKeyVal || r.Context != "stream" || r.Zone != "zone" || r.Key != "key" || r.After != "val" || !r.Success {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if r := records[1]; r.Action != AuditDeleteKeyVal || r.Context != "http" || r.Operation != "DeleteKeyValuePair" || r.After != nil {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
+
+func TestAuditRejected(t *testing.T) {
+	t.Parallel()
+	invalid := -1
+	tests := []struct {
+		call     func(context.Context, *NginxClient) error
+		msg      string
+		expected AuditRecord
+		options  []Option
+		reason   error
+	}{
+		{
+			msg:     "read-only client",
+			options: []Option{WithReadOnly()},
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.AddHTTPServer(ctx, "foo", UpstreamServer{Server: "10.0.0.2:80"})
+			},
+			expected: AuditRecord{
+				Operation: "AddHTTPServer", Action: AuditAddServer, Context: "http", Upstream: "foo", Server: "10.0.0.2:80",
+				After: UpstreamServer{Server: "10.0.0.2:80"},
+			},
+			reason: ErrOperationNotPermitted,
+		},
+		{
+			msg:     "zone not in allowlist",
+			options: []Option{WithKeyValZoneAllowlist("zone")},
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.ModifyStreamKeyValPair(ctx, "other", "key", "val")
+			},
+			expected: AuditRecord{Operation: "ModifyStreamKeyValPair", Action: AuditModifyKeyVal, Context: "stream", Zone: "other", Key: "key", After: "val"},
+			reason:   ErrOperationNotPermitted,
+		},
+		{
+			msg: "invalid server",
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.UpdateStreamServer(ctx, "foo", StreamUpstreamServer{ID: 1, Server: "10.0.0.1:53", MaxFails: &invalid})
+			},
+			expected: AuditRecord{
+				Operation: "UpdateStreamServer", Action: AuditUpdateServer, Context: "stream", Upstream: "foo", Server: "10.0.0.1:53",
+				After: StreamUpstreamServer{ID: 1, Server: "10.0.0.1:53", MaxFails: &invalid},
+			},
+			reason: ErrInvalidServer,
+		},
+		{
+			msg: "invalid servers of an upstream",
+			call: func(ctx context.Context, c *NginxClient) error {
+				_, _, _, err := c.UpdateHTTPServers(ctx, "foo", []UpstreamServer{{Server: "10.0.0.2:80", MaxFails: &invalid}})
+				return err
+			},
+			expected: AuditRecord{
+				Operation: "UpdateHTTPServers", Action: AuditUpdateServers, Context: "http", Upstream: "foo",
+				After: []UpstreamServer{{Server: "10.0.0.2:80", MaxFails: &invalid}},
+			},
+			reason: ErrInvalidServer,
+		},
+		{
+			msg:     "guard violated",
+			options: []Option{WithUpdateGuards(UpdateGuards{})},
+			call: func(ctx context.Context, c *NginxClient) error {
+				_, _, _, err := c.UpdateHTTPServers(ctx, "foo", nil)
+				return err
+			},
+			expected: AuditRecord{
+				Operation: "UpdateHTTPServers", Action: AuditUpdateServers, Context: "http", Upstream: "foo",
+				Before: []UpstreamServer{{ID: 1, Server: "10.0.0.1:80"}},
+			},
+			reason: ErrGuardViolated,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Errorf("unexpected %v request to %v", r.Method, r.URL)
+				}
+				_, _ = w.Write([]byte(`[{"id": 1, "server": "10.0.0.1:80"}]`))
+			}))
+			defer ts.Close()
+
+			sink := &MemoryAuditSink{}
+			c, err := NewNginxClient(ts.URL, append(test.options, WithAuditSink(sink))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = test.call(context.Background(), c)
+			if !errors.Is(err, test.reason) {
+				t.Fatalf("expected %v, got %v", test.reason, err)
+			}
+			records := sink.Records()
+			if len(records) != 1 {
+				t.Fatalf("expected one record, got %+v", records)
+			}
+			record := records[0]
+			if record.Success || record.Error != err.Error() {
+				t.Errorf("expected the record of the error %v, got %+v", err, record)
+			}
+			record.Time, record.Success, record.Error = time.Time{}, false, ""
+			before, after := record.Before, record.After
+			record.Before, record.After = nil, nil
+			expected := test.expected
+			expected.Before, expected.After = nil, nil
+			if record != expected || !equalJSON(t, before, test.expected.Before) || !equalJSON(t, after, test.expected.After) {
+				t.Errorf("expected the record %+v, got %+v", test.expected, records[0])
+			}
+		})
+	}
+}
+
+func TestJSONLinesAuditSink(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "audit.jsonl")
+	ctx := context.Background()
+
+	for range 2 {
+		sink, err := OpenJSONLinesAuditFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Record(ctx, AuditRecord{Action: AuditDeleteKeyVals, Context: "http", Zone: "zone", Success: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", data)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(lines[1], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["action"] != "delete_keyvals" || record["zone"] != "zone" || record["success"] != true {
+		t.Fatalf("unexpected record %v", record)
+	}
+	if _, ok := record["before"]; ok {
+		t.Fatalf("expected no before state in record %v", record)
+	}
+}
+
+func equalJSON(t *testing.T, a, b any) bool {
+	t.Helper()
+	ja, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrEmptyToken is returned when a token source has no token.
+var ErrEmptyToken = errors.New("token is empty")
+
+// Authenticator adds credentials to the requests sent to the NGINX Plus API,
+// for example when the API location is protected with auth_basic or auth_jwt.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// WithAuthenticator sets the authenticator applied to every request sent to the API,
+// including the request for the supported API versions.
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(o *NginxClient) {
+		o.authenticator = authenticator
+	}
+}
+
+// WithClientCertificate sets the client certificate presented to the API for mutual TLS.
+// The certificate is reloaded from disk when the files change.
+func WithClientCertificate(reloader *CertificateReloader) Option {
+	return func(o *NginxClient) {
+		o.certReloader = reloader
+	}
+}
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+// BasicAuth returns an Authenticator that uses HTTP Basic authentication.
+func BasicAuth(username, password string) Authenticator {
+	return &basicAuth{username: username, password: password}
+}
+
+// Authenticate sets the Authorization header of the request.
+func (a *basicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+// TokenSource provides the tokens used for Bearer authentication.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource that always returns the same token.
+type StaticToken string
+
+// Token returns the token.
+func (t StaticToken) Token(_ context.Context) (string, error) {
+	if t == "" {
+		return "", ErrEmptyToken
+	}
+	return string(t), nil
+}
+
+// FileTokenSource is a TokenSource that reads the token from a file.
+// The file is read again when it changes, so tokens rotated on disk are picked up without restarting.
+type FileTokenSource struct {
+	modTime time.Time
+	path    string
+	token   string
+	size    int64
+	mu      sync.Mutex
+}
+
+// NewFileTokenSource creates a FileTokenSource for the file.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{path: path}
+}
+
+// Token returns the token from the file with the surrounding whitespace removed.
+func (s *FileTokenSource) Token(_ context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	if s.token != "" && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.token, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %v: %w", s.path, ErrEmptyToken)
+	}
+
+	s.token = token
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return s.token, nil
+}
+
+type bearerToken struct {
+	source TokenSource
+}
+
+// BearerToken returns an Authenticator that sends the token from the source in the Authorization header.
+func BearerToken(source TokenSource) Authenticator {
+	return &bearerToken{source: source}
+}
+
+// Authenticate sets the Authorization header of the request.
+func (a *bearerToken) Authenticate(req *http.Request) error {
+	token, err := a.source.Token(req.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// CertificateReloader loads a client certificate and key from disk
+// and loads them again when either file changes.
+type CertificateReloader struct {
+	certModTime time.Time
+	keyModTime  time.Time
+	cert        *tls.Certificate
+	certFile    string
+	keyFile     string
+	mu          sync.Mutex
+}
+
+// NewCertificateReloader creates a CertificateReloader and loads the certificate.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.certificate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate returns the current certificate.
+// It can be used as tls.Config.GetClientCertificate.
+func (r *CertificateReloader) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate()
+}
+
+func (r *CertificateReloader) certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client key: %w", err)
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// A certificate and key that are being rotated can briefly not match, so keep using the previous pair.
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	return r.cert, nil
+}
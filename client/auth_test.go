@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientWithBasicAuth(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte(`[4, 5, 6, 7, 8, 9]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	_, err := NewNginxClient(ts.URL, WithCheckAPI(), WithAuthenticator(BasicAuth("admin", "wrong")))
+	if err == nil {
+		t.Fatal("expected the version check to fail with wrong credentials")
+	}
+
+	c, err := NewNginxClient(ts.URL, WithCheckAPI(), WithAuthenticator(BasicAuth("admin", "secret")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetNginxInfo(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 2 || paths[0] != "/" || paths[1] != "/9/nginx" {
+		t.Fatalf("expected authenticated requests to / and /9/nginx, got %v", paths)
+	}
+}
+
+func TestBearerTokenFromFile(t *testing.T) {
+	t.Parallel()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeFile(t, tokenFile, "first\n", time.Now().Add(-time.Minute))
+
+	var mu sync.Mutex
+	var tokens []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL, WithAuthenticator(BearerToken(NewFileTokenSource(tokenFile))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := c.GetNginxInfo(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeFile(t, tokenFile, "second", time.Now())
+	if _, err := c.GetNginxInfo(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(tokens) != 2 || tokens[0] != "Bearer first" || tokens[1] != "Bearer second" {
+		t.Fatalf("expected the rotated token to be used, got %v", tokens)
+	}
+}
+
+func TestClientWithClientCertificate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	writeClientCertificate(t, certFile, keyFile, "first", time.Now().Add(-time.Minute))
+
+	var mu sync.Mutex
+	var names []string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		names = append(names, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	reloader, err := NewCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	httpClient := ts.Client()
+	c, err := NewNginxClient(ts.URL, WithHTTPClient(httpClient), WithClientCertificate(reloader))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.httpClient == httpClient {
+		t.Fatal("expected the HTTP client passed by the caller not to be modified")
+	}
+
+	ctx := context.Background()
+	if _, err := c.GetNginxInfo(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writeClientCertificate(t, certFile, keyFile, "second", time.Now())
+	c.httpClient.CloseIdleConnections()
+	if _, err := c.GetNginxInfo(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(names) != 2 || names[0] != "first" || names[1] != "second" {
+		t.Fatalf("expected the reloaded certificate to be used, got %v", names)
+	}
+}
+
+func writeFile(t *testing.T, name, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeClientCertificate(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, certFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), modTime)
+	writeFile(t, keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})), modTime)
+}
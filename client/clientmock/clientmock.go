@@ -0,0 +1,635 @@
+// Package clientmock provides mocks of the interfaces of the client package for tests.
+//
+// Each mock has a field for the implementation of each method, for example GetHTTPServersFunc,
+// and records the calls of its methods. A method panics if its implementation is not set.
+package clientmock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// Call is a call of a method of a mock. The arguments don't include the context.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+type recorder struct {
+	calls []Call
+	mu    sync.Mutex
+}
+
+func (r *recorder) record(method string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the calls of the methods of the mock in the order they were made.
+func (r *recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsOf returns the calls of the method of the mock.
+func (r *recorder) CallsOf(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var calls []Call
+	for _, c := range r.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// UpstreamManager is a mock of client.UpstreamManager.
+type UpstreamManager struct {
+	CheckIfUpstreamExistsFunc func(ctx context.Context, upstream string) error
+	GetHTTPServersFunc        func(ctx context.Context, upstream string) ([]client.UpstreamServer, error)
+	GetHTTPServerByIDFunc     func(ctx context.Context, upstream string, id int) (client.UpstreamServer, error)
+	AddHTTPServerFunc         func(ctx context.Context, upstream string, server client.UpstreamServer) error
+	DeleteHTTPServerFunc      func(ctx context.Context, upstream string, server string) error
+	DeleteHTTPServerByIDFunc  func(ctx context.Context, upstream string, id int) error
+	UpdateHTTPServerFunc      func(ctx context.Context, upstream string, server client.UpstreamServer) error
+	UpdateHTTPServersFunc     func(ctx context.Context, upstream string, servers []client.UpstreamServer) (added []client.UpstreamServer, deleted []client.UpstreamServer, updated []client.UpstreamServer, err error)
+	ReconcileHTTPServersFunc  func(ctx context.Context, upstream string, servers []client.UpstreamServer, opts ...client.ReconcileOption) (client.ReconcileResult[client.UpstreamServer], error)
+	recorder
+}
+
+var _ client.UpstreamManager = (*UpstreamManager)(nil)
+
+// CheckIfUpstreamExists calls CheckIfUpstreamExistsFunc.
+func (m *UpstreamManager) CheckIfUpstreamExists(ctx context.Context, upstream string) error {
+	m.record("CheckIfUpstreamExists", upstream)
+	if m.CheckIfUpstreamExistsFunc == nil {
+		panic("clientmock: UpstreamManager.CheckIfUpstreamExistsFunc is nil but CheckIfUpstreamExists was called")
+	}
+	return m.CheckIfUpstreamExistsFunc(ctx, upstream)
+}
+
+// GetHTTPServers calls GetHTTPServersFunc.
+func (m *UpstreamManager) GetHTTPServers(ctx context.Context, upstream string) ([]client.UpstreamServer, error) {
+	m.record("GetHTTPServers", upstream)
+	if m.GetHTTPServersFunc == nil {
+		panic("clientmock: UpstreamManager.GetHTTPServersFunc is nil but GetHTTPServers was called")
+	}
+	return m.GetHTTPServersFunc(ctx, upstream)
+}
+
+// GetHTTPServerByID calls GetHTTPServerByIDFunc.
+func (m *UpstreamManager) GetHTTPServerByID(ctx context.Context, upstream string, id int) (client.UpstreamServer, error) {
+	m.record("GetHTTPServerByID", upstream, id)
+	if m.GetHTTPServerByIDFunc == nil {
+		panic("clientmock: UpstreamManager.GetHTTPServerByIDFunc is nil but GetHTTPServerByID was called")
+	}
+	return m.GetHTTPServerByIDFunc(ctx, upstream, id)
+}
+
+// AddHTTPServer calls AddHTTPServerFunc.
+func (m *UpstreamManager) AddHTTPServer(ctx context.Context, upstream string, server client.UpstreamServer) error {
+	m.record("AddHTTPServer", upstream, server)
+	if m.AddHTTPServerFunc == nil {
+		panic("clientmock: UpstreamManager.AddHTTPServerFunc is nil but AddHTTPServer was called")
+	}
+	return m.AddHTTPServerFunc(ctx, upstream, server)
+}
+
+// DeleteHTTPServer calls DeleteHTTPServerFunc.
+func (m *UpstreamManager) DeleteHTTPServer(ctx context.Context, upstream string, server string) error {
+	m.record("DeleteHTTPServer", upstream, server)
+	if m.DeleteHTTPServerFunc == nil {
+		panic("clientmock: UpstreamManager.DeleteHTTPServerFunc is nil but DeleteHTTPServer was called")
+	}
+	return m.DeleteHTTPServerFunc(ctx, upstream, server)
+}
+
+// DeleteHTTPServerByID calls DeleteHTTPServerByIDFunc.
+func (m *UpstreamManager) DeleteHTTPServerByID(ctx context.Context, upstream string, id int) error {
+	m.record("DeleteHTTPServerByID", upstream, id)
+	if m.DeleteHTTPServerByIDFunc == nil {
+		panic("clientmock: UpstreamManager.DeleteHTTPServerByIDFunc is nil but DeleteHTTPServerByID was called")
+	}
+	return m.DeleteHTTPServerByIDFunc(ctx, upstream, id)
+}
+
+// UpdateHTTPServer calls UpdateHTTPServerFunc.
+func (m *UpstreamManager) UpdateHTTPServer(ctx context.Context, upstream string, server client.UpstreamServer) error {
+	m.record("UpdateHTTPServer", upstream, server)
+	if m.UpdateHTTPServerFunc == nil {
+		panic("clientmock: UpstreamManager.UpdateHTTPServerFunc is nil but UpdateHTTPServer was called")
+	}
+	return m.UpdateHTTPServerFunc(ctx, upstream, server)
+}
+
+// UpdateHTTPServers calls UpdateHTTPServersFunc.
+func (m *UpstreamManager) UpdateHTTPServers(ctx context.Context, upstream string, servers []client.UpstreamServer) (added []client.UpstreamServer, deleted []client.UpstreamServer, updated []client.UpstreamServer, err error) {
+	m.record("UpdateHTTPServers", upstream, servers)
+	if m.UpdateHTTPServersFunc == nil {
+		panic("clientmock: UpstreamManager.UpdateHTTPServersFunc is nil but UpdateHTTPServers was called")
+	}
+	return m.UpdateHTTPServersFunc(ctx, upstream, servers)
+}
+
+// ReconcileHTTPServers calls ReconcileHTTPServersFunc.
+func (m *UpstreamManager) ReconcileHTTPServers(ctx context.Context, upstream string, servers []client.UpstreamServer, opts ...client.ReconcileOption) (client.ReconcileResult[client.UpstreamServer], error) {
+	m.record("ReconcileHTTPServers", upstream, servers, opts)
+	if m.ReconcileHTTPServersFunc == nil {
+		panic("clientmock: UpstreamManager.ReconcileHTTPServersFunc is nil but ReconcileHTTPServers was called")
+	}
+	return m.ReconcileHTTPServersFunc(ctx, upstream, servers, opts...)
+}
+
+// StreamUpstreamManager is a mock of client.StreamUpstreamManager.
+type StreamUpstreamManager struct {
+	CheckIfStreamUpstreamExistsFunc func(ctx context.Context, upstream string) error
+	GetStreamServersFunc            func(ctx context.Context, upstream string) ([]client.StreamUpstreamServer, error)
+	GetStreamServerByIDFunc         func(ctx context.Context, upstream string, id int) (client.StreamUpstreamServer, error)
+	AddStreamServerFunc             func(ctx context.Context, upstream string, server client.StreamUpstreamServer) error
+	DeleteStreamServerFunc          func(ctx context.Context, upstream string, server string) error
+	DeleteStreamServerByIDFunc      func(ctx context.Context, upstream string, id int) error
+	UpdateStreamServerFunc          func(ctx context.Context, upstream string, server client.StreamUpstreamServer) error
+	UpdateStreamServersFunc         func(ctx context.Context, upstream string, servers []client.StreamUpstreamServer) (added []client.StreamUpstreamServer, deleted []client.StreamUpstreamServer, updated []client.StreamUpstreamServer, err error)
+	ReconcileStreamServersFunc      func(ctx context.Context, upstream string, servers []client.StreamUpstreamServer, opts ...client.ReconcileOption) (client.ReconcileResult[client.StreamUpstreamServer], error)
+	recorder
+}
+
+var _ client.StreamUpstreamManager = (*StreamUpstreamManager)(nil)
+
+// CheckIfStreamUpstreamExists calls CheckIfStreamUpstreamExistsFunc.
+func (m *StreamUpstreamManager) CheckIfStreamUpstreamExists(ctx context.Context, upstream string) error {
+	m.record("CheckIfStreamUpstreamExists", upstream)
+	if m.CheckIfStreamUpstreamExistsFunc == nil {
+		panic("clientmock: StreamUpstreamManager.CheckIfStreamUpstreamExistsFunc is nil but CheckIfStreamUpstreamExists was called")
+	}
+	return m.CheckIfStreamUpstreamExistsFunc(ctx, upstream)
+}
+
+// GetStreamServers calls GetStreamServersFunc.
+func (m *StreamUpstreamManager) GetStreamServers(ctx context.Context, upstream string) ([]client.StreamUpstreamServer, error) {
+	m.record("GetStreamServers", upstream)
+	if m.GetStreamServersFunc == nil {
+		panic("clientmock: StreamUpstreamManager.GetStreamServersFunc is nil but GetStreamServers was called")
+	}
+	return m.GetStreamServersFunc(ctx, upstream)
+}
+
+// GetStreamServerByID calls GetStreamServerByIDFunc.
+func (m *StreamUpstreamManager) GetStreamServerByID(ctx context.Context, upstream string, id int) (client.StreamUpstreamServer, error) {
+	m.record("GetStreamServerByID", upstream, id)
+	if m.GetStreamServerByIDFunc == nil {
+		panic("clientmock: StreamUpstreamManager.GetStreamServerByIDFunc is nil but GetStreamServerByID was called")
+	}
+	return m.GetStreamServerByIDFunc(ctx, upstream, id)
+}
+
+// AddStreamServer calls AddStreamServerFunc.
+func (m *StreamUpstreamManager) AddStreamServer(ctx context.Context, upstream string, server client.StreamUpstreamServer) error {
+	m.record("AddStreamServer", upstream, server)
+	if m.AddStreamServerFunc == nil {
+		panic("clientmock: StreamUpstreamManager.AddStreamServerFunc is nil but AddStreamServer was called")
+	}
+	return m.AddStreamServerFunc(ctx, upstream, server)
+}
+
+// DeleteStreamServer calls DeleteStreamServerFunc.
+func (m *StreamUpstreamManager) DeleteStreamServer(ctx context.Context, upstream string, server string) error {
+	m.record("DeleteStreamServer", upstream, server)
+	if m.DeleteStreamServerFunc == nil {
+		panic("clientmock: StreamUpstreamManager.DeleteStreamServerFunc is nil but DeleteStreamServer was called")
+	}
+	return m.DeleteStreamServerFunc(ctx, upstream, server)
+}
+
+// DeleteStreamServerByID calls DeleteStreamServerByIDFunc.
+func (m *StreamUpstreamManager) DeleteStreamServerByID(ctx context.Context, upstream string, id int) error {
+	m.record("DeleteStreamServerByID", upstream, id)
+	if m.DeleteStreamServerByIDFunc == nil {
+		panic("clientmock: StreamUpstreamManager.DeleteStreamServerByIDFunc is nil but DeleteStreamServerByID was called")
+	}
+	return m.DeleteStreamServerByIDFunc(ctx, upstream, id)
+}
+
+// UpdateStreamServer calls UpdateStreamServerFunc.
+func (m *StreamUpstreamManager) UpdateStreamServer(ctx context.Context, upstream string, server client.StreamUpstreamServer) error {
+	m.record("UpdateStreamServer", upstream, server)
+	if m.UpdateStreamServerFunc == nil {
+		panic("clientmock: StreamUpstreamManager.UpdateStreamServerFunc is nil but UpdateStreamServer was called")
+	}
+	return m.UpdateStreamServerFunc(ctx, upstream, server)
+}
+
+// UpdateStreamServers calls UpdateStreamServersFunc.
+func (m *StreamUpstreamManager) UpdateStreamServers(ctx context.Context, upstream string, servers []client.StreamUpstreamServer) (added []client.StreamUpstreamServer, deleted []client.StreamUpstreamServer, updated []client.StreamUpstreamServer, err error) {
+	m.record("UpdateStreamServers", upstream, servers)
+	if m.UpdateStreamServersFunc == nil {
+		panic("clientmock: StreamUpstreamManager.UpdateStreamServersFunc is nil but UpdateStreamServers was called")
+	}
+	return m.UpdateStreamServersFunc(ctx, upstream, servers)
+}
+
+// ReconcileStreamServers calls ReconcileStreamServersFunc.
+func (m *StreamUpstreamManager) ReconcileStreamServers(ctx context.Context, upstream string, servers []client.StreamUpstreamServer, opts ...client.ReconcileOption) (client.ReconcileResult[client.StreamUpstreamServer], error) {
+	m.record("ReconcileStreamServers", upstream, servers, opts)
+	if m.ReconcileStreamServersFunc == nil {
+		panic("clientmock: StreamUpstreamManager.ReconcileStreamServersFunc is nil but ReconcileStreamServers was called")
+	}
+	return m.ReconcileStreamServersFunc(ctx, upstream, servers, opts...)
+}
+
+// KeyValStore is a mock of client.KeyValStore.
+type KeyValStore struct {
+	GetKeyValPairsFunc           func(ctx context.Context, zone string) (client.KeyValPairs, error)
+	GetAllKeyValPairsFunc        func(ctx context.Context) (client.KeyValPairsByZone, error)
+	AddKeyValPairFunc            func(ctx context.Context, zone string, key string, val string) error
+	ModifyKeyValPairFunc         func(ctx context.Context, zone string, key string, val string) error
+	DeleteKeyValuePairFunc       func(ctx context.Context, zone string, key string) error
+	DeleteKeyValPairsFunc        func(ctx context.Context, zone string) error
+	GetStreamKeyValPairsFunc     func(ctx context.Context, zone string) (client.KeyValPairs, error)
+	GetAllStreamKeyValPairsFunc  func(ctx context.Context) (client.KeyValPairsByZone, error)
+	AddStreamKeyValPairFunc      func(ctx context.Context, zone string, key string, val string) error
+	ModifyStreamKeyValPairFunc   func(ctx context.Context, zone string, key string, val string) error
+	DeleteStreamKeyValuePairFunc func(ctx context.Context, zone string, key string) error
+	DeleteStreamKeyValPairsFunc  func(ctx context.Context, zone string) error
+	recorder
+}
+
+var _ client.KeyValStore = (*KeyValStore)(nil)
+
+// GetKeyValPairs calls GetKeyValPairsFunc.
+func (m *KeyValStore) GetKeyValPairs(ctx context.Context, zone string) (client.KeyValPairs, error) {
+	m.record("GetKeyValPairs", zone)
+	if m.GetKeyValPairsFunc == nil {
+		panic("clientmock: KeyValStore.GetKeyValPairsFunc is nil but GetKeyValPairs was called")
+	}
+	return m.GetKeyValPairsFunc(ctx, zone)
+}
+
+// GetAllKeyValPairs calls GetAllKeyValPairsFunc.
+func (m *KeyValStore) GetAllKeyValPairs(ctx context.Context) (client.KeyValPairsByZone, error) {
+	m.record("GetAllKeyValPairs")
+	if m.GetAllKeyValPairsFunc == nil {
+		panic("clientmock: KeyValStore.GetAllKeyValPairsFunc is nil but GetAllKeyValPairs was called")
+	}
+	return m.GetAllKeyValPairsFunc(ctx)
+}
+
+// AddKeyValPair calls AddKeyValPairFunc.
+func (m *KeyValStore) AddKeyValPair(ctx context.Context, zone string, key string, val string) error {
+	m.record("AddKeyValPair", zone, key, val)
+	if m.AddKeyValPairFunc == nil {
+		panic("clientmock: KeyValStore.AddKeyValPairFunc is nil but AddKeyValPair was called")
+	}
+	return m.AddKeyValPairFunc(ctx, zone, key, val)
+}
+
+// ModifyKeyValPair calls ModifyKeyValPairFunc.
+func (m *KeyValStore) ModifyKeyValPair(ctx context.Context, zone string, key string, val string) error {
+	m.record("ModifyKeyValPair", zone, key, val)
+	if m.ModifyKeyValPairFunc == nil {
+		panic("clientmock: KeyValStore.ModifyKeyValPairFunc is nil but ModifyKeyValPair was called")
+	}
+	return m.ModifyKeyValPairFunc(ctx, zone, key, val)
+}
+
+// DeleteKeyValuePair calls DeleteKeyValuePairFunc.
+func (m *KeyValStore) DeleteKeyValuePair(ctx context.Context, zone string, key string) error {
+	m.record("DeleteKeyValuePair", zone, key)
+	if m.DeleteKeyValuePairFunc == nil {
+		panic("clientmock: KeyValStore.DeleteKeyValuePairFunc is nil but DeleteKeyValuePair was called")
+	}
+	return m.DeleteKeyValuePairFunc(ctx, zone, key)
+}
+
+// DeleteKeyValPairs calls DeleteKeyValPairsFunc.
+func (m *KeyValStore) DeleteKeyValPairs(ctx context.Context, zone string) error {
+	m.record("DeleteKeyValPairs", zone)
+	if m.DeleteKeyValPairsFunc == nil {
+		panic("clientmock: KeyValStore.DeleteKeyValPairsFunc is nil but DeleteKeyValPairs was called")
+	}
+	return m.DeleteKeyValPairsFunc(ctx, zone)
+}
+
+// GetStreamKeyValPairs calls GetStreamKeyValPairsFunc.
+func (m *KeyValStore) GetStreamKeyValPairs(ctx context.Context, zone string) (client.KeyValPairs, error) {
+	m.record("GetStreamKeyValPairs", zone)
+	if m.GetStreamKeyValPairsFunc == nil {
+		panic("clientmock: KeyValStore.GetStreamKeyValPairsFunc is nil but GetStreamKeyValPairs was called")
+	}
+	return m.GetStreamKeyValPairsFunc(ctx, zone)
+}
+
+// GetAllStreamKeyValPairs calls GetAllStreamKeyValPairsFunc.
+func (m *KeyValStore) GetAllStreamKeyValPairs(ctx context.Context) (client.KeyValPairsByZone, error) {
+	m.record("GetAllStreamKeyValPairs")
+	if m.GetAllStreamKeyValPairsFunc == nil {
+		panic("clientmock: KeyValStore.GetAllStreamKeyValPairsFunc is nil but GetAllStreamKeyValPairs was called")
+	}
+	return m.GetAllStreamKeyValPairsFunc(ctx)
+}
+
+// AddStreamKeyValPair calls AddStreamKeyValPairFunc.
+func (m *KeyValStore) AddStreamKeyValPair(ctx context.Context, zone string, key string, val string) error {
+	m.record("AddStreamKeyValPair", zone, key, val)
+	if m.AddStreamKeyValPairFunc == nil {
+		panic("clientmock: KeyValStore.AddStreamKeyValPairFunc is nil but AddStreamKeyValPair was called")
+	}
+	return m.AddStreamKeyValPairFunc(ctx, zone, key, val)
+}
+
+// ModifyStreamKeyValPair calls ModifyStreamKeyValPairFunc.
+func (m *KeyValStore) ModifyStreamKeyValPair(ctx context.Context, zone string, key string, val string) error {
+	m.record("ModifyStreamKeyValPair", zone, key, val)
+	if m.ModifyStreamKeyValPairFunc == nil {
+		panic("clientmock: KeyValStore.ModifyStreamKeyValPairFunc is nil but ModifyStreamKeyValPair was called")
+	}
+	return m.ModifyStreamKeyValPairFunc(ctx, zone, key, val)
+}
+
+// DeleteStreamKeyValuePair calls DeleteStreamKeyValuePairFunc.
+func (m *KeyValStore) DeleteStreamKeyValuePair(ctx context.Context, zone string, key string) error {
+	m.record("DeleteStreamKeyValuePair", zone, key)
+	if m.DeleteStreamKeyValuePairFunc == nil {
+		panic("clientmock: KeyValStore.DeleteStreamKeyValuePairFunc is nil but DeleteStreamKeyValuePair was called")
+	}
+	return m.DeleteStreamKeyValuePairFunc(ctx, zone, key)
+}
+
+// DeleteStreamKeyValPairs calls DeleteStreamKeyValPairsFunc.
+func (m *KeyValStore) DeleteStreamKeyValPairs(ctx context.Context, zone string) error {
+	m.record("DeleteStreamKeyValPairs", zone)
+	if m.DeleteStreamKeyValPairsFunc == nil {
+		panic("clientmock: KeyValStore.DeleteStreamKeyValPairsFunc is nil but DeleteStreamKeyValPairs was called")
+	}
+	return m.DeleteStreamKeyValPairsFunc(ctx, zone)
+}
+
+// StatsReader is a mock of client.StatsReader.
+type StatsReader struct {
+	GetStatsFunc                  func(ctx context.Context) (*client.Stats, error)
+	GetCachesFunc                 func(ctx context.Context) (*client.Caches, error)
+	GetSlabsFunc                  func(ctx context.Context) (*client.Slabs, error)
+	GetConnectionsFunc            func(ctx context.Context) (*client.Connections, error)
+	GetHTTPRequestsFunc           func(ctx context.Context) (*client.HTTPRequests, error)
+	GetSSLFunc                    func(ctx context.Context) (*client.SSL, error)
+	GetServerZonesFunc            func(ctx context.Context) (*client.ServerZones, error)
+	GetStreamServerZonesFunc      func(ctx context.Context) (*client.StreamServerZones, error)
+	GetUpstreamsFunc              func(ctx context.Context) (*client.Upstreams, error)
+	GetStreamUpstreamsFunc        func(ctx context.Context) (*client.StreamUpstreams, error)
+	GetStreamZoneSyncFunc         func(ctx context.Context) (*client.StreamZoneSync, error)
+	GetLocationZonesFunc          func(ctx context.Context) (*client.LocationZones, error)
+	GetResolversFunc              func(ctx context.Context) (*client.Resolvers, error)
+	GetProcessesFunc              func(ctx context.Context) (*client.Processes, error)
+	GetHTTPLimitReqsFunc          func(ctx context.Context) (*client.HTTPLimitRequests, error)
+	GetHTTPConnectionsLimitFunc   func(ctx context.Context) (*client.HTTPLimitConnections, error)
+	GetStreamConnectionsLimitFunc func(ctx context.Context) (*client.StreamLimitConnections, error)
+	GetWorkersFunc                func(ctx context.Context) ([]*client.Workers, error)
+	recorder
+}
+
+var _ client.StatsReader = (*StatsReader)(nil)
+
+// GetStats calls GetStatsFunc.
+func (m *StatsReader) GetStats(ctx context.Context) (*client.Stats, error) {
+	m.record("GetStats")
+	if m.GetStatsFunc == nil {
+		panic("clientmock: StatsReader.GetStatsFunc is nil but GetStats was called")
+	}
+	return m.GetStatsFunc(ctx)
+}
+
+// GetCaches calls GetCachesFunc.
+func (m *StatsReader) GetCaches(ctx context.Context) (*client.Caches, error) {
+	m.record("GetCaches")
+	if m.GetCachesFunc == nil {
+		panic("clientmock: StatsReader.GetCachesFunc is nil but GetCaches was called")
+	}
+	return m.GetCachesFunc(ctx)
+}
+
+// GetSlabs calls GetSlabsFunc.
+func (m *StatsReader) GetSlabs(ctx context.Context) (*client.Slabs, error) {
+	m.record("GetSlabs")
+	if m.GetSlabsFunc == nil {
+		panic("clientmock: StatsReader.GetSlabsFunc is nil but GetSlabs was called")
+	}
+	return m.GetSlabsFunc(ctx)
+}
+
+// GetConnections calls GetConnectionsFunc.
+func (m *StatsReader) GetConnections(ctx context.Context) (*client.Connections, error) {
+	m.record("GetConnections")
+	if m.GetConnectionsFunc == nil {
+		panic("clientmock: StatsReader.GetConnectionsFunc is nil but GetConnections was called")
+	}
+	return m.GetConnectionsFunc(ctx)
+}
+
+// GetHTTPRequests calls GetHTTPRequestsFunc.
+func (m *StatsReader) GetHTTPRequests(ctx context.Context) (*client.HTTPRequests, error) {
+	m.record("GetHTTPRequests")
+	if m.GetHTTPRequestsFunc == nil {
+		panic("clientmock: StatsReader.GetHTTPRequestsFunc is nil but GetHTTPRequests was called")
+	}
+	return m.GetHTTPRequestsFunc(ctx)
+}
+
+// GetSSL calls GetSSLFunc.
+func (m *StatsReader) GetSSL(ctx context.Context) (*client.SSL, error) {
+	m.record("GetSSL")
+	if m.GetSSLFunc == nil {
+		panic("clientmock: StatsReader.GetSSLFunc is nil but GetSSL was called")
+	}
+	return m.GetSSLFunc(ctx)
+}
+
+// GetServerZones calls GetServerZonesFunc.
+func (m *StatsReader) GetServerZones(ctx context.Context) (*client.ServerZones, error) {
+	m.record("GetServerZones")
+	if m.GetServerZonesFunc == nil {
+		panic("clientmock: StatsReader.GetServerZonesFunc is nil but GetServerZones was called")
+	}
+	return m.GetServerZonesFunc(ctx)
+}
+
+// GetStreamServerZones calls GetStreamServerZonesFunc.
+func (m *StatsReader) GetStreamServerZones(ctx context.Context) (*client.StreamServerZones, error) {
+	m.record("GetStreamServerZones")
+	if m.GetStreamServerZonesFunc == nil {
+		panic("clientmock: StatsReader.GetStreamServerZonesFunc is nil but GetStreamServerZones was called")
+	}
+	return m.GetStreamServerZonesFunc(ctx)
+}
+
+// GetUpstreams calls GetUpstreamsFunc.
+func (m *StatsReader) GetUpstreams(ctx context.Context) (*client.Upstreams, error) {
+	m.record("GetUpstreams")
+	if m.GetUpstreamsFunc == nil {
+		panic("clientmock: StatsReader.GetUpstreamsFunc is nil but GetUpstreams was called")
+	}
+	return m.GetUpstreamsFunc(ctx)
+}
+
+// GetStreamUpstreams calls GetStreamUpstreamsFunc.
+func (m *StatsReader) GetStreamUpstreams(ctx context.Context) (*client.StreamUpstreams, error) {
+	m.record("GetStreamUpstreams")
+	if m.GetStreamUpstreamsFunc == nil {
+		panic("clientmock: StatsReader.GetStreamUpstreamsFunc is nil but GetStreamUpstreams was called")
+	}
+	return m.GetStreamUpstreamsFunc(ctx)
+}
+
+// GetStreamZoneSync calls GetStreamZoneSyncFunc.
+func (m *StatsReader) GetStreamZoneSync(ctx context.Context) (*client.StreamZoneSync, error) {
+	m.record("GetStreamZoneSync")
+	if m.GetStreamZoneSyncFunc == nil {
+		panic("clientmock: StatsReader.GetStreamZoneSyncFunc is nil but GetStreamZoneSync was called")
+	}
+	return m.GetStreamZoneSyncFunc(ctx)
+}
+
+// GetLocationZones calls GetLocationZonesFunc.
+func (m *StatsReader) GetLocationZones(ctx context.Context) (*client.LocationZones, error) {
+	m.record("GetLocationZones")
+	if m.GetLocationZonesFunc == nil {
+		panic("clientmock: StatsReader.GetLocationZonesFunc is nil but GetLocationZones was called")
+	}
+	return m.GetLocationZonesFunc(ctx)
+}
+
+// GetResolvers calls GetResolversFunc.
+func (m *StatsReader) GetResolvers(ctx context.Context) (*client.Resolvers, error) {
+	m.record("GetResolvers")
+	if m.GetResolversFunc == nil {
+		panic("clientmock: StatsReader.GetResolversFunc is nil but GetResolvers was called")
+	}
+	return m.GetResolversFunc(ctx)
+}
+
+// GetProcesses calls GetProcessesFunc.
+func (m *StatsReader) GetProcesses(ctx context.Context) (*client.Processes, error) {
+	m.record("GetProcesses")
+	if m.GetProcessesFunc == nil {
+		panic("clientmock: StatsReader.GetProcessesFunc is nil but GetProcesses was called")
+	}
+	return m.GetProcessesFunc(ctx)
+}
+
+// GetHTTPLimitReqs calls GetHTTPLimitReqsFunc.
+func (m *StatsReader) GetHTTPLimitReqs(ctx context.Context) (*client.HTTPLimitRequests, error) {
+	m.record("GetHTTPLimitReqs")
+	if m.GetHTTPLimitReqsFunc == nil {
+		panic("clientmock: StatsReader.GetHTTPLimitReqsFunc is nil but GetHTTPLimitReqs was called")
+	}
+	return m.GetHTTPLimitReqsFunc(ctx)
+}
+
+// GetHTTPConnectionsLimit calls GetHTTPConnectionsLimitFunc.
+func (m *StatsReader) GetHTTPConnectionsLimit(ctx context.Context) (*client.HTTPLimitConnections, error) {
+	m.record("GetHTTPConnectionsLimit")
+	if m.GetHTTPConnectionsLimitFunc == nil {
+		panic("clientmock: StatsReader.GetHTTPConnectionsLimitFunc is nil but GetHTTPConnectionsLimit was called")
+	}
+	return m.GetHTTPConnectionsLimitFunc(ctx)
+}
+
+// GetStreamConnectionsLimit calls GetStreamConnectionsLimitFunc.
+func (m *StatsReader) GetStreamConnectionsLimit(ctx context.Context) (*client.StreamLimitConnections, error) {
+	m.record("GetStreamConnectionsLimit")
+	if m.GetStreamConnectionsLimitFunc == nil {
+		panic("clientmock: StatsReader.GetStreamConnectionsLimitFunc is nil but GetStreamConnectionsLimit was called")
+	}
+	return m.GetStreamConnectionsLimitFunc(ctx)
+}
+
+// GetWorkers calls GetWorkersFunc.
+func (m *StatsReader) GetWorkers(ctx context.Context) ([]*client.Workers, error) {
+	m.record("GetWorkers")
+	if m.GetWorkersFunc == nil {
+		panic("clientmock: StatsReader.GetWorkersFunc is nil but GetWorkers was called")
+	}
+	return m.GetWorkersFunc(ctx)
+}
+
+// InstanceInfo is a mock of client.InstanceInfo.
+type InstanceInfo struct {
+	GetNginxInfoFunc                func(ctx context.Context) (*client.NginxInfo, error)
+	GetNginxLicenseFunc             func(ctx context.Context) (*client.NginxLicense, error)
+	GetAvailableEndpointsFunc       func(ctx context.Context) ([]string, error)
+	GetAvailableStreamEndpointsFunc func(ctx context.Context) ([]string, error)
+	GetMaxAPIVersionFunc            func(ctx context.Context) (int, error)
+	VersionFunc                     func() int
+	recorder
+}
+
+var _ client.InstanceInfo = (*InstanceInfo)(nil)
+
+// GetNginxInfo calls GetNginxInfoFunc.
+func (m *InstanceInfo) GetNginxInfo(ctx context.Context) (*client.NginxInfo, error) {
+	m.record("GetNginxInfo")
+	if m.GetNginxInfoFunc == nil {
+		panic("clientmock: InstanceInfo.GetNginxInfoFunc is nil but GetNginxInfo was called")
+	}
+	return m.GetNginxInfoFunc(ctx)
+}
+
+// GetNginxLicense calls GetNginxLicenseFunc.
+func (m *InstanceInfo) GetNginxLicense(ctx context.Context) (*client.NginxLicense, error) {
+	m.record("GetNginxLicense")
+	if m.GetNginxLicenseFunc == nil {
+		panic("clientmock: InstanceInfo.GetNginxLicenseFunc is nil but GetNginxLicense was called")
+	}
+	return m.GetNginxLicenseFunc(ctx)
+}
+
+// GetAvailableEndpoints calls GetAvailableEndpointsFunc.
+func (m *InstanceInfo) GetAvailableEndpoints(ctx context.Context) ([]string, error) {
+	m.record("GetAvailableEndpoints")
+	if m.GetAvailableEndpointsFunc == nil {
+		panic("clientmock: InstanceInfo.GetAvailableEndpointsFunc is nil but GetAvailableEndpoints was called")
+	}
+	return m.GetAvailableEndpointsFunc(ctx)
+}
+
+// GetAvailableStreamEndpoints calls GetAvailableStreamEndpointsFunc.
+func (m *InstanceInfo) GetAvailableStreamEndpoints(ctx context.Context) ([]string, error) {
+	m.record("GetAvailableStreamEndpoints")
+	if m.GetAvailableStreamEndpointsFunc == nil {
+		panic("clientmock: InstanceInfo.GetAvailableStreamEndpointsFunc is nil but GetAvailableStreamEndpoints was called")
+	}
+	return m.GetAvailableStreamEndpointsFunc(ctx)
+}
+
+// GetMaxAPIVersion calls GetMaxAPIVersionFunc.
+func (m *InstanceInfo) GetMaxAPIVersion(ctx context.Context) (int, error) {
+	m.record("GetMaxAPIVersion")
+	if m.GetMaxAPIVersionFunc == nil {
+		panic("clientmock: InstanceInfo.GetMaxAPIVersionFunc is nil but GetMaxAPIVersion was called")
+	}
+	return m.GetMaxAPIVersionFunc(ctx)
+}
+
+// Version calls VersionFunc.
+func (m *InstanceInfo) Version() int {
+	m.record("Version")
+	if m.VersionFunc == nil {
+		panic("clientmock: InstanceInfo.VersionFunc is nil but Version was called")
+	}
+	return m.VersionFunc()
+}
+
+// Client is a mock of client.Client that consists of the mocks of the interfaces client.Client embeds.
+type Client struct {
+	UpstreamManager
+	StreamUpstreamManager
+	KeyValStore
+	StatsReader
+	InstanceInfo
+}
+
+var _ client.Client = (*Client)(nil)
@@ -0,0 +1,56 @@
+package clientmock
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	mock := &Client{}
+	mock.GetHTTPServersFunc = func(_ context.Context, upstream string) ([]client.UpstreamServer, error) {
+		return []client.UpstreamServer{{Server: upstream + ":80"}}, nil
+	}
+	mock.AddKeyValPairFunc = func(context.Context, string, string, string) error {
+		return nil
+	}
+
+	var c client.Client = mock
+	ctx := context.Background()
+	servers, err := c.GetHTTPServers(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Server != "foo:80" {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+	if err := c.AddKeyValPair(ctx, "zone", "key", "val"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetHTTPServers(ctx, "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Call{{Method: "GetHTTPServers", Args: []any{"foo"}}, {Method: "GetHTTPServers", Args: []any{"bar"}}}
+	if calls := mock.UpstreamManager.Calls(); !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("expected calls %+v, got %+v", expected, calls)
+	}
+	expected = []Call{{Method: "AddKeyValPair", Args: []any{"zone", "key", "val"}}}
+	if calls := mock.KeyValStore.CallsOf("AddKeyValPair"); !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("expected calls %+v, got %+v", expected, calls)
+	}
+}
+
+func TestMissingFunc(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	mock := &StatsReader{}
+	_, _ = mock.GetStats(context.Background())
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrConflict is returned when the servers of an upstream were changed by another client during an update.
+var ErrConflict = errors.New("upstream changed concurrently")
+
+// ConflictError is returned for a server change that was not made because
+// another client changed the servers of the upstream since they were read.
+type ConflictError struct {
+	Upstream string
+	Server   string
+	Reason   string
+	Change   ServerChange
+	ID       int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: failed to %v %v server of %v upstream: %v", ErrConflict, e.Change, e.Server, e.Upstream, e.Reason)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// checkConflict reads the servers of the upstream again and returns a ConflictError
+// if the change of the server is based on a stale view of them:
+// a server to add already exists, or a server to remove or update doesn't exist anymore,
+// has a different address or, for updates, has been updated by another client.
+func checkConflict[S Server](ctx context.Context, ops serverOps[S], upstream string, change ServerChange, server S, before map[int]S) error {
+	servers, err := ops.get(ctx, upstream)
+	if err != nil {
+		return fmt.Errorf("failed to check for conflicts: %w", err)
+	}
+
+	conflict := &ConflictError{Upstream: upstream, Server: server.Address(), Change: change, ID: server.ServerID()}
+	key := serverKey(server.Address())
+	if change == ServerAdded {
+		for _, s := range servers {
+			if serverKey(s.Address()) == key {
+				conflict.Reason = "the server was added by another client"
+				conflict.ID = s.ServerID()
+				return conflict
+			}
+		}
+		return nil
+	}
+
+	for _, s := range servers {
+		if s.ServerID() != server.ServerID() {
+			continue
+		}
+		if serverKey(s.Address()) != key {
+			conflict.Reason = fmt.Sprintf("the server ID now belongs to %v", s.Address())
+			return conflict
+		}
+		if prev, ok := before[s.ServerID()]; change == ServerUpdated && ok && !sameParameters(s, prev) {
+			conflict.Reason = "the server was updated by another client"
+			return conflict
+		}
+		return nil
+	}
+	conflict.Reason = "the server was removed by another client"
+	return conflict
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type retryHooks struct {
+	NopHooks
+	attempts []int
+	mu       sync.Mutex
+}
+
+func (h *retryHooks) Retry(_ context.Context, _ *Operation, attempt int, _ error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.attempts = append(h.attempts, attempt)
+}
+
+func TestConflicts(t *testing.T) {
+	t.Parallel()
+	weight := 2
+	desired := []UpstreamServer{{Server: "10.0.0.1", Weight: &weight}, {Server: "10.0.0.2"}}
+	initial := `[{"id":1,"server":"10.0.0.1:80"}]`
+	tests := []struct {
+		msg        string
+		changed    string
+		reason     string
+		requests   string
+		options    []ReconcileOption
+		retries    int
+		wantChange ServerChange
+	}{
+		{
+			msg:      "no conflict",
+			options:  []ReconcileOption{WithConflictCheck()},
+			changed:  initial,
+			requests: "POST,PATCH",
+		},
+		{
+			msg:        "server added by another client",
+			options:    []ReconcileOption{WithConflictCheck()},
+			changed:    `[{"id":1,"server":"10.0.0.1:80"},{"id":5,"server":"10.0.0.2:80"}]`,
+			requests:   "PATCH",
+			wantChange: ServerAdded,
+			reason:     "added by another client",
+		},
+		{
+			msg:        "server updated by another client",
+			options:    []ReconcileOption{WithConflictCheck()},
+			changed:    `[{"id":1,"server":"10.0.0.1:80","weight":3}]`,
+			requests:   "POST",
+			wantChange: ServerUpdated,
+			reason:     "updated by another client",
+		},
+		{
+			msg:        "server ID reused",
+			options:    []ReconcileOption{WithConflictCheck()},
+			changed:    `[{"id":1,"server":"10.0.0.9:80"}]`,
+			requests:   "POST",
+			wantChange: ServerUpdated,
+			reason:     "now belongs to 10.0.0.9:80",
+		},
+		{
+			msg:      "retry after conflict",
+			options:  []ReconcileOption{WithConflictRetry(1)},
+			changed:  `[{"id":1,"server":"10.0.0.1:80","weight":2},{"id":5,"server":"10.0.0.2:80"}]`,
+			requests: "",
+			retries:  1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			var mu sync.Mutex
+			var gets int
+			var requests []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch r.Method {
+				case http.MethodGet:
+					gets++
+					if gets == 1 {
+						_, _ = w.Write([]byte(initial))
+						return
+					}
+					_, _ = w.Write([]byte(test.changed))
+				case http.MethodPost:
+					requests = append(requests, r.Method)
+					w.WriteHeader(http.StatusCreated)
+				default:
+					requests = append(requests, r.Method)
+					_, _ = w.Write([]byte(`{}`))
+				}
+			}))
+			defer ts.Close()
+
+			hooks := &retryHooks{}
+			c, err := NewNginxClient(ts.URL, WithHooks(hooks))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.ReconcileHTTPServers(context.Background(), "foo", desired, test.options...)
+
+			mu.Lock()
+			if strings.Join(requests, ",") != test.requests {
+				t.Errorf("expected requests %v, got %v", test.requests, requests)
+			}
+			mu.Unlock()
+			if len(hooks.attempts) != test.retries {
+				t.Errorf("expected %v retries, got %v", test.retries, hooks.attempts)
+			}
+
+			if test.wantChange == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if failed := result.Failed(); len(failed) != 0 {
+					t.Fatalf("unexpected failed outcomes %+v", failed)
+				}
+				return
+			}
+
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+				t.Fatalf("expected a ConflictError, got %v", err)
+			}
+			if conflict.Change != test.wantChange || !strings.Contains(conflict.Reason, test.reason) {
+				t.Fatalf("unexpected conflict %v", conflict)
+			}
+		})
+	}
+}
+
+func TestReconcileDefaults(t *testing.T) {
+	t.Parallel()
+	var gets int
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			gets++
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL, WithReconcileDefaults(WithConflictCheck()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := c.UpdateHTTPServers(context.Background(), "foo", []UpstreamServer{{Server: "10.0.0.1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if gets != 2 {
+		t.Fatalf("expected the servers to be read again before the change, got %v reads", gets)
+	}
+}
@@ -0,0 +1,337 @@
+package fleet
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// DriftKind is the kind of configuration that drifted.
+type DriftKind string
+
+const (
+	DriftHTTPUpstream     DriftKind = "http_upstream"
+	DriftStreamUpstream   DriftKind = "stream_upstream"
+	DriftKeyValZone       DriftKind = "keyval_zone"
+	DriftStreamKeyValZone DriftKind = "stream_keyval_zone"
+	DriftNginxVersion     DriftKind = "nginx_version"
+	DriftNginxBuild       DriftKind = "nginx_build"
+)
+
+// Drift is a difference between the configuration of a member and the expected configuration.
+type Drift struct {
+	Kind   DriftKind `json:"kind"`
+	Member string    `json:"member"`
+	// Name is the name of the upstream or keyval zone.
+	Name string `json:"name,omitempty"`
+	// Missing are the servers or keys the member doesn't have.
+	Missing []string `json:"missing,omitempty"`
+	// Extra are the servers or keys the member has but shouldn't.
+	Extra []string `json:"extra,omitempty"`
+	// Changed are the servers with different parameters or the keys with different values.
+	// The values of keys are not included, because they can be secrets.
+	Changed []string `json:"changed,omitempty"`
+	// Expected and Actual are the expected and the actual version or build of NGINX.
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	// Error is the error that occurred reading the configuration of the member.
+	Error string `json:"error,omitempty"`
+}
+
+// DriftReport is the result of Fleet.Drift.
+type DriftReport struct {
+	Drifts []Drift `json:"drifts"`
+}
+
+// HasDrift reports whether any member drifted.
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Drifts) > 0
+}
+
+// Members returns the names of the members that drifted.
+func (r *DriftReport) Members() []string {
+	var members []string
+	for _, d := range r.Drifts {
+		if !slices.Contains(members, d.Member) {
+			members = append(members, d.Member)
+		}
+	}
+	return members
+}
+
+// DesiredState is the configuration the members of a fleet are expected to have.
+type DesiredState struct {
+	HTTPUpstreams     map[string][]client.UpstreamServer
+	StreamUpstreams   map[string][]client.StreamUpstreamServer
+	KeyValZones       map[string]client.KeyValPairs
+	StreamKeyValZones map[string]client.KeyValPairs
+	// Version and Build are the expected version and build of NGINX.
+	// If they are empty, the versions and builds of the members are compared with each other.
+	Version string
+	Build   string
+}
+
+type driftConfig struct {
+	desired           *DesiredState
+	httpUpstreams     []string
+	streamUpstreams   []string
+	keyValZones       []string
+	streamKeyValZones []string
+}
+
+// DriftOption configures Fleet.Drift.
+type DriftOption func(*driftConfig)
+
+// WithDriftHTTPUpstreams adds HTTP upstreams whose servers are compared.
+func WithDriftHTTPUpstreams(upstreams ...string) DriftOption {
+	return func(c *driftConfig) {
+		c.httpUpstreams = append(c.httpUpstreams, upstreams...)
+	}
+}
+
+// WithDriftStreamUpstreams adds stream upstreams whose servers are compared.
+func WithDriftStreamUpstreams(upstreams ...string) DriftOption {
+	return func(c *driftConfig) {
+		c.streamUpstreams = append(c.streamUpstreams, upstreams...)
+	}
+}
+
+// WithDriftKeyValZones adds HTTP keyval zones whose key-value pairs are compared.
+func WithDriftKeyValZones(zones ...string) DriftOption {
+	return func(c *driftConfig) {
+		c.keyValZones = append(c.keyValZones, zones...)
+	}
+}
+
+// WithDriftStreamKeyValZones adds stream keyval zones whose key-value pairs are compared.
+func WithDriftStreamKeyValZones(zones ...string) DriftOption {
+	return func(c *driftConfig) {
+		c.streamKeyValZones = append(c.streamKeyValZones, zones...)
+	}
+}
+
+// WithDesiredState compares the members with the desired state instead of with each other.
+// The upstreams and keyval zones of the desired state are compared, in addition to those of the other options,
+// which are still compared with each other.
+func WithDesiredState(desired DesiredState) DriftOption {
+	return func(c *driftConfig) {
+		c.desired = &desired
+		c.httpUpstreams = append(c.httpUpstreams, slices.Sorted(maps.Keys(desired.HTTPUpstreams))...)
+		c.streamUpstreams = append(c.streamUpstreams, slices.Sorted(maps.Keys(desired.StreamUpstreams))...)
+		c.keyValZones = append(c.keyValZones, slices.Sorted(maps.Keys(desired.KeyValZones))...)
+		c.streamKeyValZones = append(c.streamKeyValZones, slices.Sorted(maps.Keys(desired.StreamKeyValZones))...)
+	}
+}
+
+// Drift compares the version and build of NGINX, the servers of the upstreams and the key-value pairs of the keyval zones
+// of the members. Without WithDesiredState, the members are compared with each other: for every upstream, keyval zone,
+// version and build, the value that most members have is expected, and the value of the first member wins a tie.
+// Members whose configuration can't be read are reported with the error. The error of Drift is only the error of the context.
+func (f *Fleet) Drift(ctx context.Context, opts ...DriftOption) (*DriftReport, error) {
+	var cfg driftConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	desired := cfg.desired
+	if desired == nil {
+		desired = &DesiredState{}
+	}
+
+	report := &DriftReport{Drifts: []Drift{}}
+	infos := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) (*client.NginxInfo, error) {
+		return m.Client.GetNginxInfo(ctx)
+	})
+	report.add(compare(DriftNginxVersion, "", infos, expectedString(cfg.desired, desired.Version), versionOf, diffStrings))
+	report.add(compare(DriftNginxBuild, "", infos, expectedString(cfg.desired, desired.Build), buildOf, diffStrings))
+
+	for _, upstream := range unique(cfg.httpUpstreams) {
+		results := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) ([]client.UpstreamServer, error) {
+			return m.Client.GetHTTPServers(ctx, upstream)
+		})
+		report.add(compareServers(DriftHTTPUpstream, upstream, results, desired.HTTPUpstreams, cfg.desired != nil))
+	}
+	for _, upstream := range unique(cfg.streamUpstreams) {
+		results := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) ([]client.StreamUpstreamServer, error) {
+			return m.Client.GetStreamServers(ctx, upstream)
+		})
+		report.add(compareServers(DriftStreamUpstream, upstream, results, desired.StreamUpstreams, cfg.desired != nil))
+	}
+	for _, zone := range unique(cfg.keyValZones) {
+		results := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) (client.KeyValPairs, error) {
+			return m.Client.GetKeyValPairs(ctx, zone)
+		})
+		report.add(compareKeyVals(DriftKeyValZone, zone, results, desired.KeyValZones, cfg.desired != nil))
+	}
+	for _, zone := range unique(cfg.streamKeyValZones) {
+		results := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) (client.KeyValPairs, error) {
+			return m.Client.GetStreamKeyValPairs(ctx, zone)
+		})
+		report.add(compareKeyVals(DriftStreamKeyValZone, zone, results, desired.StreamKeyValZones, cfg.desired != nil))
+	}
+
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	return report, nil
+}
+
+func (r *DriftReport) add(drifts []Drift) {
+	r.Drifts = append(r.Drifts, drifts...)
+}
+
+func versionOf(info *client.NginxInfo) string {
+	if info == nil {
+		return ""
+	}
+	return info.Version
+}
+
+func buildOf(info *client.NginxInfo) string {
+	if info == nil {
+		return ""
+	}
+	return info.Build
+}
+
+// expectedString returns the expected value if there is a desired state and the value is set.
+func expectedString(desired *DesiredState, value string) *string {
+	if desired == nil || value == "" {
+		return nil
+	}
+	return &value
+}
+
+func diffStrings(expected, actual string) (Drift, bool) {
+	return Drift{Expected: expected, Actual: actual}, expected != actual
+}
+
+func compareServers[S client.Server](kind DriftKind, upstream string, results Results[[]S], desired map[string][]S, hasDesired bool) []Drift {
+	var expected *[]S
+	if hasDesired {
+		servers, ok := desired[upstream]
+		if ok {
+			expected = &servers
+		}
+	}
+	return compare(kind, upstream, results, expected, func(servers []S) []S { return servers }, func(expected, actual []S) (Drift, bool) {
+		diff := client.DiffServers(expected, actual)
+		if len(diff.Added)+len(diff.Deleted)+len(diff.Updated) == 0 {
+			return Drift{}, false
+		}
+		return Drift{Missing: addresses(diff.Added), Extra: addresses(diff.Deleted), Changed: addresses(diff.Updated)}, true
+	})
+}
+
+func compareKeyVals(kind DriftKind, zone string, results Results[client.KeyValPairs], desired map[string]client.KeyValPairs, hasDesired bool) []Drift {
+	var expected *client.KeyValPairs
+	if hasDesired {
+		pairs, ok := desired[zone]
+		if ok {
+			expected = &pairs
+		}
+	}
+	return compare(kind, zone, results, expected, func(pairs client.KeyValPairs) client.KeyValPairs { return pairs }, func(expected, actual client.KeyValPairs) (Drift, bool) {
+		var d Drift
+		for key, val := range expected {
+			actualVal, ok := actual[key]
+			switch {
+			case !ok:
+				d.Missing = append(d.Missing, key)
+			case actualVal != val:
+				d.Changed = append(d.Changed, key)
+			}
+		}
+		for key := range actual {
+			if _, ok := expected[key]; !ok {
+				d.Extra = append(d.Extra, key)
+			}
+		}
+		slices.Sort(d.Missing)
+		slices.Sort(d.Extra)
+		slices.Sort(d.Changed)
+		return d, len(d.Missing)+len(d.Extra)+len(d.Changed) > 0
+	})
+}
+
+// compare compares the values of the members with the expected value, or, if it is nil,
+// with the value of the most members. key returns the part of the value that is compared.
+func compare[T any, V any](kind DriftKind, name string, results Results[T], expected *V, key func(T) V, diff func(expected, actual V) (Drift, bool)) []Drift {
+	var drifts []Drift
+	var values []V
+	var members []string
+	for _, result := range results {
+		if result.Err != nil {
+			drifts = append(drifts, Drift{Kind: kind, Name: name, Member: result.Member, Error: result.Err.Error()})
+			continue
+		}
+		values = append(values, key(result.Value))
+		members = append(members, result.Member)
+	}
+	if expected == nil {
+		if len(values) == 0 {
+			return drifts
+		}
+		expected = &values[majority(values, func(a, b V) bool {
+			_, differ := diff(a, b)
+			return !differ
+		})]
+	}
+
+	for i, value := range values {
+		d, differ := diff(*expected, value)
+		if differ {
+			d.Kind, d.Name, d.Member = kind, name, members[i]
+			drifts = append(drifts, d)
+		}
+	}
+	return drifts
+}
+
+// majority returns the index of the first value of the largest group of equal values.
+func majority[V any](values []V, equal func(a, b V) bool) int {
+	var representatives, counts []int
+	for i, value := range values {
+		found := false
+		for g, r := range representatives {
+			if equal(values[r], value) {
+				counts[g]++
+				found = true
+				break
+			}
+		}
+		if !found {
+			representatives = append(representatives, i)
+			counts = append(counts, 1)
+		}
+	}
+	best := 0
+	for g := range counts {
+		if counts[g] > counts[best] {
+			best = g
+		}
+	}
+	return representatives[best]
+}
+
+func addresses[S client.Server](servers []S) []string {
+	if len(servers) == 0 {
+		return nil
+	}
+	result := make([]string, len(servers))
+	for i, s := range servers {
+		result[i] = s.Address()
+	}
+	return result
+}
+
+func unique(names []string) []string {
+	var result []string
+	for _, name := range names {
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
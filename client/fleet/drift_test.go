@@ -0,0 +1,105 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+func TestDrift(t *testing.T) {
+	t.Parallel()
+	weight := 5
+	members := []struct {
+		servers []client.UpstreamServer
+		pairs   client.KeyValPairs
+		build   string
+		err     error
+	}{
+		{
+			servers: []client.UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}},
+			pairs:   client.KeyValPairs{"a": "1", "b": "2"},
+			build:   "nginx-plus-r34",
+		},
+		{
+			servers: []client.UpstreamServer{{Server: "10.0.0.1:80", Weight: &weight}, {Server: "10.0.0.3:80"}},
+			pairs:   client.KeyValPairs{"a": "changed", "c": "3"},
+			build:   "nginx-plus-r33",
+		},
+		{
+			servers: []client.UpstreamServer{{Server: "10.0.0.1"}, {Server: "10.0.0.2:80"}},
+			pairs:   client.KeyValPairs{"a": "1", "b": "2"},
+			build:   "nginx-plus-r34",
+		},
+		{
+			err: errFailed,
+		},
+	}
+	fakes := make([]*fakeMember, len(members))
+	for i, m := range members {
+		fake := newFakeMember()
+		fake.servers = m.servers
+		fake.pairs = m.pairs
+		if m.err != nil {
+			fake.fail = map[string]bool{"GetHTTPServers": true, "GetKeyValPairs": true}
+		}
+		fake.GetNginxInfoFunc = func(context.Context) (*client.NginxInfo, error) {
+			if m.err != nil {
+				return nil, m.err
+			}
+			return &client.NginxInfo{Version: "1.27.4", Build: m.build}, nil
+		}
+		fakes[i] = fake
+	}
+	f := newFleet(t, fakes)
+	ctx := context.Background()
+
+	report, err := f.Drift(ctx, WithDriftHTTPUpstreams("backend"), WithDriftKeyValZones("zone"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Drift{
+		{Kind: DriftNginxVersion, Member: "nginx-3", Error: errFailed.Error()},
+		{Kind: DriftNginxBuild, Member: "nginx-3", Error: errFailed.Error()},
+		{Kind: DriftNginxBuild, Member: "nginx-1", Expected: "nginx-plus-r34", Actual: "nginx-plus-r33"},
+		{Kind: DriftHTTPUpstream, Name: "backend", Member: "nginx-3", Error: errFailed.Error()},
+		{Kind: DriftHTTPUpstream, Name: "backend", Member: "nginx-1", Missing: []string{"10.0.0.2:80"}, Extra: []string{"10.0.0.3:80"}, Changed: []string{"10.0.0.1:80"}},
+		{Kind: DriftKeyValZone, Name: "zone", Member: "nginx-3", Error: errFailed.Error()},
+		{Kind: DriftKeyValZone, Name: "zone", Member: "nginx-1", Missing: []string{"b"}, Extra: []string{"c"}, Changed: []string{"a"}},
+	}
+	if !reflect.DeepEqual(report.Drifts, expected) {
+		t.Fatalf("expected drifts\n%+v\ngot\n%+v", expected, report.Drifts)
+	}
+	if !report.HasDrift() || !reflect.DeepEqual(report.Members(), []string{"nginx-3", "nginx-1"}) {
+		t.Fatalf("unexpected members %v", report.Members())
+	}
+	if _, err := json.Marshal(report); err != nil {
+		t.Fatal(err)
+	}
+
+	desired := DesiredState{
+		HTTPUpstreams: map[string][]client.UpstreamServer{"backend": {{Server: "10.0.0.1:80", Weight: &weight}, {Server: "10.0.0.3:80"}}},
+		Build:         "nginx-plus-r33",
+	}
+	report, err = newFleet(t, fakes[:3]).Drift(ctx, WithDesiredState(desired))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range report.Drifts {
+		if d.Member == "nginx-1" {
+			t.Fatalf("unexpected drift of the member with the desired state %+v", d)
+		}
+	}
+	if len(report.Drifts) != 4 {
+		t.Fatalf("expected the build and servers of two members to drift, got %+v", report.Drifts)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := f.Drift(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
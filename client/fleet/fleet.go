@@ -0,0 +1,346 @@
+// Package fleet runs operations of the NGINX Plus client on many NGINX Plus instances at once.
+// A Fleet sends every operation to all its members concurrently, returns the result of every member
+// and checks the results against a consistency policy, rolling back changes if the policy requires it.
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// ErrPolicyNotMet is returned when not enough members of the fleet succeeded for the consistency policy.
+	ErrPolicyNotMet = errors.New("consistency policy not met")
+	// ErrInvalidFleet is returned by New for invalid members or options.
+	ErrInvalidFleet = errors.New("invalid fleet")
+
+	errNotMade = errors.New("change not made because the state of another member could not be read")
+)
+
+// Policy is the consistency policy of a fleet. It determines how many members must succeed for an operation to succeed
+// and whether the changes are rolled back when they don't.
+type Policy int
+
+const (
+	// AllOrNothing requires all members to succeed. If a change fails on any member,
+	// it is rolled back on the members it was made on. This is the default policy.
+	AllOrNothing Policy = iota
+	// BestEffort requires one member to succeed. Changes are never rolled back.
+	BestEffort
+	// Quorum requires a quorum of members to succeed, by default a majority. Changes are never rolled back.
+	Quorum
+)
+
+func (p Policy) String() string {
+	switch p {
+	case AllOrNothing:
+		return "all-or-nothing"
+	case BestEffort:
+		return "best-effort"
+	case Quorum:
+		return "quorum"
+	default:
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+}
+
+// Member is a NGINX Plus instance of a fleet.
+type Member struct {
+	// Client is the client of the instance, usually a *client.NginxClient.
+	Client client.Client
+	// Name identifies the instance in the results, for example by its address.
+	Name string
+}
+
+// Fleet runs operations on all its members concurrently.
+type Fleet struct {
+	logger      *slog.Logger
+	members     []Member
+	policy      Policy
+	quorum      int
+	parallelism int
+}
+
+// Option configures a Fleet.
+type Option func(*Fleet)
+
+// WithPolicy sets the consistency policy. AllOrNothing is used by default.
+func WithPolicy(policy Policy) Option {
+	return func(f *Fleet) {
+		f.policy = policy
+	}
+}
+
+// WithQuorum sets the Quorum policy with the number of members that must succeed.
+func WithQuorum(quorum int) Option {
+	return func(f *Fleet) {
+		f.policy = Quorum
+		f.quorum = quorum
+	}
+}
+
+// WithParallelism limits the number of members an operation runs on at the same time.
+// By default, operations run on all members at the same time.
+func WithParallelism(n int) Option {
+	return func(f *Fleet) {
+		f.parallelism = n
+	}
+}
+
+// WithLogger sets the logger used to log rollbacks. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(f *Fleet) {
+		f.logger = logger
+	}
+}
+
+// New creates a Fleet of the members. The names of the members must be unique.
+func New(members []Member, opts ...Option) (*Fleet, error) {
+	f := &Fleet{
+		members: append([]Member(nil), members...),
+		logger:  slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if len(f.members) == 0 {
+		return nil, fmt.Errorf("%w: no members", ErrInvalidFleet)
+	}
+	names := make(map[string]bool, len(f.members))
+	for _, m := range f.members {
+		if m.Client == nil {
+			return nil, fmt.Errorf("%w: member %q has no client", ErrInvalidFleet, m.Name)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("%w: duplicate member %q", ErrInvalidFleet, m.Name)
+		}
+		names[m.Name] = true
+	}
+	if f.policy == Quorum && f.quorum == 0 {
+		f.quorum = len(f.members)/2 + 1
+	}
+	if f.quorum < 0 || f.quorum > len(f.members) {
+		return nil, fmt.Errorf("%w: quorum %v for %v members", ErrInvalidFleet, f.quorum, len(f.members))
+	}
+	if f.parallelism < 0 {
+		return nil, fmt.Errorf("%w: parallelism %v", ErrInvalidFleet, f.parallelism)
+	}
+	if f.logger == nil {
+		return nil, fmt.Errorf("%w: no logger", ErrInvalidFleet)
+	}
+	return f, nil
+}
+
+// Members returns the members of the fleet.
+func (f *Fleet) Members() []Member {
+	return append([]Member(nil), f.members...)
+}
+
+// Policy returns the consistency policy of the fleet.
+func (f *Fleet) Policy() Policy {
+	return f.policy
+}
+
+// Result is the result of an operation on a member of the fleet.
+type Result[T any] struct {
+	Value T
+	// Err is the error of the operation on the member.
+	Err error
+	// RollbackErr is the error of the rollback of the change on the member.
+	RollbackErr error
+	Member      string
+	// RolledBack is true if the change was rolled back on the member.
+	RolledBack bool
+}
+
+// Results are the results of an operation on all the members of the fleet, in the order of the members.
+type Results[T any] []Result[T]
+
+// Succeeded returns the results of the members the operation succeeded on.
+func (r Results[T]) Succeeded() Results[T] {
+	var results Results[T]
+	for _, result := range r {
+		if result.Err == nil {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// Failed returns the results of the members the operation failed on.
+func (r Results[T]) Failed() Results[T] {
+	var results Results[T]
+	for _, result := range r {
+		if result.Err != nil {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// Member returns the result of the member.
+func (r Results[T]) Member(name string) (Result[T], bool) {
+	for _, result := range r {
+		if result.Member == name {
+			return result, true
+		}
+	}
+	return Result[T]{}, false
+}
+
+// PolicyError is returned when not enough members succeeded for the consistency policy.
+// It wraps ErrPolicyNotMet and the errors of the members.
+type PolicyError struct {
+	Err       error
+	Policy    Policy
+	Succeeded int
+	Required  int
+	Members   int
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%v: %v of %v members succeeded, %v required by the %v policy: %v",
+		ErrPolicyNotMet, e.Succeeded, e.Members, e.Required, e.Policy, e.Err)
+}
+
+func (e *PolicyError) Unwrap() []error {
+	return []error{ErrPolicyNotMet, e.Err}
+}
+
+// required returns the number of members that must succeed.
+func (f *Fleet) required() int {
+	switch f.policy {
+	case BestEffort:
+		return 1
+	case Quorum:
+		return f.quorum
+	default:
+		return len(f.members)
+	}
+}
+
+// check returns a PolicyError if not enough members succeeded.
+func check[T any](f *Fleet, results Results[T]) error {
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", result.Member, result.Err))
+		}
+	}
+	succeeded := len(results) - len(errs)
+	if succeeded >= f.required() {
+		return nil
+	}
+	return &PolicyError{
+		Policy:    f.policy,
+		Succeeded: succeeded,
+		Required:  f.required(),
+		Members:   len(results),
+		Err:       errors.Join(errs...),
+	}
+}
+
+// run runs the function for the members with the indexes concurrently.
+func run[T any](ctx context.Context, f *Fleet, members []int, fn func(ctx context.Context, i int, m Member) (T, error)) Results[T] {
+	results := make(Results[T], len(members))
+	var g errgroup.Group
+	if f.parallelism > 0 {
+		g.SetLimit(f.parallelism)
+	}
+	for i, member := range members {
+		g.Go(func() error {
+			m := f.members[member]
+			value, err := fn(ctx, member, m)
+			results[i] = Result[T]{Member: m.Name, Value: value, Err: err}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results
+}
+
+func (f *Fleet) all() []int {
+	members := make([]int, len(f.members))
+	for i := range members {
+		members[i] = i
+	}
+	return members
+}
+
+// change describes how to make a change on a member and how to roll it back.
+type change[T any, S any] struct {
+	// snapshot reads the state that restore needs. It can be nil.
+	snapshot func(ctx context.Context, m Member) (S, error)
+	apply    func(ctx context.Context, m Member) (T, error)
+	restore  func(ctx context.Context, m Member, state S) error
+	// name describes the change in logs.
+	name string
+	// partial is true if a change that fails can still be partially made, so it needs to be rolled back too.
+	partial bool
+}
+
+// mutate makes the change on all the members. With the AllOrNothing policy, the state of the members is read first,
+// and the change isn't made at all if that fails for any member. If the change then fails on any member,
+// it is rolled back on all the members it was made on.
+func mutate[T any, S any](ctx context.Context, f *Fleet, c change[T, S]) (Results[T], error) {
+	if f.policy != AllOrNothing {
+		results := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) (T, error) {
+			return c.apply(ctx, m)
+		})
+		return results, check(f, results)
+	}
+
+	states := make([]S, len(f.members))
+	if c.snapshot != nil {
+		snapshots := run(ctx, f, f.all(), func(ctx context.Context, i int, m Member) (S, error) {
+			state, err := c.snapshot(ctx, m)
+			states[i] = state
+			return state, err
+		})
+		if len(snapshots.Failed()) > 0 {
+			results := make(Results[T], len(snapshots))
+			for i, s := range snapshots {
+				results[i] = Result[T]{Member: s.Member, Err: errNotMade}
+				if s.Err != nil {
+					results[i].Err = fmt.Errorf("failed to read the state before the change: %w", s.Err)
+				}
+			}
+			return results, check(f, results)
+		}
+	}
+
+	results := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) (T, error) {
+		return c.apply(ctx, m)
+	})
+	err := check(f, results)
+	if err == nil {
+		return results, nil
+	}
+
+	var rollback []int
+	for i, result := range results {
+		if result.Err == nil || c.partial {
+			rollback = append(rollback, i)
+		}
+	}
+	f.logger.WarnContext(ctx, "rolling back change of fleet", "change", c.name, "error", err, "members", len(rollback))
+	// The change must be rolled back even if the context of the change is canceled.
+	rollbackCtx := context.WithoutCancel(ctx)
+	restored := run(rollbackCtx, f, rollback, func(ctx context.Context, i int, m Member) (struct{}, error) {
+		return struct{}{}, c.restore(ctx, m, states[i])
+	})
+	for i, member := range rollback {
+		results[member].RolledBack = restored[i].Err == nil
+		results[member].RollbackErr = restored[i].Err
+		if restored[i].Err != nil {
+			f.logger.ErrorContext(ctx, "failed to roll back change of fleet member", "change", c.name, "member", restored[i].Member, "error", restored[i].Err)
+		}
+	}
+	return results, err
+}
@@ -0,0 +1,346 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+var (
+	errFailed   = errors.New("failed")
+	errServerID = errors.New("unknown parameter \"id\"")
+)
+
+// fakeMember is a member with the servers of one upstream and the pairs of one keyval zone.
+type fakeMember struct {
+	*clientmock.Client
+	pairs   client.KeyValPairs
+	fail    map[string]bool
+	servers []client.UpstreamServer
+	mu      sync.Mutex
+}
+
+func newFakeMember(fail ...string) *fakeMember {
+	m := &fakeMember{
+		Client:  &clientmock.Client{},
+		pairs:   client.KeyValPairs{"key": "old"},
+		servers: []client.UpstreamServer{{Server: "10.0.0.1:80"}},
+		fail:    make(map[string]bool),
+	}
+	for _, method := range fail {
+		m.fail[method] = true
+	}
+	m.GetHTTPServersFunc = func(context.Context, string) ([]client.UpstreamServer, error) {
+		return m.getServers("GetHTTPServers")
+	}
+	m.UpdateHTTPServersFunc = func(_ context.Context, _ string, servers []client.UpstreamServer) ([]client.UpstreamServer, []client.UpstreamServer, []client.UpstreamServer, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.fail["UpdateHTTPServers"] {
+			// The update failed after some of the servers were changed.
+			m.servers = append(m.servers, client.UpstreamServer{Server: "10.0.0.99:80"})
+			m.fail["UpdateHTTPServers"] = false
+			return nil, nil, nil, errFailed
+		}
+		present := make(map[string]bool)
+		for _, server := range m.servers {
+			present[server.Server] = true
+		}
+		for _, server := range servers {
+			// NGINX rejects added servers with an ID.
+			if !present[server.Server] && server.ID != 0 {
+				return nil, nil, nil, errServerID
+			}
+		}
+		m.servers = servers
+		return servers, nil, nil, nil
+	}
+	m.GetKeyValPairsFunc = func(context.Context, string) (client.KeyValPairs, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.fail["GetKeyValPairs"] {
+			return nil, errFailed
+		}
+		return m.pairs, nil
+	}
+	m.ModifyKeyValPairFunc = func(_ context.Context, _ string, key string, val string) error {
+		return m.setPair("ModifyKeyValPair", key, val)
+	}
+	m.AddKeyValPairFunc = func(_ context.Context, _ string, key string, val string) error {
+		return m.setPair("AddKeyValPair", key, val)
+	}
+	m.DeleteKeyValuePairFunc = func(_ context.Context, _ string, key string) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.fail["DeleteKeyValuePair"] {
+			return errFailed
+		}
+		delete(m.pairs, key)
+		return nil
+	}
+	m.GetStatsFunc = func(context.Context) (*client.Stats, error) {
+		if m.fail["GetStats"] {
+			return nil, errFailed
+		}
+		return &client.Stats{}, nil
+	}
+	return m
+}
+
+func (m *fakeMember) getServers(method string) ([]client.UpstreamServer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail[method] {
+		return nil, errFailed
+	}
+	return m.servers, nil
+}
+
+func (m *fakeMember) setPair(method, key, val string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail[method] {
+		return errFailed
+	}
+	m.pairs[key] = val
+	return nil
+}
+
+func newFleet(t *testing.T, fakes []*fakeMember, opts ...Option) *Fleet {
+	t.Helper()
+	members := make([]Member, len(fakes))
+	for i, fake := range fakes {
+		members[i] = Member{Name: fmt.Sprintf("nginx-%d", i), Client: fake}
+	}
+	f, err := New(members, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	c := &clientmock.Client{}
+	tests := []struct {
+		msg     string
+		members []Member
+		opts    []Option
+	}{
+		{msg: "no members"},
+		{msg: "no client", members: []Member{{Name: "a"}}},
+		{msg: "duplicate member", members: []Member{{Name: "a", Client: c}, {Name: "a", Client: c}}},
+		{msg: "quorum too large", members: []Member{{Name: "a", Client: c}}, opts: []Option{WithQuorum(2)}},
+		{msg: "negative parallelism", members: []Member{{Name: "a", Client: c}}, opts: []Option{WithParallelism(-1)}},
+	}
+	for _, test := range tests {
+		if _, err := New(test.members, test.opts...); !errors.Is(err, ErrInvalidFleet) {
+			t.Errorf("%v: expected ErrInvalidFleet, got %v", test.msg, err)
+		}
+	}
+
+	f, err := New([]Member{{Name: "a", Client: c}, {Name: "b", Client: c}, {Name: "c", Client: c}}, WithPolicy(Quorum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.required() != 2 {
+		t.Fatalf("expected a majority quorum of 2, got %v", f.required())
+	}
+}
+
+func TestUpdateHTTPServers(t *testing.T) {
+	t.Parallel()
+	desired := []client.UpstreamServer{{Server: "10.0.0.2:80"}}
+	initial := []client.UpstreamServer{{Server: "10.0.0.1:80"}}
+	tests := []struct {
+		msg        string
+		fail       []string
+		opts       []Option
+		expected   []client.UpstreamServer
+		rolledBack bool
+		wantErr    bool
+	}{
+		{
+			msg:      "all members succeed",
+			expected: desired,
+		},
+		{
+			msg:        "all or nothing rolls back all members",
+			fail:       []string{"UpdateHTTPServers"},
+			expected:   initial,
+			rolledBack: true,
+			wantErr:    true,
+		},
+		{
+			msg:      "all or nothing makes no change if a snapshot fails",
+			fail:     []string{"GetHTTPServers"},
+			expected: initial,
+			wantErr:  true,
+		},
+		{
+			msg:      "quorum reached",
+			fail:     []string{"UpdateHTTPServers"},
+			opts:     []Option{WithQuorum(2)},
+			expected: desired,
+		},
+		{
+			msg:      "quorum not reached",
+			fail:     []string{"UpdateHTTPServers"},
+			opts:     []Option{WithQuorum(3)},
+			expected: desired,
+			wantErr:  true,
+		},
+		{
+			msg:      "best effort",
+			fail:     []string{"UpdateHTTPServers"},
+			opts:     []Option{WithPolicy(BestEffort)},
+			expected: desired,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			fakes := []*fakeMember{newFakeMember(), newFakeMember(test.fail...), newFakeMember()}
+			f := newFleet(t, fakes, test.opts...)
+
+			results, err := f.UpdateHTTPServers(context.Background(), "foo", desired)
+			if test.wantErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+			if err != nil && (!errors.Is(err, ErrPolicyNotMet) || !errors.Is(err, errFailed)) {
+				t.Fatalf("expected the error to wrap ErrPolicyNotMet and the error of the member, got %v", err)
+			}
+			if len(results) != 3 || results[1].Member != "nginx-1" {
+				t.Fatalf("unexpected results %+v", results)
+			}
+			if len(test.fail) > 0 && results[1].Err == nil {
+				t.Fatalf("expected the second member to fail, got %+v", results[1])
+			}
+			for i, result := range results {
+				if result.RolledBack != test.rolledBack {
+					t.Errorf("member %v: expected rolled back %v, got %v", i, test.rolledBack, result.RolledBack)
+				}
+			}
+			// The members that succeeded keep the change unless it was rolled back.
+			for _, i := range []int{0, 2} {
+				if servers, _ := fakes[i].getServers(""); !reflect.DeepEqual(servers, test.expected) {
+					t.Errorf("member %v: expected servers %v, got %v", i, test.expected, servers)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateHTTPServersRollbackAddsDeletedServers(t *testing.T) {
+	t.Parallel()
+	fakes := []*fakeMember{newFakeMember(), newFakeMember("UpdateHTTPServers")}
+	for _, fake := range fakes {
+		fake.servers = []client.UpstreamServer{{ID: 7, Server: "10.0.0.1:80"}}
+	}
+	f := newFleet(t, fakes, WithParallelism(1))
+
+	results, err := f.UpdateHTTPServers(context.Background(), "foo", []client.UpstreamServer{{Server: "10.0.0.2:80"}})
+	if !errors.Is(err, ErrPolicyNotMet) {
+		t.Fatalf("expected %v, got %v", ErrPolicyNotMet, err)
+	}
+	if !results[0].RolledBack || results[0].RollbackErr != nil {
+		t.Fatalf("expected the first member to be rolled back, got %+v", results[0])
+	}
+	expected := []client.UpstreamServer{{Server: "10.0.0.1:80"}}
+	if servers, _ := fakes[0].getServers(""); !reflect.DeepEqual(servers, expected) {
+		t.Fatalf("expected servers %v, got %v", expected, servers)
+	}
+}
+
+func TestKeyVals(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		change   func(f *Fleet) (Results[struct{}], error)
+		expected client.KeyValPairs
+		msg      string
+		fail     string
+	}{
+		{
+			msg:      "add",
+			change:   func(f *Fleet) (Results[struct{}], error) { return f.AddKeyValPair(context.Background(), "zone", "new", "val") },
+			fail:     "AddKeyValPair",
+			expected: client.KeyValPairs{"key": "old"},
+		},
+		{
+			msg:      "modify",
+			change:   func(f *Fleet) (Results[struct{}], error) { return f.ModifyKeyValPair(context.Background(), "zone", "key", "new") },
+			fail:     "ModifyKeyValPair",
+			expected: client.KeyValPairs{"key": "old"},
+		},
+		{
+			msg:      "delete",
+			change:   func(f *Fleet) (Results[struct{}], error) { return f.DeleteKeyValuePair(context.Background(), "zone", "key") },
+			fail:     "DeleteKeyValuePair",
+			expected: client.KeyValPairs{"key": "old"},
+		},
+		{
+			msg:      "modify missing key",
+			change:   func(f *Fleet) (Results[struct{}], error) { return f.ModifyKeyValPair(context.Background(), "zone", "new", "val") },
+			fail:     "ModifyKeyValPair",
+			expected: client.KeyValPairs{"key": "old"},
+		},
+		{
+			msg:      "delete missing key",
+			change:   func(f *Fleet) (Results[struct{}], error) { return f.DeleteKeyValuePair(context.Background(), "zone", "new") },
+			fail:     "DeleteKeyValuePair",
+			expected: client.KeyValPairs{"key": "old"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			fakes := []*fakeMember{newFakeMember(), newFakeMember(test.fail)}
+			f := newFleet(t, fakes)
+
+			results, err := test.change(f)
+			if !errors.Is(err, ErrPolicyNotMet) {
+				t.Fatalf("expected ErrPolicyNotMet, got %v", err)
+			}
+			if !results[0].RolledBack || results[1].RolledBack {
+				t.Fatalf("expected only the first member to be rolled back, got %+v", results)
+			}
+			if !reflect.DeepEqual(fakes[0].pairs, test.expected) {
+				t.Fatalf("expected pairs %v, got %v", test.expected, fakes[0].pairs)
+			}
+			if calls := fakes[1].KeyValStore.CallsOf(test.fail); len(calls) != 1 {
+				t.Fatalf("expected one call of %v on the failed member, got %v", test.fail, calls)
+			}
+		})
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	t.Parallel()
+	fakes := []*fakeMember{newFakeMember(), newFakeMember("GetStats")}
+
+	results, err := newFleet(t, fakes, WithPolicy(BestEffort), WithParallelism(1)).GetStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Succeeded()) != 1 || len(results.Failed()) != 1 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if result, ok := results.Member("nginx-0"); !ok || result.Value == nil {
+		t.Fatalf("expected the stats of nginx-0, got %+v", result)
+	}
+	if merged := MergeStats(results); len(merged.Instances) != 1 || merged.Instances["nginx-0"] == nil {
+		t.Fatalf("expected the merged stats of nginx-0, got %+v", merged.Instances)
+	}
+
+	if _, err := newFleet(t, fakes).GetStats(context.Background()); !errors.Is(err, ErrPolicyNotMet) {
+		t.Fatalf("expected ErrPolicyNotMet, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package fleet
+
+import (
+	"context"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// UpdateHTTPServers updates the servers of the HTTP upstream on all members with client.NginxClient.UpdateHTTPServers.
+// With the AllOrNothing policy, the servers of every member are read first and, if the update fails on any member,
+// the servers of all the members are updated back to them.
+func (f *Fleet) UpdateHTTPServers(ctx context.Context, upstream string, servers []client.UpstreamServer) (Results[client.ServerUpdates[client.UpstreamServer]], error) {
+	return updateServers(ctx, f, upstream, servers, func(m Member) serverAPI[client.UpstreamServer] {
+		return serverAPI[client.UpstreamServer]{get: m.Client.GetHTTPServers, update: m.Client.UpdateHTTPServers}
+	})
+}
+
+// UpdateStreamServers updates the servers of the stream upstream on all members with client.NginxClient.UpdateStreamServers.
+// With the AllOrNothing policy, the servers of every member are read first and, if the update fails on any member,
+// the servers of all the members are updated back to them.
+func (f *Fleet) UpdateStreamServers(ctx context.Context, upstream string, servers []client.StreamUpstreamServer) (Results[client.ServerUpdates[client.StreamUpstreamServer]], error) {
+	return updateServers(ctx, f, upstream, servers, func(m Member) serverAPI[client.StreamUpstreamServer] {
+		return serverAPI[client.StreamUpstreamServer]{get: m.Client.GetStreamServers, update: m.Client.UpdateStreamServers}
+	})
+}
+
+type serverAPI[S client.Server] struct {
+	get    func(ctx context.Context, upstream string) ([]S, error)
+	update func(ctx context.Context, upstream string, servers []S) ([]S, []S, []S, error)
+}
+
+func updateServers[S client.Server](ctx context.Context, f *Fleet, upstream string, servers []S, api func(Member) serverAPI[S]) (Results[client.ServerUpdates[S]], error) {
+	return mutate(ctx, f, change[client.ServerUpdates[S], []S]{
+		name:    "update servers of upstream " + upstream,
+		partial: true,
+		snapshot: func(ctx context.Context, m Member) ([]S, error) {
+			servers, err := api(m).get(ctx, upstream)
+			return withoutIDs(servers), err
+		},
+		apply: func(ctx context.Context, m Member) (client.ServerUpdates[S], error) {
+			added, deleted, updated, err := api(m).update(ctx, upstream, servers)
+			return client.ServerUpdates[S]{Added: added, Deleted: deleted, Updated: updated}, err
+		},
+		restore: func(ctx context.Context, m Member, servers []S) error {
+			_, _, _, err := api(m).update(ctx, upstream, servers)
+			return err
+		},
+	})
+}
+
+// withoutIDs returns copies of the servers without their IDs, so that the servers of a snapshot can be added back:
+// NGINX assigns the ID of an added server and rejects a server that has one.
+func withoutIDs[S client.Server](servers []S) []S {
+	result := make([]S, len(servers))
+	for i, server := range servers {
+		switch s := any(&server).(type) {
+		case *client.UpstreamServer:
+			s.ID = 0
+		case *client.StreamUpstreamServer:
+			s.ID = 0
+		}
+		result[i] = server
+	}
+	return result
+}
+
+// AddKeyValPair adds the key-value pair to the HTTP keyval zone on all members.
+// With the AllOrNothing policy, the key is deleted again from the members it was added to if adding it fails on any member.
+func (f *Fleet) AddKeyValPair(ctx context.Context, zone string, key string, val string) (Results[struct{}], error) {
+	return keyValChange(ctx, f, httpKeyVals, keyValAdd, zone, key, val)
+}
+
+// AddStreamKeyValPair adds the key-value pair to the stream keyval zone on all members.
+// With the AllOrNothing policy, the key is deleted again from the members it was added to if adding it fails on any member.
+func (f *Fleet) AddStreamKeyValPair(ctx context.Context, zone string, key string, val string) (Results[struct{}], error) {
+	return keyValChange(ctx, f, streamKeyVals, keyValAdd, zone, key, val)
+}
+
+// ModifyKeyValPair modifies the value of the key in the HTTP keyval zone on all members.
+// With the AllOrNothing policy, the value of every member is read first and
+// restored on the members it was modified on if modifying it fails on any member. A key that didn't exist is deleted again.
+func (f *Fleet) ModifyKeyValPair(ctx context.Context, zone string, key string, val string) (Results[struct{}], error) {
+	return keyValChange(ctx, f, httpKeyVals, keyValModify, zone, key, val)
+}
+
+// ModifyStreamKeyValPair modifies the value of the key in the stream keyval zone on all members.
+// With the AllOrNothing policy, the value of every member is read first and
+// restored on the members it was modified on if modifying it fails on any member. A key that didn't exist is deleted again.
+func (f *Fleet) ModifyStreamKeyValPair(ctx context.Context, zone string, key string, val string) (Results[struct{}], error) {
+	return keyValChange(ctx, f, streamKeyVals, keyValModify, zone, key, val)
+}
+
+// DeleteKeyValuePair deletes the key from the HTTP keyval zone on all members.
+// With the AllOrNothing policy, the value of every member is read first and
+// added back to the members it was deleted from if deleting it fails on any member. A key that didn't exist stays deleted.
+func (f *Fleet) DeleteKeyValuePair(ctx context.Context, zone string, key string) (Results[struct{}], error) {
+	return keyValChange(ctx, f, httpKeyVals, keyValDelete, zone, key, "")
+}
+
+// DeleteStreamKeyValuePair deletes the key from the stream keyval zone on all members.
+// With the AllOrNothing policy, the value of every member is read first and
+// added back to the members it was deleted from if deleting it fails on any member. A key that didn't exist stays deleted.
+func (f *Fleet) DeleteStreamKeyValuePair(ctx context.Context, zone string, key string) (Results[struct{}], error) {
+	return keyValChange(ctx, f, streamKeyVals, keyValDelete, zone, key, "")
+}
+
+type keyValAction int
+
+const (
+	keyValAdd keyValAction = iota
+	keyValModify
+	keyValDelete
+)
+
+type keyValAPI struct {
+	get    func(ctx context.Context, zone string) (client.KeyValPairs, error)
+	add    func(ctx context.Context, zone string, key string, val string) error
+	modify func(ctx context.Context, zone string, key string, val string) error
+	delete func(ctx context.Context, zone string, key string) error
+}
+
+func httpKeyVals(c client.KeyValStore) keyValAPI {
+	return keyValAPI{get: c.GetKeyValPairs, add: c.AddKeyValPair, modify: c.ModifyKeyValPair, delete: c.DeleteKeyValuePair}
+}
+
+func streamKeyVals(c client.KeyValStore) keyValAPI {
+	return keyValAPI{get: c.GetStreamKeyValPairs, add: c.AddStreamKeyValPair, modify: c.ModifyStreamKeyValPair, delete: c.DeleteStreamKeyValuePair}
+}
+
+// keyValSnapshot is the value of a key before a change, and whether the key existed.
+type keyValSnapshot struct {
+	val    string
+	exists bool
+}
+
+func keyValChange(ctx context.Context, f *Fleet, api func(client.KeyValStore) keyValAPI, action keyValAction, zone, key, val string) (Results[struct{}], error) {
+	c := change[struct{}, keyValSnapshot]{
+		apply: func(ctx context.Context, m Member) (struct{}, error) {
+			kv := api(m.Client)
+			switch action {
+			case keyValAdd:
+				return struct{}{}, kv.add(ctx, zone, key, val)
+			case keyValModify:
+				return struct{}{}, kv.modify(ctx, zone, key, val)
+			default:
+				return struct{}{}, kv.delete(ctx, zone, key)
+			}
+		},
+	}
+
+	switch action {
+	case keyValAdd:
+		c.name = "add key " + key + " to keyval zone " + zone
+		c.restore = func(ctx context.Context, m Member, _ keyValSnapshot) error {
+			return api(m.Client).delete(ctx, zone, key)
+		}
+	case keyValModify, keyValDelete:
+		c.name = "modify key " + key + " of keyval zone " + zone
+		if action == keyValDelete {
+			c.name = "delete key " + key + " from keyval zone " + zone
+		}
+		c.snapshot = func(ctx context.Context, m Member) (keyValSnapshot, error) {
+			pairs, err := api(m.Client).get(ctx, zone)
+			val, exists := pairs[key]
+			return keyValSnapshot{val: val, exists: exists}, err
+		}
+		c.restore = func(ctx context.Context, m Member, snapshot keyValSnapshot) error {
+			switch {
+			case !snapshot.exists:
+				return api(m.Client).delete(ctx, zone, key)
+			case action == keyValDelete:
+				return api(m.Client).add(ctx, zone, key, snapshot.val)
+			default:
+				return api(m.Client).modify(ctx, zone, key, snapshot.val)
+			}
+		}
+	}
+	return mutate(ctx, f, c)
+}
+
+// GetStats gets the stats of all members.
+// The error reports whether enough members returned their stats for the consistency policy.
+func (f *Fleet) GetStats(ctx context.Context) (Results[*client.Stats], error) {
+	results := run(ctx, f, f.all(), func(ctx context.Context, _ int, m Member) (*client.Stats, error) {
+		return m.Client.GetStats(ctx)
+	})
+	return results, check(f, results)
+}
+
+// MergeStats merges the stats of the members that GetStats succeeded on with client.MergeStats.
+// The stats of each member are available by member name in the Instances of the result.
+func MergeStats(results Results[*client.Stats]) *client.AggregatedStats {
+	stats := make(map[string]*client.Stats, len(results))
+	for _, result := range results.Succeeded() {
+		stats[result.Member] = result.Value
+	}
+	return client.MergeStats(stats)
+}
@@ -0,0 +1,269 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// ErrRolloutHalted is returned when a rollout is halted and the change is rolled back.
+var ErrRolloutHalted = errors.New("rollout halted")
+
+// HealthThresholds are the limits on the health of an upstream during the bake period of a rollout.
+type HealthThresholds struct {
+	// MaxUnhealthyPeers is the number of peers of the upstream that can become "unhealthy" or "unavail" on a member
+	// during the bake period. Peers that are already unhealthy at the start of the bake period are not counted.
+	MaxUnhealthyPeers int
+	// Max5xxRate is the highest fraction, from 0 to 1, of the responses of the peers of the upstream
+	// that can have a 5xx status on a member, counted from the start of the bake period.
+	Max5xxRate float64
+	// MinRequests is the number of responses a member must have sent from the start of the bake period
+	// before Max5xxRate is checked.
+	MinRequests uint64
+}
+
+// DefaultHealthThresholds are the thresholds used if WithHealthThresholds is not passed to a rollout.
+var DefaultHealthThresholds = HealthThresholds{
+	MaxUnhealthyPeers: 0,
+	Max5xxRate:        0.05,
+	MinRequests:       20,
+}
+
+type rolloutConfig struct {
+	thresholds    HealthThresholds
+	canaries      int
+	waveSize      int
+	bakeTime      time.Duration
+	checkInterval time.Duration
+}
+
+// RolloutOption configures a rollout.
+type RolloutOption func(*rolloutConfig)
+
+// WithCanaries sets the number of members the change is made on first. One member is used by default.
+func WithCanaries(n int) RolloutOption {
+	return func(c *rolloutConfig) {
+		c.canaries = n
+	}
+}
+
+// WithWaveSize sets the number of members the change is made on in each wave after the canaries.
+// By default, the change is made on all the other members in one wave.
+func WithWaveSize(n int) RolloutOption {
+	return func(c *rolloutConfig) {
+		c.waveSize = n
+	}
+}
+
+// WithBakeTime sets how long the health of the upstream is watched after each wave and how often it is checked.
+// By default, it is watched for 1 minute and checked every 5 seconds.
+func WithBakeTime(bakeTime, checkInterval time.Duration) RolloutOption {
+	return func(c *rolloutConfig) {
+		c.bakeTime = bakeTime
+		c.checkInterval = checkInterval
+	}
+}
+
+// WithHealthThresholds sets the thresholds that halt the rollout. DefaultHealthThresholds are used by default.
+func WithHealthThresholds(thresholds HealthThresholds) RolloutOption {
+	return func(c *rolloutConfig) {
+		c.thresholds = thresholds
+	}
+}
+
+// RolloutError is returned when a rollout is halted. It wraps ErrRolloutHalted and the cause.
+type RolloutError struct {
+	Err    error
+	Member string
+	// Wave is the wave the rollout was halted in. The canaries are wave 0.
+	Wave int
+}
+
+func (e *RolloutError) Error() string {
+	return fmt.Sprintf("%v in wave %v on member %v: %v", ErrRolloutHalted, e.Wave, e.Member, e.Err)
+}
+
+func (e *RolloutError) Unwrap() []error {
+	return []error{ErrRolloutHalted, e.Err}
+}
+
+// RolloutHTTPServers updates the servers of the HTTP upstream with client.NginxClient.UpdateHTTPServers on the members
+// in waves: first on the canaries, then on the other members in waves of the wave size, in the order of the members.
+// After each wave, the health of the upstream on the members of the wave is checked with GetUpstreams for the bake time.
+// If the update fails, the thresholds are exceeded or the context is done, the rollout is halted
+// and the servers of all the members that were updated are updated back, whatever the policy of the fleet.
+// The results are the results of the members the rollout reached.
+func (f *Fleet) RolloutHTTPServers(ctx context.Context, upstream string, servers []client.UpstreamServer, opts ...RolloutOption) (Results[client.ServerUpdates[client.UpstreamServer]], error) {
+	cfg := rolloutConfig{
+		canaries:      1,
+		bakeTime:      time.Minute,
+		checkInterval: 5 * time.Second,
+		thresholds:    DefaultHealthThresholds,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.canaries < 1 || cfg.waveSize < 0 || cfg.checkInterval <= 0 {
+		return nil, fmt.Errorf("%w: canaries %v, wave size %v, check interval %v", ErrInvalidFleet, cfg.canaries, cfg.waveSize, cfg.checkInterval)
+	}
+
+	var results Results[client.ServerUpdates[client.UpstreamServer]]
+	var updated []int
+	snapshots := make([][]client.UpstreamServer, len(f.members))
+	for wave, members := range cfg.waves(len(f.members)) {
+		f.logger.InfoContext(ctx, "starting wave of rollout", "upstream", upstream, "wave", wave, "members", len(members))
+
+		reads := run(ctx, f, members, func(ctx context.Context, i int, m Member) ([]client.UpstreamServer, error) {
+			servers, err := m.Client.GetHTTPServers(ctx, upstream)
+			snapshots[i] = withoutIDs(servers)
+			return servers, err
+		})
+		if failed := reads.Failed(); len(failed) > 0 {
+			return f.haltRollout(ctx, upstream, results, updated, snapshots, &RolloutError{Wave: wave, Member: failed[0].Member, Err: failed[0].Err})
+		}
+
+		waveResults := run(ctx, f, members, func(ctx context.Context, _ int, m Member) (client.ServerUpdates[client.UpstreamServer], error) {
+			added, deleted, updated, err := m.Client.UpdateHTTPServers(ctx, upstream, servers)
+			return client.ServerUpdates[client.UpstreamServer]{Added: added, Deleted: deleted, Updated: updated}, err
+		})
+		results = append(results, waveResults...)
+		// A failed update can still have changed some servers, so it is rolled back too.
+		updated = append(updated, members...)
+		if failed := waveResults.Failed(); len(failed) > 0 {
+			return f.haltRollout(ctx, upstream, results, updated, snapshots, &RolloutError{Wave: wave, Member: failed[0].Member, Err: failed[0].Err})
+		}
+
+		if err := f.bake(ctx, upstream, members, cfg); err != nil {
+			err.Wave = wave
+			return f.haltRollout(ctx, upstream, results, updated, snapshots, err)
+		}
+	}
+	return results, nil
+}
+
+// waves returns the indexes of the members in each wave.
+func (c rolloutConfig) waves(members int) [][]int {
+	var waves [][]int
+	size := min(c.canaries, members)
+	for start := 0; start < members; {
+		wave := make([]int, 0, size)
+		for i := start; i < start+size && i < members; i++ {
+			wave = append(wave, i)
+		}
+		waves = append(waves, wave)
+		start += size
+		size = c.waveSize
+		if size == 0 {
+			size = members - start
+		}
+	}
+	return waves
+}
+
+// peerCounters are the response counters and the health of a peer at the start of the bake period.
+type peerCounters struct {
+	total     uint64
+	errors    uint64
+	unhealthy bool
+}
+
+// bake checks the health of the upstream on the members until the bake time is over.
+func (f *Fleet) bake(ctx context.Context, upstream string, members []int, cfg rolloutConfig) *RolloutError {
+	baselines := make([]map[int]peerCounters, len(f.members))
+	deadline := time.Now().Add(cfg.bakeTime)
+	ticker := time.NewTicker(cfg.checkInterval)
+	defer ticker.Stop()
+	for {
+		checks := run(ctx, f, members, func(ctx context.Context, i int, m Member) (struct{}, error) {
+			upstreams, err := m.Client.GetUpstreams(ctx)
+			if err != nil {
+				return struct{}{}, fmt.Errorf("failed to check the health of upstream %v: %w", upstream, err)
+			}
+			stats, ok := (*upstreams)[upstream]
+			if !ok {
+				return struct{}{}, fmt.Errorf("upstream %v: %w", upstream, client.ErrUpstreamNotFound)
+			}
+			if baselines[i] == nil {
+				baselines[i] = counters(stats.Peers)
+			}
+			return struct{}{}, cfg.thresholds.check(baselines[i], stats.Peers)
+		})
+		if failed := checks.Failed(); len(failed) > 0 {
+			return &RolloutError{Member: failed[0].Member, Err: failed[0].Err}
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return &RolloutError{Member: f.members[members[0]].Name, Err: context.Cause(ctx)}
+		case <-ticker.C:
+		}
+	}
+}
+
+func counters(peers []client.Peer) map[int]peerCounters {
+	c := make(map[int]peerCounters, len(peers))
+	for _, p := range peers {
+		c[p.ID] = peerCounters{total: p.Responses.Total, errors: p.Responses.Responses5xx, unhealthy: isUnhealthy(p)}
+	}
+	return c
+}
+
+func isUnhealthy(p client.Peer) bool {
+	return p.State == "unhealthy" || p.State == "unavail"
+}
+
+// check returns an error if the peers exceed the thresholds.
+// Only the peers that became unhealthy since the baseline are counted as unhealthy.
+// Peers that are not in the baseline, or whose counters went down, are counted from zero.
+func (t HealthThresholds) check(baseline map[int]peerCounters, peers []client.Peer) error {
+	var unhealthy int
+	var total, errs uint64
+	for _, p := range peers {
+		base := baseline[p.ID]
+		if isUnhealthy(p) && !base.unhealthy {
+			unhealthy++
+		}
+		if p.Responses.Total < base.total || p.Responses.Responses5xx < base.errors {
+			base = peerCounters{}
+		}
+		total += p.Responses.Total - base.total
+		errs += p.Responses.Responses5xx - base.errors
+	}
+
+	if unhealthy > t.MaxUnhealthyPeers {
+		return fmt.Errorf("%v unhealthy peers exceed the limit of %v", unhealthy, t.MaxUnhealthyPeers)
+	}
+	if total > 0 && total >= t.MinRequests {
+		if rate := float64(errs) / float64(total); rate > t.Max5xxRate {
+			return fmt.Errorf("5xx rate %.3f exceeds the limit of %.3f", rate, t.Max5xxRate)
+		}
+	}
+	return nil
+}
+
+// haltRollout updates the servers of the updated members back to their snapshots.
+func (f *Fleet) haltRollout(ctx context.Context, upstream string, results Results[client.ServerUpdates[client.UpstreamServer]],
+	updated []int, snapshots [][]client.UpstreamServer, halt *RolloutError,
+) (Results[client.ServerUpdates[client.UpstreamServer]], error) {
+	f.logger.WarnContext(ctx, "halting rollout", "upstream", upstream, "error", halt, "members", len(updated))
+	// The change must be rolled back even if the context of the rollout is canceled.
+	restored := run(context.WithoutCancel(ctx), f, updated, func(ctx context.Context, i int, m Member) (struct{}, error) {
+		_, _, _, err := m.Client.UpdateHTTPServers(ctx, upstream, snapshots[i])
+		return struct{}{}, err
+	})
+	// The results are in the same order as the updated members.
+	for i, r := range restored {
+		results[i].RolledBack = r.Err == nil
+		results[i].RollbackErr = r.Err
+		if r.Err != nil {
+			f.logger.ErrorContext(ctx, "failed to roll back rollout on fleet member", "upstream", upstream, "member", r.Member, "error", r.Err)
+		}
+	}
+	return results, halt
+}
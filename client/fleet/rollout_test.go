@@ -0,0 +1,207 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+func TestWaves(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		cfg      rolloutConfig
+		expected [][]int
+		members  int
+	}{
+		{cfg: rolloutConfig{canaries: 1}, members: 4, expected: [][]int{{0}, {1, 2, 3}}},
+		{cfg: rolloutConfig{canaries: 1, waveSize: 2}, members: 4, expected: [][]int{{0}, {1, 2}, {3}}},
+		{cfg: rolloutConfig{canaries: 2, waveSize: 1}, members: 3, expected: [][]int{{0, 1}, {2}}},
+		{cfg: rolloutConfig{canaries: 3}, members: 2, expected: [][]int{{0, 1}}},
+	}
+	for _, test := range tests {
+		if waves := test.cfg.waves(test.members); !reflect.DeepEqual(waves, test.expected) {
+			t.Errorf("%+v: expected waves %v, got %v", test.cfg, test.expected, waves)
+		}
+	}
+}
+
+func TestHealthThresholds(t *testing.T) {
+	t.Parallel()
+	peer := func(id int, state string, total, errs uint64) client.Peer {
+		return client.Peer{ID: id, State: state, Responses: client.Responses{Total: total, Responses5xx: errs}}
+	}
+	baseline := counters([]client.Peer{peer(1, "up", 100, 50), peer(3, "unhealthy", 0, 0)})
+	tests := []struct {
+		msg     string
+		peers   []client.Peer
+		wantErr bool
+	}{
+		{msg: "healthy", peers: []client.Peer{peer(1, "up", 200, 51)}},
+		{msg: "unhealthy peer", peers: []client.Peer{peer(1, "unhealthy", 100, 50)}, wantErr: true},
+		{msg: "5xx since the baseline", peers: []client.Peer{peer(1, "up", 200, 60)}, wantErr: true},
+		{msg: "too few requests", peers: []client.Peer{peer(1, "up", 110, 60)}},
+		{msg: "new peer", peers: []client.Peer{peer(1, "up", 100, 50), peer(2, "checking", 30, 30)}, wantErr: true},
+		{msg: "counters reset", peers: []client.Peer{peer(1, "up", 30, 0)}},
+		{msg: "unhealthy at the baseline", peers: []client.Peer{peer(1, "up", 200, 51), peer(3, "unhealthy", 0, 0)}},
+		{msg: "unavail peer", peers: []client.Peer{peer(1, "unavail", 100, 50), peer(3, "unhealthy", 0, 0)}, wantErr: true},
+	}
+	for _, test := range tests {
+		if err := DefaultHealthThresholds.check(baseline, test.peers); test.wantErr != (err != nil) {
+			t.Errorf("%v: expected error %v, got %v", test.msg, test.wantErr, err)
+		}
+	}
+}
+
+func TestRolloutHTTPServers(t *testing.T) {
+	t.Parallel()
+	desired := []client.UpstreamServer{{Server: "10.0.0.2:80"}}
+	initial := []client.UpstreamServer{{Server: "10.0.0.1:80"}}
+	tests := []struct {
+		msg string
+		// unhealthy is the member whose upstream becomes unhealthy after the change.
+		unhealthy string
+		fail      string
+		expected  [][]client.UpstreamServer
+		updated   int
+		wave      int
+	}{
+		{
+			msg:      "all waves succeed",
+			expected: [][]client.UpstreamServer{desired, desired, desired, desired},
+			updated:  4,
+		},
+		{
+			msg:       "unhealthy canary",
+			unhealthy: "nginx-0",
+			expected:  [][]client.UpstreamServer{initial, initial, initial, initial},
+			updated:   1,
+		},
+		{
+			msg:       "unhealthy member of second wave",
+			unhealthy: "nginx-2",
+			expected:  [][]client.UpstreamServer{initial, initial, initial, initial},
+			updated:   3,
+			wave:      1,
+		},
+		{
+			msg:      "failed update in last wave",
+			fail:     "UpdateHTTPServers",
+			expected: [][]client.UpstreamServer{initial, initial, initial, initial},
+			updated:  4,
+			wave:     2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			last := newFakeMember()
+			if test.fail != "" {
+				last = newFakeMember(test.fail)
+			}
+			fakes := []*fakeMember{newFakeMember(), newFakeMember(), newFakeMember(), last}
+			for i, fake := range fakes {
+				var checks atomic.Int32
+				fake.GetUpstreamsFunc = func(context.Context) (*client.Upstreams, error) {
+					state := "up"
+					if test.unhealthy == fmt.Sprintf("nginx-%d", i) && checks.Add(1) > 1 {
+						state = "unhealthy"
+					}
+					return &client.Upstreams{"foo": {Peers: []client.Peer{{ID: 1, State: state}}}}, nil
+				}
+			}
+			f := newFleet(t, fakes)
+
+			results, err := f.RolloutHTTPServers(context.Background(), "foo", desired,
+				WithWaveSize(2), WithBakeTime(5*time.Millisecond, time.Millisecond))
+			if len(results) != test.updated {
+				t.Fatalf("expected results of %v members, got %+v", test.updated, results)
+			}
+			for i, fake := range fakes {
+				if servers, _ := fake.getServers(""); !reflect.DeepEqual(servers, test.expected[i]) {
+					t.Errorf("member %v: expected servers %v, got %v", i, test.expected[i], servers)
+				}
+			}
+			if test.updated == len(fakes) && test.fail == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var rolloutErr *RolloutError
+			if !errors.As(err, &rolloutErr) || !errors.Is(err, ErrRolloutHalted) {
+				t.Fatalf("expected a RolloutError, got %v", err)
+			}
+			if rolloutErr.Wave != test.wave {
+				t.Fatalf("expected the rollout to halt in wave %v, got %v", test.wave, rolloutErr.Wave)
+			}
+			for _, result := range results {
+				if !result.RolledBack {
+					t.Fatalf("expected member %v to be rolled back", result.Member)
+				}
+			}
+		})
+	}
+}
+
+func TestRolloutHaltAddsDeletedServers(t *testing.T) {
+	t.Parallel()
+	fakes := []*fakeMember{newFakeMember(), newFakeMember()}
+	for _, fake := range fakes {
+		fake.servers = []client.UpstreamServer{{ID: 7, Server: "10.0.0.1:80"}}
+		var checks atomic.Int32
+		fake.GetUpstreamsFunc = func(context.Context) (*client.Upstreams, error) {
+			state := "up"
+			if checks.Add(1) > 1 {
+				state = "unhealthy"
+			}
+			return &client.Upstreams{"foo": {Peers: []client.Peer{{ID: 1, State: state}}}}, nil
+		}
+	}
+	f := newFleet(t, fakes)
+
+	results, err := f.RolloutHTTPServers(context.Background(), "foo", []client.UpstreamServer{{Server: "10.0.0.2:80"}},
+		WithBakeTime(5*time.Millisecond, time.Millisecond))
+	if !errors.Is(err, ErrRolloutHalted) {
+		t.Fatalf("expected %v, got %v", ErrRolloutHalted, err)
+	}
+	if len(results) != 1 || !results[0].RolledBack || results[0].RollbackErr != nil {
+		t.Fatalf("expected the canary to be rolled back, got %+v", results)
+	}
+	expected := []client.UpstreamServer{{Server: "10.0.0.1:80"}}
+	if servers, _ := fakes[0].getServers(""); !reflect.DeepEqual(servers, expected) {
+		t.Fatalf("expected servers %v, got %v", expected, servers)
+	}
+}
+
+func TestRolloutIgnoresPeersUnhealthyBeforeTheChange(t *testing.T) {
+	t.Parallel()
+	fakes := []*fakeMember{newFakeMember(), newFakeMember()}
+	for _, fake := range fakes {
+		fake.GetUpstreamsFunc = func(context.Context) (*client.Upstreams, error) {
+			return &client.Upstreams{"foo": {Peers: []client.Peer{{ID: 1, State: "up"}, {ID: 2, State: "unhealthy"}}}}, nil
+		}
+	}
+	f := newFleet(t, fakes)
+
+	desired := []client.UpstreamServer{{Server: "10.0.0.2:80"}}
+	results, err := f.RolloutHTTPServers(context.Background(), "foo", desired, WithBakeTime(5*time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(fakes) {
+		t.Fatalf("expected results of %v members, got %+v", len(fakes), results)
+	}
+	for i, fake := range fakes {
+		if servers, _ := fake.getServers(""); !reflect.DeepEqual(servers, desired) {
+			t.Errorf("member %v: expected servers %v, got %v", i, desired, servers)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrGuardViolated is returned for a change that violates the UpdateGuards.
+var ErrGuardViolated = errors.New("update guard violated")
+
+const peerStateUp = "up"
+
+// Guard is a threshold of the UpdateGuards.
+type Guard string
+
+const (
+	// GuardRemoveAll refuses changes that remove every server of the upstream.
+	GuardRemoveAll Guard = "remove_all"
+	// GuardMaxRemovals caps the number of servers removed.
+	GuardMaxRemovals Guard = "max_removals"
+	// GuardMaxRemovalPercent caps the percentage of servers removed.
+	GuardMaxRemovalPercent Guard = "max_removal_percent"
+	// GuardMinHealthyPeers requires a minimum number of healthy peers to remain.
+	GuardMinHealthyPeers Guard = "min_healthy_peers"
+)
+
+// UpdateGuards protect upstreams from changes that remove too many servers at once,
+// for example when a service discovery feed returns an empty list.
+// The guards are checked by UpdateHTTPServers, UpdateStreamServers, ReconcileHTTPServers and ReconcileStreamServers
+// before any change is made.
+type UpdateGuards struct {
+	// MaxRemovalPercent is the maximum percentage of the servers of the upstream that can be removed. Zero means no limit.
+	MaxRemovalPercent float64
+	// MaxRemovals is the maximum number of servers that can be removed. Zero means no limit.
+	MaxRemovals int
+	// MinHealthyPeers is the minimum number of peers in the "up" state, as reported by GetUpstreams or GetStreamUpstreams,
+	// that must remain after the servers are removed or marked as down. Zero means no minimum.
+	// Changes that don't remove servers or mark them as down are always allowed.
+	MinHealthyPeers int
+	// AllowRemoveAll allows changes that leave the upstream without servers.
+	AllowRemoveAll bool
+}
+
+// WithUpdateGuards sets the guards checked by the methods that update all the servers of an upstream.
+// Once set, the guards refuse to remove all servers of an upstream unless AllowRemoveAll is true.
+func WithUpdateGuards(guards UpdateGuards) Option {
+	return func(o *NginxClient) {
+		o.guards = &guards
+	}
+}
+
+// GuardError is returned when a change violates the UpdateGuards. No change is made.
+type GuardError struct {
+	Upstream string
+	Guard    Guard
+	// Limit is the threshold of the guard.
+	Limit float64
+	// Value is the value for the change: the number or percentage of removed servers, or the number of remaining healthy peers.
+	Value float64
+}
+
+func (e *GuardError) Error() string {
+	var reason string
+	switch e.Guard {
+	case GuardRemoveAll:
+		reason = fmt.Sprintf("all %v servers would be removed", e.Value)
+	case GuardMaxRemovals:
+		reason = fmt.Sprintf("%v servers would be removed, the maximum is %v", e.Value, e.Limit)
+	case GuardMaxRemovalPercent:
+		reason = fmt.Sprintf("%.1f%% of the servers would be removed, the maximum is %v%%", e.Value, e.Limit)
+	case GuardMinHealthyPeers:
+		reason = fmt.Sprintf("%v healthy peers would remain, the minimum is %v", e.Value, e.Limit)
+	}
+	return fmt.Sprintf("%v %v of upstream %v: %v", ErrGuardViolated, e.Guard, e.Upstream, reason)
+}
+
+func (e *GuardError) Unwrap() error {
+	return ErrGuardViolated
+}
+
+// checkUpdateGuards checks the change of the servers of the upstream against the guards of the client.
+// remaining is the number of servers the upstream will have after the change.
+func checkUpdateGuards[S Server](ctx context.Context, client *NginxClient, upstream string, stream bool, current []S, remaining int, toDelete, toUpdate []S) error {
+	guards := client.guards
+	if guards == nil {
+		return nil
+	}
+
+	removed := len(toDelete)
+	if removed > 0 && remaining == 0 && !guards.AllowRemoveAll {
+		return &GuardError{Upstream: upstream, Guard: GuardRemoveAll, Value: float64(removed)}
+	}
+	if guards.MaxRemovals > 0 && removed > guards.MaxRemovals {
+		return &GuardError{Upstream: upstream, Guard: GuardMaxRemovals, Limit: float64(guards.MaxRemovals), Value: float64(removed)}
+	}
+	if guards.MaxRemovalPercent > 0 && len(current) > 0 {
+		percent := float64(removed) / float64(len(current)) * 100
+		if percent > guards.MaxRemovalPercent {
+			return &GuardError{Upstream: upstream, Guard: GuardMaxRemovalPercent, Limit: guards.MaxRemovalPercent, Value: percent}
+		}
+	}
+
+	if guards.MinHealthyPeers <= 0 {
+		return nil
+	}
+	lost := make(map[int]struct{}, removed)
+	for _, server := range toDelete {
+		lost[server.ServerID()] = struct{}{}
+	}
+	for _, server := range toUpdate {
+		if server.IsDown() {
+			lost[server.ServerID()] = struct{}{}
+		}
+	}
+	if len(lost) == 0 {
+		return nil
+	}
+
+	peers, err := client.peerStates(ctx, upstream, stream)
+	if err != nil {
+		return fmt.Errorf("failed to check healthy peers: %w", err)
+	}
+	remainingHealthy := 0
+	for _, peer := range peers {
+		if _, ok := lost[peer.id]; !ok && peer.state == peerStateUp {
+			remainingHealthy++
+		}
+	}
+	if remainingHealthy < guards.MinHealthyPeers {
+		return &GuardError{Upstream: upstream, Guard: GuardMinHealthyPeers, Limit: float64(guards.MinHealthyPeers), Value: float64(remainingHealthy)}
+	}
+	return nil
+}
+
+type peerState struct {
+	name  string
+	state string
+	id    int
+}
+
+// peerStates returns the state of the peers of the upstream, as reported by GetUpstreams or GetStreamUpstreams.
+func (client *NginxClient) peerStates(ctx context.Context, upstream string, stream bool) ([]peerState, error) {
+	var peers []peerState
+	if stream {
+		upstreams, err := client.GetStreamUpstreams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, peer := range (*upstreams)[upstream].Peers {
+			peers = append(peers, peerState{id: peer.ID, name: peer.Name, state: peer.State})
+		}
+		return peers, nil
+	}
+
+	upstreams, err := client.GetUpstreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, peer := range (*upstreams)[upstream].Peers {
+		peers = append(peers, peerState{id: peer.ID, name: peer.Name, state: peer.State})
+	}
+	return peers, nil
+}
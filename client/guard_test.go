@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpdateGuards(t *testing.T) {
+	t.Parallel()
+	down := true
+	tests := []struct {
+		msg     string
+		servers []UpstreamServer
+		guard   Guard
+		guards  UpdateGuards
+	}{
+		{
+			msg:    "remove all",
+			guards: UpdateGuards{},
+			guard:  GuardRemoveAll,
+		},
+		{
+			msg:    "remove all allowed",
+			guards: UpdateGuards{AllowRemoveAll: true},
+		},
+		{
+			msg:     "replace all",
+			guards:  UpdateGuards{},
+			servers: []UpstreamServer{{Server: "10.0.0.9:80"}},
+		},
+		{
+			msg:     "too many removals",
+			guards:  UpdateGuards{MaxRemovals: 2},
+			servers: []UpstreamServer{{Server: "10.0.0.1:80"}},
+			guard:   GuardMaxRemovals,
+		},
+		{
+			msg:     "removals within limit",
+			guards:  UpdateGuards{MaxRemovals: 2},
+			servers: []UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}},
+		},
+		{
+			msg:     "too large percentage of removals",
+			guards:  UpdateGuards{MaxRemovalPercent: 50},
+			servers: []UpstreamServer{{Server: "10.0.0.1:80"}},
+			guard:   GuardMaxRemovalPercent,
+		},
+		{
+			msg:     "percentage of removals within limit",
+			guards:  UpdateGuards{MaxRemovalPercent: 50},
+			servers: []UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}},
+		},
+		{
+			msg:     "too few healthy peers",
+			guards:  UpdateGuards{MinHealthyPeers: 3},
+			servers: []UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.3:80"}, {Server: "10.0.0.4:80"}},
+			guard:   GuardMinHealthyPeers,
+		},
+		{
+			msg:     "server marked as down",
+			guards:  UpdateGuards{MinHealthyPeers: 3},
+			servers: []UpstreamServer{{Server: "10.0.0.1:80", Down: &down}, {Server: "10.0.0.2:80"}, {Server: "10.0.0.3:80"}, {Server: "10.0.0.4:80"}},
+			guard:   GuardMinHealthyPeers,
+		},
+		{
+			msg:     "unhealthy server removed",
+			guards:  UpdateGuards{MinHealthyPeers: 3},
+			servers: []UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}, {Server: "10.0.0.4:80"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			var changes atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost:
+					changes.Add(1)
+					w.WriteHeader(http.StatusCreated)
+				case r.Method != http.MethodGet:
+					changes.Add(1)
+					_, _ = w.Write([]byte(`{}`))
+				case strings.HasSuffix(r.URL.Path, "/servers"):
+					_, _ = w.Write([]byte(`[{"id":1,"server":"10.0.0.1:80"},{"id":2,"server":"10.0.0.2:80"},{"id":3,"server":"10.0.0.3:80"},{"id":4,"server":"10.0.0.4:80"}]`))
+				default:
+					_, _ = w.Write([]byte(`{"foo":{"peers":[{"id":1,"state":"up"},{"id":2,"state":"up"},{"id":3,"state":"unhealthy"},{"id":4,"state":"up"}]}}`))
+				}
+			}))
+			defer ts.Close()
+
+			c, err := NewNginxClient(ts.URL, WithUpdateGuards(test.guards))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, _, err = c.UpdateHTTPServers(context.Background(), "foo", test.servers)
+			if test.guard == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var guardErr *GuardError
+			if !errors.As(err, &guardErr) || !errors.Is(err, ErrGuardViolated) {
+				t.Fatalf("expected a GuardError, got %v", err)
+			}
+			if guardErr.Guard != test.guard || guardErr.Upstream != "foo" {
+				t.Fatalf("expected guard %v, got %v", test.guard, guardErr)
+			}
+			if n := changes.Load(); n != 0 {
+				t.Fatalf("expected no changes, got %v", n)
+			}
+		})
+	}
+}
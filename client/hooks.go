@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Hooks receives instrumentation events from the client, for example to record traces and metrics.
+// The client/otelhooks module implements Hooks with OpenTelemetry, so that the client itself doesn't depend on it.
+// Embed NopHooks to implement only some of the methods.
+type Hooks interface {
+	// OperationStart is called before an operation runs, including the operations called by other operations
+	// such as the ones GetStats runs concurrently. The returned context is used to run the operation,
+	// so it can carry a span that the HTTP requests and the nested operations see.
+	OperationStart(ctx context.Context, op *Operation) context.Context
+	// OperationEnd is called after an operation with the context returned by OperationStart.
+	// Use errors.As with StatusError to get the NGINX error code from err.
+	OperationEnd(ctx context.Context, op *Operation, duration time.Duration, err error)
+	// RequestStart is called before an HTTP request is sent. It can add headers to the request,
+	// for example to propagate the trace context in the traceparent header.
+	RequestStart(ctx context.Context, req *http.Request)
+	// RequestEnd is called after the response to an HTTP request is received or the request fails.
+	RequestEnd(ctx context.Context, info RequestInfo)
+	// Retry is called before an operation is attempted again, with the error of the previous attempt.
+	Retry(ctx context.Context, op *Operation, attempt int, err error)
+}
+
+// RequestInfo describes an HTTP request sent to the API.
+type RequestInfo struct {
+	// Err is the error if no response was received.
+	Err        error
+	Method     string
+	Path       string
+	StatusCode int
+	Duration   time.Duration
+}
+
+// WithHooks sets the hooks that receive instrumentation events from the client.
+func WithHooks(hooks Hooks) Option {
+	return func(o *NginxClient) {
+		o.hooks = hooks
+	}
+}
+
+// NopHooks implements Hooks with methods that do nothing.
+type NopHooks struct{}
+
+var _ Hooks = NopHooks{}
+
+// OperationStart returns the context unchanged.
+func (NopHooks) OperationStart(ctx context.Context, _ *Operation) context.Context {
+	return ctx
+}
+
+// OperationEnd does nothing.
+func (NopHooks) OperationEnd(context.Context, *Operation, time.Duration, error) {}
+
+// RequestStart does nothing.
+func (NopHooks) RequestStart(context.Context, *http.Request) {}
+
+// RequestEnd does nothing.
+func (NopHooks) RequestEnd(context.Context, RequestInfo) {}
+
+// Retry does nothing.
+func (NopHooks) Retry(context.Context, *Operation, int, error) {}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idUnknown is the ID cached for servers added by the client, whose ID NGINX doesn't return.
+const idUnknown = -2
+
+type upstreamKey struct {
+	name   string
+	stream bool
+}
+
+// serverIDCache caches the IDs of the servers of upstreams by their canonical address.
+// The servers of an upstream are cached when the client lists them, and the cache is cleared when NGINX is reloaded.
+type serverIDCache struct {
+	checked       time.Time
+	upstreams     map[upstreamKey]map[string]int
+	interval      time.Duration
+	generation    uint64
+	hasGeneration bool
+	mu            sync.Mutex
+}
+
+// WithServerIDCache makes the client cache the IDs of the servers of upstreams, so that AddHTTPServer, DeleteHTTPServer,
+// AddStreamServer and DeleteStreamServer don't need to list all the servers of the upstream every time.
+// The servers of an upstream are cached when they are listed, for example with GetHTTPServers,
+// and are forgotten when a request fails with a 404 response or a conflict is detected.
+// Because NGINX assigns new IDs when it is reloaded, the cache checks the generation of NGINX with GetNginxInfo
+// before it is used, at most once per reloadCheckInterval, and is cleared when the generation changes.
+// Changes made by other clients are only seen when the servers are listed again.
+func WithServerIDCache(reloadCheckInterval time.Duration) Option {
+	return func(o *NginxClient) {
+		o.idCache = &serverIDCache{
+			interval:  reloadCheckInterval,
+			upstreams: make(map[upstreamKey]map[string]int),
+		}
+	}
+}
+
+// observeGeneration clears the cache if NGINX was reloaded.
+func (c *serverIDCache) observeGeneration(generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hasGeneration && c.generation != generation {
+		clear(c.upstreams)
+	}
+	c.generation = generation
+	c.hasGeneration = true
+	c.checked = time.Now()
+}
+
+func (c *serverIDCache) needsCheck() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.hasGeneration || time.Since(c.checked) >= c.interval
+}
+
+func (c *serverIDCache) lookup(key upstreamKey, address string) (id int, found bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids, cached := c.upstreams[key]
+	if !cached {
+		return -1, false, false
+	}
+	id, found = ids[serverKey(address)]
+	if !found {
+		return -1, false, true
+	}
+	return id, true, id != idUnknown
+}
+
+func (c *serverIDCache) set(key upstreamKey, address string, id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ids, cached := c.upstreams[key]; cached {
+		ids[serverKey(address)] = id
+	}
+}
+
+func (c *serverIDCache) remove(key upstreamKey, id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for address, cachedID := range c.upstreams[key] {
+		if cachedID == id {
+			delete(c.upstreams[key], address)
+		}
+	}
+}
+
+func (c *serverIDCache) forget(key upstreamKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.upstreams, key)
+}
+
+// cacheServers replaces the cached servers of the upstream.
+func cacheServers[S Server](client *NginxClient, stream bool, upstream string, servers []S) {
+	if client.idCache == nil {
+		return
+	}
+	ids := make(map[string]int, len(servers))
+	for _, s := range servers {
+		ids[serverKey(s.Address())] = s.ServerID()
+	}
+	client.idCache.mu.Lock()
+	defer client.idCache.mu.Unlock()
+	client.idCache.upstreams[upstreamKey{name: upstream, stream: stream}] = ids
+}
+
+// cachedServerID returns the ID of the server of the upstream from the cache.
+// ok is false if the cache can't tell whether the server exists or what its ID is.
+func (client *NginxClient) cachedServerID(ctx context.Context, stream bool, upstream string, address string) (id int, found bool, ok bool) {
+	if client.idCache == nil {
+		return -1, false, false
+	}
+	if client.idCache.needsCheck() {
+		// GetNginxInfo updates the generation of the cache.
+		if _, err := client.GetNginxInfo(ctx); err != nil {
+			return -1, false, false
+		}
+	}
+	return client.idCache.lookup(upstreamKey{name: upstream, stream: stream}, address)
+}
+
+// updateServerIDCache updates the cache after a change of a server of the upstream.
+// Servers that NGINX doesn't know and conflicts make the cached servers of the upstream stale, so they are forgotten.
+func (client *NginxClient) updateServerIDCache(stream bool, upstream string, change ServerChange, address string, id int, err error) {
+	if client.idCache == nil {
+		return
+	}
+	key := upstreamKey{name: upstream, stream: stream}
+	var conflict *ConflictError
+	switch {
+	case isNotFound(err), errors.As(err, &conflict):
+		client.idCache.forget(key)
+	case err != nil:
+	case change == ServerAdded:
+		client.idCache.set(key, address, idUnknown)
+	case change == ServerDeleted:
+		client.idCache.remove(key, id)
+	}
+}
+
+// isNotFound reports whether the error is a 404 response of the API.
+func isNotFound(err error) bool {
+	var statusErr StatusError
+	return errors.As(err, &statusErr) && statusErr.Status() == http.StatusNotFound
+}
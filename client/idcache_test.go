@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeIDs struct {
+	servers    string
+	notFound   string
+	requests   []string
+	generation int
+	mu         sync.Mutex
+}
+
+func (f *fakeIDs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/9/"), "/")
+	f.requests = append(f.requests, r.Method+" "+path)
+	switch {
+	case path == f.notFound:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"status":404,"text":"server not found","code":"UpstreamServerNotFound"}}`))
+	case path == "nginx":
+		_, _ = fmt.Fprintf(w, `{"generation":%d}`, f.generation)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/servers"):
+		_, _ = w.Write([]byte(f.servers))
+	case r.Method == http.MethodGet:
+		_, _ = w.Write([]byte(`{"id":7,"server":"10.0.0.7:80"}`))
+	case r.Method == http.MethodPost:
+		w.WriteHeader(http.StatusCreated)
+	default:
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func (f *fakeIDs) takeRequests() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := strings.Join(f.requests, ",")
+	f.requests = nil
+	return requests
+}
+
+func (f *fakeIDs) set(fn func(*fakeIDs)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+func TestServerIDCache(t *testing.T) {
+	t.Parallel()
+	fake := &fakeIDs{servers: `[{"id":1,"server":"10.0.0.1:80"},{"id":2,"server":"10.0.0.2:80"}]`}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL, WithServerIDCache(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	steps := []struct {
+		call     func() error
+		msg      string
+		requests string
+	}{
+		{
+			msg:      "list servers",
+			call:     func() error { _, err := c.GetHTTPServers(ctx, "foo"); return err },
+			requests: "GET http/upstreams/foo/servers",
+		},
+		{
+			msg:      "delete cached server",
+			call:     func() error { return c.DeleteHTTPServer(ctx, "foo", "10.0.0.1") },
+			requests: "GET nginx,DELETE http/upstreams/foo/servers/1",
+		},
+		{
+			msg:      "add server not in cache",
+			call:     func() error { return c.AddHTTPServer(ctx, "foo", UpstreamServer{Server: "10.0.0.1"}) },
+			requests: "POST http/upstreams/foo/servers",
+		},
+		{
+			msg:      "add server of upstream not in cache",
+			call:     func() error { return c.AddHTTPServer(ctx, "bar", UpstreamServer{Server: "10.0.0.5"}) },
+			requests: "GET http/upstreams/bar/servers,POST http/upstreams/bar/servers",
+		},
+		{
+			msg: "stale ID",
+			call: func() error {
+				fake.set(func(f *fakeIDs) {
+					f.notFound = "http/upstreams/foo/servers/2"
+					f.servers = `[{"id":3,"server":"10.0.0.2:80"}]`
+				})
+				return c.DeleteHTTPServer(ctx, "foo", "10.0.0.2")
+			},
+			requests: "DELETE http/upstreams/foo/servers/2,GET http/upstreams/foo/servers,DELETE http/upstreams/foo/servers/3",
+		},
+		{
+			msg: "reload",
+			call: func() error {
+				fake.set(func(f *fakeIDs) { f.generation = 2 })
+				if _, err := c.GetNginxInfo(ctx); err != nil {
+					return err
+				}
+				return c.DeleteHTTPServer(ctx, "foo", "10.0.0.2")
+			},
+			requests: "GET nginx,GET http/upstreams/foo/servers,DELETE http/upstreams/foo/servers/3",
+		},
+	}
+
+	for _, step := range steps {
+		if err := step.call(); err != nil {
+			t.Fatalf("%v: unexpected error: %v", step.msg, err)
+		}
+		if requests := fake.takeRequests(); requests != step.requests {
+			t.Fatalf("%v: expected requests %v, got %v", step.msg, step.requests, requests)
+		}
+	}
+}
+
+func TestServersByID(t *testing.T) {
+	t.Parallel()
+	fake := &fakeIDs{notFound: "stream/upstreams/foo/servers/8"}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	server, err := c.GetHTTPServerByID(ctx, "foo", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.ID != 7 || server.Server != "10.0.0.7:80" {
+		t.Fatalf("unexpected server %+v", server)
+	}
+	if err := c.DeleteHTTPServerByID(ctx, "foo", 7); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetStreamServerByID(ctx, "foo", 8); !errors.Is(err, ErrServerNotFound) {
+		t.Fatalf("expected ErrServerNotFound, got %v", err)
+	}
+	if err := c.DeleteStreamServerByID(ctx, "foo", 8); !errors.Is(err, ErrServerNotFound) {
+		t.Fatalf("expected ErrServerNotFound, got %v", err)
+	}
+
+	expected := "GET http/upstreams/foo/servers/7,DELETE http/upstreams/foo/servers/7,GET stream/upstreams/foo/servers/8,DELETE stream/upstreams/foo/servers/8"
+	if requests := fake.takeRequests(); requests != expected {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+}
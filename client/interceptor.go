@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUnexpectedType is returned when an interceptor replaces the request or the result of an operation with a value of the wrong type.
+var ErrUnexpectedType = errors.New("unexpected type")
+
+// Operation describes a call of an NginxClient method, for example AddHTTPServer for the upstream "foo".
+// Operations called by other operations, such as GetHTTPServers called by UpdateHTTPServers, have the caller as Parent.
+type Operation struct {
+	// Request is the input of the operation, for example the UpstreamServer passed to AddHTTPServer.
+	// An interceptor may replace it with a value of the same type before calling the next invoker.
+	Request any
+	// Parent is the operation that called this one or nil for operations called directly.
+	Parent *Operation
+	// Header is added to every HTTP request sent by the operation and the operations it calls.
+	Header http.Header
+	// Name is the name of the NginxClient method.
+	Name string
+	// Upstream is the name of the upstream the operation targets, if any.
+	// Upstream, Zone, Server, Key and Mutating describe the arguments of the method and are read-only:
+	// changing them doesn't change the operation, and permissions are checked against the original values.
+	Upstream string
+	// Zone is the name of the key-value zone the operation targets, if any.
+	Zone string
+	// Server is the address of the upstream server the operation targets, if any.
+	Server string
+	// Key is the key in the key-value zone the operation targets, if any.
+	Key string
+	// Mutating is true for operations that change the state of NGINX.
+	Mutating bool
+}
+
+// Target returns the targets of the operation, for example "upstream=foo server=10.0.0.1:80".
+func (op *Operation) Target() string {
+	var targets []string
+	for _, t := range []struct{ name, value string }{
+		{"upstream", op.Upstream},
+		{"server", op.Server},
+		{"zone", op.Zone},
+		{"key", op.Key},
+	} {
+		if t.value != "" {
+			targets = append(targets, t.name+"="+t.value)
+		}
+	}
+	return strings.Join(targets, " ")
+}
+
+// String returns the name and the targets of the operation, for example "AddHTTPServer upstream=foo".
+func (op *Operation) String() string {
+	if target := op.Target(); target != "" {
+		return op.Name + " " + target
+	}
+	return op.Name
+}
+
+// Invoker runs an operation and returns its result.
+// The result has the type of the first value returned by the NginxClient method, or is nil for methods that only return an error.
+type Invoker func(ctx context.Context, op *Operation) (any, error)
+
+// Interceptor wraps operations of the NginxClient. It can inspect or modify the operation,
+// the context and the result, or return without calling next to short-circuit the operation.
+type Interceptor func(ctx context.Context, op *Operation, next Invoker) (any, error)
+
+// WithInterceptors adds interceptors that wrap every operation of the client.
+// The first interceptor is the outermost one.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *NginxClient) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+type operationKey struct{}
+
+// OperationFromContext returns the operation that is being run with the context.
+func OperationFromContext(ctx context.Context) (*Operation, bool) {
+	op, ok := ctx.Value(operationKey{}).(*Operation)
+	return op, ok
+}
+
+// invoke runs the operation through the interceptors of the client.
+func invoke[T any](ctx context.Context, client *NginxClient, op *Operation, call func(context.Context, *Operation) (T, error)) (T, error) {
+	if parent, ok := OperationFromContext(ctx); ok {
+		op.Parent = parent
+		if op.Header == nil {
+			op.Header = parent.Header.Clone()
+		}
+	}
+
+	// The permissions are checked against the operation as created from the arguments of the method,
+	// since those are what the call uses, even if an interceptor changes the targets of op.
+	original := *op
+	invoker := func(ctx context.Context, op *Operation) (any, error) {
+		if err := client.checkPermission(&original); err != nil {
+			client.auditRejected(ctx, &original, err)
+			return nil, err
+		}
+		return call(context.WithValue(ctx, operationKey{}, op), op)
+	}
+	for i := len(client.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := client.interceptors[i], invoker
+		invoker = func(ctx context.Context, op *Operation) (any, error) {
+			return interceptor(ctx, op, next)
+		}
+	}
+
+	var start time.Time
+	if client.hooks != nil {
+		ctx = client.hooks.OperationStart(ctx, op)
+		start = time.Now()
+	}
+
+	res, err := invoker(ctx, op)
+
+	if client.hooks != nil {
+		client.hooks.OperationEnd(ctx, op, time.Since(start), err)
+	}
+
+	result, ok := res.(T)
+	if !ok && res != nil {
+		return result, errors.Join(err, fmt.Errorf("result of %v of type %T: %w", op.Name, res, ErrUnexpectedType))
+	}
+	return result, err
+}
+
+// invokeErr runs the operation of a method that only returns an error through the interceptors of the client.
+func invokeErr(ctx context.Context, client *NginxClient, op *Operation, call func(context.Context, *Operation) error) error {
+	_, err := invoke(ctx, client, op, func(ctx context.Context, op *Operation) (any, error) {
+		return nil, call(ctx, op)
+	})
+	return err
+}
+
+// requestAs returns the request of the operation, which an interceptor may have replaced.
+func requestAs[T any](op *Operation) (T, error) {
+	req, ok := op.Request.(T)
+	if !ok {
+		return req, fmt.Errorf("request of %v of type %T: %w", op.Name, op.Request, ErrUnexpectedType)
+	}
+	return req, nil
+}
+
+// setOperationHeaders adds the headers of the operation run with the context of the request.
+func setOperationHeaders(req *http.Request) {
+	op, ok := OperationFromContext(req.Context())
+	if !ok {
+		return
+	}
+	for name, values := range op.Header {
+		req.Header[name] = values
+	}
+}
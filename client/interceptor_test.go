@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestInterceptorsSeeOperations(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var requestIDs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+		mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`[]`))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer ts.Close()
+
+	var calls []string
+	record := func(prefix string) Interceptor {
+		return func(ctx context.Context, op *Operation, next Invoker) (any, error) {
+			calls = append(calls, prefix+" "+op.String())
+			if op.Parent == nil {
+				op.Header = http.Header{"X-Request-Id": []string{"42"}}
+			}
+			return next(ctx, op)
+		}
+	}
+
+	c, err := NewNginxClient(ts.URL, WithInterceptors(record("outer"), record("inner")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.AddHTTPServer(context.Background(), "foo", UpstreamServer{Server: "10.0.0.1:80"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"outer AddHTTPServer upstream=foo server=10.0.0.1:80",
+		"inner AddHTTPServer upstream=foo server=10.0.0.1:80",
+		"outer GetHTTPServers upstream=foo",
+		"inner GetHTTPServers upstream=foo",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected calls %v, got %v", expected, calls)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requestIDs) != 2 || requestIDs[0] != "42" || requestIDs[1] != "42" {
+		t.Fatalf("expected the request ID header on every request, got %v", requestIDs)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected %v request to %v", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	errDenied := errors.New("denied")
+	c, err := NewNginxClient(ts.URL, WithInterceptors(func(_ context.Context, op *Operation, _ Invoker) (any, error) {
+		if op.Mutating {
+			return nil, errDenied
+		}
+		return &NginxInfo{Version: "cached"}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	info, err := c.GetNginxInfo(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Version != "cached" {
+		t.Fatalf("expected the result of the interceptor, got %v", info)
+	}
+
+	if err := c.DeleteKeyValuePair(ctx, "zone", "key"); !errors.Is(err, errDenied) {
+		t.Fatalf("expected %v, got %v", errDenied, err)
+	}
+
+	if _, err := c.GetCaches(ctx); !errors.Is(err, ErrUnexpectedType) {
+		t.Fatalf("expected %v, got %v", ErrUnexpectedType, err)
+	}
+}
+
+func TestInterceptorModifiesRequest(t *testing.T) {
+	t.Parallel()
+	var body KeyValPairs
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL, WithInterceptors(func(ctx context.Context, op *Operation, next Invoker) (any, error) {
+		op.Request = KeyValPairs{op.Key: "replaced"}
+		return next(ctx, op)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AddKeyValPair(context.Background(), "zone", "key", "value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["key"] != "replaced" {
+		t.Fatalf("expected the request modified by the interceptor, got %v", body)
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import "context"
+
+// UpstreamManager manages the servers of HTTP upstreams.
+type UpstreamManager interface {
+	CheckIfUpstreamExists(ctx context.Context, upstream string) error
+	GetHTTPServers(ctx context.Context, upstream string) ([]UpstreamServer, error)
+	GetHTTPServerByID(ctx context.Context, upstream string, id int) (UpstreamServer, error)
+	AddHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error
+	DeleteHTTPServer(ctx context.Context, upstream string, server string) error
+	DeleteHTTPServerByID(ctx context.Context, upstream string, id int) error
+	UpdateHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error
+	UpdateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer) (added []UpstreamServer, deleted []UpstreamServer, updated []UpstreamServer, err error)
+	ReconcileHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer, opts ...ReconcileOption) (ReconcileResult[UpstreamServer], error)
+}
+
+// StreamUpstreamManager manages the servers of stream upstreams.
+type StreamUpstreamManager interface {
+	CheckIfStreamUpstreamExists(ctx context.Context, upstream string) error
+	GetStreamServers(ctx context.Context, upstream string) ([]StreamUpstreamServer, error)
+	GetStreamServerByID(ctx context.Context, upstream string, id int) (StreamUpstreamServer, error)
+	AddStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer) error
+	DeleteStreamServer(ctx context.Context, upstream string, server string) error
+	DeleteStreamServerByID(ctx context.Context, upstream string, id int) error
+	UpdateStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer) error
+	UpdateStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer) (added []StreamUpstreamServer, deleted []StreamUpstreamServer, updated []StreamUpstreamServer, err error)
+	ReconcileStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer, opts ...ReconcileOption) (ReconcileResult[StreamUpstreamServer], error)
+}
+
+// KeyValStore reads and changes the key-value pairs of the HTTP and stream keyval zones.
+type KeyValStore interface {
+	GetKeyValPairs(ctx context.Context, zone string) (KeyValPairs, error)
+	GetAllKeyValPairs(ctx context.Context) (KeyValPairsByZone, error)
+	AddKeyValPair(ctx context.Context, zone string, key string, val string) error
+	ModifyKeyValPair(ctx context.Context, zone string, key string, val string) error
+	DeleteKeyValuePair(ctx context.Context, zone string, key string) error
+	DeleteKeyValPairs(ctx context.Context, zone string) error
+	GetStreamKeyValPairs(ctx context.Context, zone string) (KeyValPairs, error)
+	GetAllStreamKeyValPairs(ctx context.Context) (KeyValPairsByZone, error)
+	AddStreamKeyValPair(ctx context.Context, zone string, key string, val string) error
+	ModifyStreamKeyValPair(ctx context.Context, zone string, key string, val string) error
+	DeleteStreamKeyValuePair(ctx context.Context, zone string, key string) error
+	DeleteStreamKeyValPairs(ctx context.Context, zone string) error
+}
+
+// StatsReader reads the stats of NGINX.
+type StatsReader interface {
+	GetStats(ctx context.Context) (*Stats, error)
+	GetCaches(ctx context.Context) (*Caches, error)
+	GetSlabs(ctx context.Context) (*Slabs, error)
+	GetConnections(ctx context.Context) (*Connections, error)
+	GetHTTPRequests(ctx context.Context) (*HTTPRequests, error)
+	GetSSL(ctx context.Context) (*SSL, error)
+	GetServerZones(ctx context.Context) (*ServerZones, error)
+	GetStreamServerZones(ctx context.Context) (*StreamServerZones, error)
+	GetUpstreams(ctx context.Context) (*Upstreams, error)
+	GetStreamUpstreams(ctx context.Context) (*StreamUpstreams, error)
+	GetStreamZoneSync(ctx context.Context) (*StreamZoneSync, error)
+	GetLocationZones(ctx context.Context) (*LocationZones, error)
+	GetResolvers(ctx context.Context) (*Resolvers, error)
+	GetProcesses(ctx context.Context) (*Processes, error)
+	GetHTTPLimitReqs(ctx context.Context) (*HTTPLimitRequests, error)
+	GetHTTPConnectionsLimit(ctx context.Context) (*HTTPLimitConnections, error)
+	GetStreamConnectionsLimit(ctx context.Context) (*StreamLimitConnections, error)
+	GetWorkers(ctx context.Context) ([]*Workers, error)
+}
+
+// InstanceInfo reads information about the NGINX instance and its API.
+type InstanceInfo interface {
+	GetNginxInfo(ctx context.Context) (*NginxInfo, error)
+	GetNginxLicense(ctx context.Context) (*NginxLicense, error)
+	GetAvailableEndpoints(ctx context.Context) ([]string, error)
+	GetAvailableStreamEndpoints(ctx context.Context) ([]string, error)
+	GetMaxAPIVersion(ctx context.Context) (int, error)
+	Version() int
+}
+
+// Client is implemented by NginxClient. Code that depends on Client, or on one of the smaller interfaces it embeds,
+// can use a mock, for example from the clientmock package, or a decorator of NginxClient instead.
+type Client interface {
+	UpstreamManager
+	StreamUpstreamManager
+	KeyValStore
+	StatsReader
+	InstanceInfo
+}
+
+var _ Client = (*NginxClient)(nil)
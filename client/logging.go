@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// Redactor returns the form of a request body that is safe to log.
+// The path is the path of the request URL, for example "/api/9/http/keyvals/zone".
+type Redactor func(path string, body []byte) string
+
+// WithLogger sets the logger of the client. API requests are logged at the debug level
+// and the decisions of UpdateHTTPServers and UpdateStreamServers at the info level.
+// Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *NginxClient) {
+		o.logger = logger
+	}
+}
+
+// WithLogRedactor sets the Redactor applied to request bodies before they are logged.
+// DefaultRedactor is used by default.
+func WithLogRedactor(redactor Redactor) Option {
+	return func(o *NginxClient) {
+		o.redactor = redactor
+	}
+}
+
+// DefaultRedactor hides the values of key-value pairs, which may hold secrets, and keeps other bodies as is.
+func DefaultRedactor(path string, body []byte) string {
+	if !strings.Contains(path, "/keyvals/") {
+		return string(body)
+	}
+	var keyvals map[string]any
+	if err := json.Unmarshal(body, &keyvals); err != nil {
+		return redacted
+	}
+	for key, val := range keyvals {
+		if val != nil {
+			keyvals[key] = redacted
+		}
+	}
+	masked, err := json.Marshal(keyvals)
+	if err != nil {
+		return redacted
+	}
+	return string(masked)
+}
+
+// RedactAll hides every request body.
+func RedactAll(string, []byte) string {
+	return redacted
+}
+
+// logRequest logs the request sent to the API and its response at the debug level.
+// The request_id NGINX includes in error responses is logged as well, so the body of such responses is buffered.
+func (client *NginxClient) logRequest(ctx context.Context, req *http.Request, resp *http.Response, duration time.Duration, err error) {
+	if !client.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Duration("duration", duration),
+	}
+	if op, ok := OperationFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("operation", op.Name))
+	}
+	if req.GetBody != nil {
+		if body, bodyErr := req.GetBody(); bodyErr == nil {
+			data, _ := io.ReadAll(body)
+			attrs = append(attrs, slog.String("body", client.redactor(req.URL.Path, data)))
+		}
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		client.logger.LogAttrs(ctx, slog.LevelDebug, "API request failed", attrs...)
+		return
+	}
+
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		data, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		if readErr == nil {
+			var apiErr apiErrorResponse
+			if json.Unmarshal(data, &apiErr) == nil && apiErr.RequestID != "" {
+				attrs = append(attrs, slog.String("request_id", apiErr.RequestID))
+			}
+		}
+	}
+	client.logger.LogAttrs(ctx, slog.LevelDebug, "API request", attrs...)
+}
+
+// logServerUpdates logs the changes UpdateHTTPServers or UpdateStreamServers is about to make.
+func logServerUpdates[S Server](ctx context.Context, logger *slog.Logger, upstream string, toAdd, toDelete, toUpdate []S) {
+	for _, s := range toAdd {
+		logger.InfoContext(ctx, "adding server", "upstream", upstream, "server", s.Address())
+	}
+	for _, s := range toDelete {
+		logger.InfoContext(ctx, "deleting server", "upstream", upstream, "server", s.Address())
+	}
+	for _, s := range toUpdate {
+		logger.InfoContext(ctx, "updating server", "upstream", upstream, "server", s.Address())
+	}
+}
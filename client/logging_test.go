@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type logRecords struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (l *logRecords) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *logRecords) records(t *testing.T) []map[string]any {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var records []map[string]any
+	for line := range strings.Lines(l.buf.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogging(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`[{"id": 1, "server": "10.0.0.1:80"}]`))
+		case http.MethodPost:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"status":400,"text":"invalid","code":"UpstreamConfFormatError"},"request_id":"abc"}`))
+		case http.MethodDelete:
+			_, _ = w.Write([]byte(`[]`))
+		}
+	}))
+	defer ts.Close()
+
+	logs := &logRecords{}
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c, err := NewNginxClient(ts.URL, WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := []UpstreamServer{{Server: "10.0.0.2"}, {Server: "10.0.0.2:80"}}
+	_, _, _, err = c.UpdateHTTPServers(context.Background(), "foo", servers)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var messages []string
+	var requestID any
+	for _, record := range logs.records(t) {
+		messages = append(messages, fmt.Sprint(record["msg"]))
+		if record["method"] == http.MethodPost {
+			requestID = record["request_id"]
+			if record["body"] != `{"server":"10.0.0.2:80"}` || record["status"] != float64(http.StatusBadRequest) {
+				t.Errorf("unexpected record %v", record)
+			}
+		}
+	}
+
+	expected := []string{
+		"API request",
+		"ignoring duplicate server",
+		"adding server",
+		"deleting server",
+		"API request",
+		"API request",
+	}
+	if strings.Join(messages, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected messages %v, got %v", expected, messages)
+	}
+	if requestID != "abc" {
+		t.Fatalf("expected request_id abc, got %v", requestID)
+	}
+}
+
+func TestDefaultRedactor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		path     string
+		body     string
+		expected string
+		msg      string
+	}{
+		{
+			path:     "/api/9/http/keyvals/zone",
+			body:     `{"key":"secret"}`,
+			expected: `{"key":"[REDACTED]"}`,
+			msg:      "key-value pair",
+		},
+		{
+			path:     "/api/9/http/keyvals/zone",
+			body:     `{"key":null}`,
+			expected: `{"key":null}`,
+			msg:      "deleted key",
+		},
+		{
+			path:     "/api/9/http/upstreams/foo/servers/",
+			body:     `{"server":"10.0.0.1:80"}`,
+			expected: `{"server":"10.0.0.1:80"}`,
+			msg:      "server",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			if result := DefaultRedactor(test.path, []byte(test.body)); result != test.expected {
+				t.Errorf("DefaultRedactor(%v, %v) returned %v but expected %v", test.path, test.body, result, test.expected)
+			}
+		})
+	}
+}
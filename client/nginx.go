@@ -7,12 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"reflect"
 	"regexp"
 	"slices"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -26,7 +25,7 @@ const (
 	pathNotFoundCode  = "PathNotFound"
 	streamContext     = true
 	httpContext       = false
-	defaultServerPort = "80"
+	defaultServerPort = 80
 )
 
 var (
@@ -46,6 +45,7 @@ var (
 	ErrParameterRequired   = errors.New("parameter is required")
 	ErrServerNotFound      = errors.New("server not found")
 	ErrServerExists        = errors.New("server already exists")
+	ErrUpstreamNotFound    = errors.New("upstream not found")
 	ErrNotSupported        = errors.New("not supported")
 	ErrInvalidTimeout      = errors.New("invalid timeout")
 	ErrParameterMismatch   = errors.New("encountered duplicate server with different parameters")
@@ -63,10 +63,25 @@ var _ StatusError = (*internalError)(nil)
 
 // NginxClient lets you access NGINX Plus API.
 type NginxClient struct {
-	httpClient  *http.Client
-	apiEndpoint string
-	apiVersion  int
-	checkAPI    bool
+	httpClient        *http.Client
+	authenticator     Authenticator
+	auditSink         AuditSink
+	guards            *UpdateGuards
+	certReloader      *CertificateReloader
+	hooks             Hooks
+	idCache           *serverIDCache
+	logger            *slog.Logger
+	redactor          Redactor
+	interceptors      []Interceptor
+	reconcileDefaults []ReconcileOption
+	upstreamAllowlist map[string]struct{}
+	zoneAllowlist     map[string]struct{}
+	apiEndpoint       string
+	socketPath        string
+	apiVersion        int
+	checkAPI          bool
+	maxAPIVersion     bool
+	readOnly          bool
 }
 
 type Option func(*NginxClient)
@@ -80,7 +95,7 @@ type UpstreamServer struct {
 	Backup      *bool  `json:"backup,omitempty"`
 	Down        *bool  `json:"down,omitempty"`
 	Weight      *int   `json:"weight,omitempty"`
-	Server      string `json:"server"`
+	Server      string `json:"server,omitempty"`
 	FailTimeout string `json:"fail_timeout,omitempty"`
 	SlowStart   string `json:"slow_start,omitempty"`
 	Route       string `json:"route,omitempty"`
@@ -96,13 +111,77 @@ type StreamUpstreamServer struct {
 	Backup      *bool  `json:"backup,omitempty"`
 	Down        *bool  `json:"down,omitempty"`
 	Weight      *int   `json:"weight,omitempty"`
-	Server      string `json:"server"`
+	Server      string `json:"server,omitempty"`
 	FailTimeout string `json:"fail_timeout,omitempty"`
 	SlowStart   string `json:"slow_start,omitempty"`
 	Service     string `json:"service,omitempty"`
 	ID          int    `json:"id,omitempty"`
 }
 
+// valueOr returns the value p points to, or def if p is nil.
+func valueOr[T comparable](p *T, def T) T {
+	if p == nil {
+		return def
+	}
+	return *p
+}
+
+// stringOr returns s, or def if s is empty.
+func stringOr(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Server is the constraint satisfied by the servers of HTTP and stream upstreams, UpstreamServer and StreamUpstreamServer.
+// It lets code, such as UpstreamHandle, work with both kinds of upstreams.
+type Server interface {
+	UpstreamServer | StreamUpstreamServer
+	Validate() error
+	Address() string
+	ServerID() int
+	IsDown() bool
+}
+
+// Address returns the address of the server.
+func (s UpstreamServer) Address() string {
+	return s.Server
+}
+
+// ServerID returns the ID of the server.
+func (s UpstreamServer) ServerID() int {
+	return s.ID
+}
+
+// IsDown reports whether the server is marked as down.
+func (s UpstreamServer) IsDown() bool {
+	return s.Down != nil && *s.Down
+}
+
+// Address returns the address of the server.
+func (s StreamUpstreamServer) Address() string {
+	return s.Server
+}
+
+// ServerID returns the ID of the server.
+func (s StreamUpstreamServer) ServerID() int {
+	return s.ID
+}
+
+// IsDown reports whether the server is marked as down.
+func (s StreamUpstreamServer) IsDown() bool {
+	return s.Down != nil && *s.Down
+}
+
+// ServerUpdates holds the servers added, deleted and updated by UpdateHTTPServers or UpdateStreamServers,
+// or the differences found by DiffServers. It is the result of those operations passed to interceptors.
+type ServerUpdates[S Server] struct {
+	Added   []S
+	Deleted []S
+	Updated []S
+}
+
 type apiErrorResponse struct {
 	RequestID string   `json:"request_id"`
 	Href      string   `json:"href"`
@@ -626,25 +705,38 @@ func WithCheckAPI() Option {
 }
 
 // WithMaxAPIVersion sets the API version to the max API version.
+// If the server can't be reached, the API version is left unchanged.
 func WithMaxAPIVersion() Option {
 	return func(o *NginxClient) {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		version, err := o.GetMaxAPIVersion(ctx)
-		if err != nil {
-			return
-		}
-		o.apiVersion = version
+		o.maxAPIVersion = true
+	}
+}
+
+// WithUnixSocket sets the UNIX-domain socket to connect to the API through.
+// The host of the API endpoint is then ignored, for example "http://localhost/api".
+// An endpoint in the form "unix:///path/to/socket:/api" has the same effect.
+func WithUnixSocket(socketPath string) Option {
+	return func(o *NginxClient) {
+		o.socketPath = socketPath
 	}
 }
 
 // NewNginxClient creates a new NginxClient.
+// The apiEndpoint is either an HTTP URL such as "http://127.0.0.1:8080/api"
+// or a UNIX-domain socket followed by the API path such as "unix:///run/nginx-api.sock:/api".
 func NewNginxClient(apiEndpoint string, opts ...Option) (*NginxClient, error) {
 	c := &NginxClient{
 		httpClient:  http.DefaultClient,
 		apiEndpoint: apiEndpoint,
 		apiVersion:  APIVersion,
 		checkAPI:    false,
+		logger:      slog.New(slog.DiscardHandler),
+		redactor:    DefaultRedactor,
+	}
+
+	if socketPath, httpEndpoint, ok := parseUnixEndpoint(apiEndpoint); ok {
+		c.socketPath = socketPath
+		c.apiEndpoint = httpEndpoint
 	}
 
 	for _, opt := range opts {
@@ -655,6 +747,29 @@ func NewNginxClient(apiEndpoint string, opts ...Option) (*NginxClient, error) {
 		return nil, fmt.Errorf("http client: %w", ErrParameterRequired)
 	}
 
+	if c.logger == nil {
+		return nil, fmt.Errorf("logger: %w", ErrParameterRequired)
+	}
+
+	if c.redactor == nil {
+		return nil, fmt.Errorf("log redactor: %w", ErrParameterRequired)
+	}
+
+	httpClient, err := c.configureTransport(c.httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the http client: %w", err)
+	}
+	c.httpClient = httpClient
+
+	if c.maxAPIVersion {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		version, err := c.GetMaxAPIVersion(ctx)
+		cancel()
+		if err == nil {
+			c.apiVersion = version
+		}
+	}
+
 	if !versionSupported(c.apiVersion) {
 		return nil, fmt.Errorf("API version %v: %w by the client", c.apiVersion, ErrNotSupported)
 	}
@@ -662,7 +777,7 @@ func NewNginxClient(apiEndpoint string, opts ...Option) (*NginxClient, error) {
 	if c.checkAPI {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		versions, err := c.getAPIVersions(ctx, c.httpClient, apiEndpoint)
+		versions, err := c.getAPIVersions(ctx, c.apiEndpoint)
 		if err != nil {
 			return nil, fmt.Errorf("error accessing the API: %w", err)
 		}
@@ -692,27 +807,29 @@ func versionSupported(n int) bool {
 
 // GetMaxAPIVersion returns the maximum API version supported by the server and the client.
 func (client *NginxClient) GetMaxAPIVersion(ctx context.Context) (int, error) {
-	serverVersions, err := client.getAPIVersions(ctx, client.httpClient, client.apiEndpoint)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get max API version: %w", err)
-	}
+	return invoke(ctx, client, &Operation{Name: "GetMaxAPIVersion"}, func(ctx context.Context, _ *Operation) (int, error) {
+		serverVersions, err := client.getAPIVersions(ctx, client.apiEndpoint)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get max API version: %w", err)
+		}
 
-	maxServerVersion := slices.Max(*serverVersions)
-	maxClientVersion := slices.Max(supportedAPIVersions)
+		maxServerVersion := slices.Max(*serverVersions)
+		maxClientVersion := slices.Max(supportedAPIVersions)
 
-	if maxServerVersion > maxClientVersion {
-		return maxClientVersion, nil
-	}
+		if maxServerVersion > maxClientVersion {
+			return maxClientVersion, nil
+		}
 
-	return maxServerVersion, nil
+		return maxServerVersion, nil
+	})
 }
 
-func (client *NginxClient) getAPIVersions(ctx context.Context, httpClient *http.Client, endpoint string) (*versions, error) {
+func (client *NginxClient) getAPIVersions(ctx context.Context, endpoint string) (*versions, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a get request: %w", err)
 	}
-	resp, err := httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("%v is not accessible: %w", endpoint, err)
 	}
@@ -738,6 +855,41 @@ func (client *NginxClient) getAPIVersions(ctx context.Context, httpClient *http.
 	return &vers, nil
 }
 
+// do sends the request to the API.
+func (client *NginxClient) do(req *http.Request) (*http.Response, error) {
+	setOperationHeaders(req)
+	if client.authenticator != nil {
+		if err := client.authenticator.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("failed to authenticate the request: %w", err)
+		}
+	}
+
+	ctx := req.Context()
+	if client.hooks != nil {
+		client.hooks.RequestStart(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := client.httpClient.Do(req)
+	duration := time.Since(start)
+
+	if client.hooks != nil {
+		info := RequestInfo{
+			Err:      err,
+			Method:   req.Method,
+			Path:     req.URL.Path,
+			Duration: duration,
+		}
+		if resp != nil {
+			info.StatusCode = resp.StatusCode
+		}
+		client.hooks.RequestEnd(ctx, info)
+	}
+	client.logRequest(ctx, req, resp, duration, err)
+
+	return resp, err
+}
+
 func createResponseMismatchError(respBody io.ReadCloser) *internalError {
 	apiErrResp, err := readAPIErrorResponse(respBody)
 	if err != nil {
@@ -769,39 +921,57 @@ func readAPIErrorResponse(respBody io.ReadCloser) (*apiErrorResponse, error) {
 
 // CheckIfUpstreamExists checks if the upstream exists in NGINX. If the upstream doesn't exist, it returns the error.
 func (client *NginxClient) CheckIfUpstreamExists(ctx context.Context, upstream string) error {
-	_, err := client.GetHTTPServers(ctx, upstream)
-	return err
+	return invokeErr(ctx, client, &Operation{Name: "CheckIfUpstreamExists", Upstream: upstream}, func(ctx context.Context, _ *Operation) error {
+		_, err := client.GetHTTPServers(ctx, upstream)
+		return err
+	})
 }
 
 // GetHTTPServers returns the servers of the upstream from NGINX.
 func (client *NginxClient) GetHTTPServers(ctx context.Context, upstream string) ([]UpstreamServer, error) {
-	path := fmt.Sprintf("http/upstreams/%v/servers", upstream)
+	return invoke(ctx, client, &Operation{Name: "GetHTTPServers", Upstream: upstream}, func(ctx context.Context, _ *Operation) ([]UpstreamServer, error) {
+		path := fmt.Sprintf("http/upstreams/%v/servers", upstream)
 
-	var servers []UpstreamServer
-	err := client.get(ctx, path, &servers)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get the HTTP servers of upstream %v: %w", upstream, err)
-	}
+		var servers []UpstreamServer
+		err := client.get(ctx, path, &servers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the HTTP servers of upstream %v: %w", upstream, err)
+		}
+		cacheServers(client, httpContext, upstream, servers)
 
-	return servers, nil
+		return servers, nil
+	})
 }
 
 // AddHTTPServer adds the server to the upstream.
 func (client *NginxClient) AddHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error {
-	id, err := client.getIDOfHTTPServer(ctx, upstream, server.Server)
-	if err != nil {
-		return fmt.Errorf("failed to add %v server to %v upstream: %w", server.Server, upstream, err)
-	}
-	if id != -1 {
-		return fmt.Errorf("failed to add %v server to %v upstream: %w", server.Server, upstream, ErrServerExists)
-	}
-	err = client.addHTTPServer(ctx, upstream, server)
-	return err
+	return invokeErr(ctx, client, &Operation{Name: "AddHTTPServer", Upstream: upstream, Server: server.Server, Request: server, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		server, err := requestAs[UpstreamServer](op)
+		if err != nil {
+			return err
+		}
+		if err := server.Validate(); err != nil {
+			err = fmt.Errorf("failed to add %v server to %v upstream: %w", server.Server, upstream, err)
+			client.audit(ctx, AuditRecord{Action: AuditAddServer, Context: contextName(httpContext), Upstream: upstream, Server: server.Server, After: server}, err)
+			return err
+		}
+		id, err := client.getIDOfHTTPServer(ctx, upstream, server.Server)
+		if err != nil {
+			return fmt.Errorf("failed to add %v server to %v upstream: %w", server.Server, upstream, err)
+		}
+		if id != -1 {
+			return fmt.Errorf("failed to add %v server to %v upstream: %w", server.Server, upstream, ErrServerExists)
+		}
+		err = client.addHTTPServer(ctx, upstream, server)
+		return err
+	})
 }
 
 func (client *NginxClient) addHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error {
 	path := fmt.Sprintf("http/upstreams/%v/servers/", upstream)
 	err := client.post(ctx, path, &server)
+	client.audit(ctx, AuditRecord{Action: AuditAddServer, Context: contextName(httpContext), Upstream: upstream, Server: server.Server, After: server}, err)
+	client.updateServerIDCache(httpContext, upstream, ServerAdded, server.Server, -1, err)
 	if err != nil {
 		return fmt.Errorf("failed to add %v server to %v upstream: %w", server.Server, upstream, err)
 	}
@@ -811,22 +981,63 @@ func (client *NginxClient) addHTTPServer(ctx context.Context, upstream string, s
 
 // DeleteHTTPServer the server from the upstream.
 func (client *NginxClient) DeleteHTTPServer(ctx context.Context, upstream string, server string) error {
-	id, err := client.getIDOfHTTPServer(ctx, upstream, server)
-	if err != nil {
-		return fmt.Errorf("failed to remove %v server from  %v upstream: %w", server, upstream, err)
-	}
-	if id == -1 {
-		return fmt.Errorf("failed to remove %v server from %v upstream: %w", server, upstream, ErrServerNotFound)
-	}
-	err = client.deleteHTTPServer(ctx, upstream, server, id)
-	return err
+	return invokeErr(ctx, client, &Operation{Name: "DeleteHTTPServer", Upstream: upstream, Server: server, Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		for attempt := 1; ; attempt++ {
+			serverInNginx, found, err := client.findHTTPServer(ctx, upstream, server)
+			if err != nil {
+				return fmt.Errorf("failed to remove %v server from  %v upstream: %w", server, upstream, err)
+			}
+			if !found {
+				return fmt.Errorf("failed to remove %v server from %v upstream: %w", server, upstream, ErrServerNotFound)
+			}
+			err = client.deleteHTTPServer(ctx, upstream, serverInNginx)
+			// The ID may have come from a stale cache, which the failure has cleared, so look the server up again once.
+			if attempt == 1 && client.idCache != nil && isNotFound(err) {
+				continue
+			}
+			return err
+		}
+	})
+}
+
+// DeleteHTTPServerByID removes the server with the ID from the upstream.
+func (client *NginxClient) DeleteHTTPServerByID(ctx context.Context, upstream string, id int) error {
+	return invokeErr(ctx, client, &Operation{Name: "DeleteHTTPServerByID", Upstream: upstream, Server: strconv.Itoa(id), Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		err := client.deleteHTTPServer(ctx, upstream, UpstreamServer{ID: id})
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %w", ErrServerNotFound, err)
+		}
+		return err
+	})
+}
+
+// GetHTTPServerByID returns the server with the ID of the upstream.
+func (client *NginxClient) GetHTTPServerByID(ctx context.Context, upstream string, id int) (UpstreamServer, error) {
+	return invoke(ctx, client, &Operation{Name: "GetHTTPServerByID", Upstream: upstream, Server: strconv.Itoa(id)}, func(ctx context.Context, _ *Operation) (UpstreamServer, error) {
+		path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, id)
+
+		var server UpstreamServer
+		err := client.get(ctx, path, &server)
+		if err != nil {
+			if isNotFound(err) {
+				err = fmt.Errorf("%w: %w", ErrServerNotFound, err)
+			}
+			return UpstreamServer{}, fmt.Errorf("failed to get server %v of upstream %v: %w", id, upstream, err)
+		}
+		if client.idCache != nil {
+			client.idCache.set(upstreamKey{name: upstream, stream: httpContext}, server.Server, server.ID)
+		}
+		return server, nil
+	})
 }
 
-func (client *NginxClient) deleteHTTPServer(ctx context.Context, upstream, server string, serverID int) error {
-	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, serverID)
+func (client *NginxClient) deleteHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error {
+	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, server.ID)
 	err := client.delete(ctx, path, http.StatusOK)
+	client.audit(ctx, AuditRecord{Action: AuditDeleteServer, Context: contextName(httpContext), Upstream: upstream, Server: server.Server, Before: server}, err)
+	client.updateServerIDCache(httpContext, upstream, ServerDeleted, server.Server, server.ID, err)
 	if err != nil {
-		return fmt.Errorf("failed to remove %v server from %v upstream: %w", server, upstream, err)
+		return fmt.Errorf("failed to remove %v server from %v upstream: %w", server.Server, upstream, err)
 	}
 
 	return nil
@@ -839,185 +1050,189 @@ func (client *NginxClient) deleteHTTPServer(ctx context.Context, upstream, serve
 // The client will attempt to update all servers, returning all the errors that occurred.
 // If there are duplicate servers with equivalent parameters, the duplicates will be ignored.
 // If there are duplicate servers with different parameters, those server entries will be ignored and an error returned.
+// If any server fails validation, no changes are made and the validation errors of all servers are returned.
+// If the change violates the guards set with WithUpdateGuards, no changes are made and a GuardError is returned.
+// The changes are applied with the options set with WithReconcileDefaults.
 func (client *NginxClient) UpdateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer) (added []UpstreamServer, deleted []UpstreamServer, updated []UpstreamServer, err error) {
-	serversInNginx, err := client.GetHTTPServers(ctx, upstream)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to update servers of %v upstream: %w", upstream, err)
-	}
-
-	// We assume port 80 if no port is set for servers.
-	formattedServers := make([]UpstreamServer, 0, len(servers))
-	for _, server := range servers {
-		server.Server = addPortToServer(server.Server)
-		formattedServers = append(formattedServers, server)
-	}
-
-	formattedServers, err = deduplicateServers(upstream, formattedServers)
-
-	toAdd, toDelete, toUpdate := determineUpdates(formattedServers, serversInNginx)
-
-	for _, server := range toAdd {
-		addErr := client.addHTTPServer(ctx, upstream, server)
-		if addErr != nil {
-			err = errors.Join(err, addErr)
-			continue
-		}
-		added = append(added, server)
-	}
-
-	for _, server := range toDelete {
-		deleteErr := client.deleteHTTPServer(ctx, upstream, server.Server, server.ID)
-		if deleteErr != nil {
-			err = errors.Join(err, deleteErr)
-			continue
-		}
-		deleted = append(deleted, server)
-	}
-
-	for _, server := range toUpdate {
-		updateErr := client.UpdateHTTPServer(ctx, upstream, server)
-		if updateErr != nil {
-			err = errors.Join(err, updateErr)
-			continue
+	op := &Operation{Name: "UpdateHTTPServers", Upstream: upstream, Request: servers, Mutating: true}
+	result, err := invoke(ctx, client, op, func(ctx context.Context, op *Operation) (ServerUpdates[UpstreamServer], error) {
+		servers, err := requestAs[[]UpstreamServer](op)
+		if err != nil {
+			return ServerUpdates[UpstreamServer]{}, err
 		}
-		updated = append(updated, server)
-	}
+		var result ServerUpdates[UpstreamServer]
+		result.Added, result.Deleted, result.Updated, err = client.updateHTTPServers(ctx, upstream, servers)
+		return result, err
+	})
+	return result.Added, result.Deleted, result.Updated, err
+}
 
-	if err != nil {
-		err = fmt.Errorf("failed to update servers of %s upstream: %w", upstream, err)
-	}
+func (client *NginxClient) updateHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer) (added []UpstreamServer, deleted []UpstreamServer, updated []UpstreamServer, err error) {
+	result, err := reconcile(ctx, client, client.httpServerOps(), upstream, servers, client.newReconcileConfig(nil))
+	return result.Added(), result.Deleted(), result.Updated(), err
+}
 
-	return added, deleted, updated, err
+func deduplicateServers(ctx context.Context, logger *slog.Logger, upstream string, servers []UpstreamServer) ([]UpstreamServer, error) {
+	return deduplicate(ctx, logger, upstream, "server", servers)
 }
 
-func deduplicateServers(upstream string, servers []UpstreamServer) ([]UpstreamServer, error) {
+// deduplicate removes duplicate servers. Servers with duplicate entries with different parameters are removed
+// and an error is returned for them. The noun is how the servers are called in logs and errors.
+func deduplicate[S Server](ctx context.Context, logger *slog.Logger, upstream, noun string, servers []S) ([]S, error) {
 	type serverCheck struct {
-		server UpstreamServer
+		server S
 		valid  bool
 	}
 
 	serverMap := make(map[string]*serverCheck, len(servers))
 	var err error
 	for _, server := range servers {
-		if prev, ok := serverMap[server.Server]; ok {
+		key := ServerKey(server)
+		if prev, ok := serverMap[key]; ok {
 			if !prev.valid {
 				continue
 			}
-			if !server.hasSameParametersAs(prev.server) {
+			if !sameParameters(server, prev.server) {
 				prev.valid = false
+				logger.InfoContext(ctx, "ignoring "+noun+" with duplicate entries with different parameters", "upstream", upstream, "server", server.Address())
 				err = errors.Join(err, fmt.Errorf(
-					"failed to update %s server to %s upstream: %w",
-					server.Server, upstream, ErrParameterMismatch))
+					"failed to update %s %s to %s upstream: %w",
+					server.Address(), noun, upstream, ErrParameterMismatch))
+			} else {
+				logger.InfoContext(ctx, "ignoring duplicate "+noun, "upstream", upstream, "server", server.Address())
 			}
 			continue
 		}
-		serverMap[server.Server] = &serverCheck{server, true}
+		serverMap[key] = &serverCheck{server, true}
 	}
-	retServers := make([]UpstreamServer, 0, len(serverMap))
+	retServers := make([]S, 0, len(serverMap))
 	for _, server := range servers {
-		if check, ok := serverMap[server.Server]; ok && check.valid {
+		key := ServerKey(server)
+		if check, ok := serverMap[key]; ok && check.valid {
 			retServers = append(retServers, server)
-			delete(serverMap, server.Server)
+			delete(serverMap, key)
 		}
 	}
 	return retServers, err
 }
 
-// hasSameParametersAs checks if a given server has the same parameters.
-func (s UpstreamServer) hasSameParametersAs(compareServer UpstreamServer) bool {
-	s.ID = compareServer.ID
-	s.applyDefaults()
-	compareServer.applyDefaults()
-	return reflect.DeepEqual(s, compareServer)
-}
-
-func (s *UpstreamServer) applyDefaults() {
-	if s.MaxConns == nil {
-		s.MaxConns = &defaultMaxConns
+// determineServerUpdates returns the servers to add, remove and update for NGINX to have the updated servers.
+// The servers to update have the ID of the NGINX server they update.
+func determineServerUpdates[S Server](updatedServers []S, nginxServers []S) (toAdd []S, toRemove []S, toUpdate []S) {
+	nginxKeys := make([]string, len(nginxServers))
+	nginxByKey := make(map[string][]S, len(nginxServers))
+	for i, serverNGX := range nginxServers {
+		nginxKeys[i] = ServerKey(serverNGX)
+		nginxByKey[nginxKeys[i]] = append(nginxByKey[nginxKeys[i]], serverNGX)
 	}
 
-	if s.MaxFails == nil {
-		s.MaxFails = &defaultMaxFails
+	updatedKeys := make(map[string]struct{}, len(updatedServers))
+	for _, server := range updatedServers {
+		key := ServerKey(server)
+		updatedKeys[key] = struct{}{}
+		matches, found := nginxByKey[key]
+		if !found {
+			toAdd = append(toAdd, server)
+			continue
+		}
+		for _, serverNGX := range matches {
+			if !sameParameters(server, serverNGX) {
+				toUpdate = append(toUpdate, withID(server, serverNGX.ServerID()))
+				break
+			}
+		}
 	}
 
-	if s.FailTimeout == "" {
-		s.FailTimeout = defaultFailTimeout
+	for i, serverNGX := range nginxServers {
+		if _, found := updatedKeys[nginxKeys[i]]; !found {
+			toRemove = append(toRemove, serverNGX)
+		}
 	}
 
-	if s.SlowStart == "" {
-		s.SlowStart = defaultSlowStart
-	}
+	return toAdd, toRemove, toUpdate
+}
 
-	if s.Backup == nil {
-		s.Backup = &defaultBackup
-	}
+// DiffServers compares the servers of an upstream with the desired servers the same way UpdateHTTPServers and UpdateStreamServers do.
+// Added are the desired servers that the upstream doesn't have, Deleted are the servers of the upstream that are not desired,
+// and Updated are the desired servers with different parameters, with the ID of the server of the upstream.
+// The desired servers must not have duplicates.
+func DiffServers[S Server](desired []S, actual []S) ServerUpdates[S] {
+	added, deleted, updated := determineServerUpdates(desired, actual)
+	return ServerUpdates[S]{Added: added, Deleted: deleted, Updated: updated}
+}
 
-	if s.Down == nil {
-		s.Down = &defaultDown
+// sameParameters checks if the servers have the same parameters.
+func sameParameters[S Server](a, b S) bool {
+	switch a := any(a).(type) {
+	case UpstreamServer:
+		b, ok := any(b).(UpstreamServer)
+		return ok && a.hasSameParametersAs(b)
+	case StreamUpstreamServer:
+		b, ok := any(b).(StreamUpstreamServer)
+		return ok && a.hasSameParametersAs(b)
 	}
+	return false
+}
 
-	if s.Weight == nil {
-		s.Weight = &defaultWeight
+// withID returns the server with the ID.
+func withID[S Server](server S, id int) S {
+	switch s := any(&server).(type) {
+	case *UpstreamServer:
+		s.ID = id
+	case *StreamUpstreamServer:
+		s.ID = id
 	}
+	return server
+}
+
+// hasSameParametersAs checks if a given server has the same parameters.
+// Parameters that aren't set are compared with their default values.
+func (s UpstreamServer) hasSameParametersAs(compareServer UpstreamServer) bool {
+	return valueOr(s.MaxConns, defaultMaxConns) == valueOr(compareServer.MaxConns, defaultMaxConns) &&
+		valueOr(s.MaxFails, defaultMaxFails) == valueOr(compareServer.MaxFails, defaultMaxFails) &&
+		valueOr(s.Backup, defaultBackup) == valueOr(compareServer.Backup, defaultBackup) &&
+		valueOr(s.Down, defaultDown) == valueOr(compareServer.Down, defaultDown) &&
+		valueOr(s.Weight, defaultWeight) == valueOr(compareServer.Weight, defaultWeight) &&
+		stringOr(s.FailTimeout, defaultFailTimeout) == stringOr(compareServer.FailTimeout, defaultFailTimeout) &&
+		stringOr(s.SlowStart, defaultSlowStart) == stringOr(compareServer.SlowStart, defaultSlowStart) &&
+		s.Route == compareServer.Route &&
+		s.Drain == compareServer.Drain &&
+		s.Service == compareServer.Service &&
+		(s.Server == compareServer.Server || serverKey(s.Server) == serverKey(compareServer.Server))
 }
 
 func determineUpdates(updatedServers []UpstreamServer, nginxServers []UpstreamServer) (toAdd []UpstreamServer, toRemove []UpstreamServer, toUpdate []UpstreamServer) {
-	for _, server := range updatedServers {
-		updateFound := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server && !server.hasSameParametersAs(serverNGX) {
-				server.ID = serverNGX.ID
-				updateFound = true
-				break
-			}
-		}
-		if updateFound {
-			toUpdate = append(toUpdate, server)
-		}
-	}
+	return determineServerUpdates(updatedServers, nginxServers)
+}
 
-	for _, server := range updatedServers {
-		found := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server {
-				found = true
-				break
-			}
-		}
-		if !found {
-			toAdd = append(toAdd, server)
-		}
+func (client *NginxClient) getIDOfHTTPServer(ctx context.Context, upstream string, name string) (int, error) {
+	server, found, err := client.findHTTPServer(ctx, upstream, name)
+	if err != nil || !found {
+		return -1, err
 	}
+	return server.ID, nil
+}
 
-	for _, serverNGX := range nginxServers {
-		found := false
-		for _, server := range updatedServers {
-			if serverNGX.Server == server.Server {
-				found = true
-				break
-			}
-		}
-		if !found {
-			toRemove = append(toRemove, serverNGX)
-		}
+// findHTTPServer returns the server of the upstream with the address name.
+// The ID cache is used if it is enabled, in which case only the ID and address of the server are known.
+func (client *NginxClient) findHTTPServer(ctx context.Context, upstream string, name string) (UpstreamServer, bool, error) {
+	if id, found, ok := client.cachedServerID(ctx, httpContext, upstream, name); ok {
+		return UpstreamServer{ID: id, Server: name}, found, nil
 	}
 
-	return toAdd, toRemove, toUpdate
-}
-
-func (client *NginxClient) getIDOfHTTPServer(ctx context.Context, upstream string, name string) (int, error) {
 	servers, err := client.GetHTTPServers(ctx, upstream)
 	if err != nil {
-		return -1, fmt.Errorf("error getting id of server %v of upstream %v: %w", name, upstream, err)
+		return UpstreamServer{}, false, fmt.Errorf("error getting id of server %v of upstream %v: %w", name, upstream, err)
 	}
 
+	key := serverKey(name)
 	for _, s := range servers {
-		if s.Server == name {
-			return s.ID, nil
+		if serverKey(s.Server) == key {
+			return s, true, nil
 		}
 	}
 
-	return -1, nil
+	return UpstreamServer{}, false, nil
 }
 
 func (client *NginxClient) get(ctx context.Context, path string, data interface{}) error {
@@ -1028,7 +1243,7 @@ func (client *NginxClient) get(ctx context.Context, path string, data interface{
 		return fmt.Errorf("failed to create a get request: %w", err)
 	}
 
-	resp, err := client.httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to get %v: %w", path, err)
 	}
@@ -1065,7 +1280,7 @@ func (client *NginxClient) post(ctx context.Context, path string, input interfac
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to post %v: %w", path, err)
 	}
@@ -1088,7 +1303,7 @@ func (client *NginxClient) delete(ctx context.Context, path string, expectedStat
 		return fmt.Errorf("failed to create a delete request: %w", err)
 	}
 
-	resp, err := client.httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to create delete request: %w", err)
 	}
@@ -1116,7 +1331,7 @@ func (client *NginxClient) patch(ctx context.Context, path string, input interfa
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to create patch request: %w", err)
 	}
@@ -1132,38 +1347,56 @@ func (client *NginxClient) patch(ctx context.Context, path string, input interfa
 
 // CheckIfStreamUpstreamExists checks if the stream upstream exists in NGINX. If the upstream doesn't exist, it returns the error.
 func (client *NginxClient) CheckIfStreamUpstreamExists(ctx context.Context, upstream string) error {
-	_, err := client.GetStreamServers(ctx, upstream)
-	return err
+	return invokeErr(ctx, client, &Operation{Name: "CheckIfStreamUpstreamExists", Upstream: upstream}, func(ctx context.Context, _ *Operation) error {
+		_, err := client.GetStreamServers(ctx, upstream)
+		return err
+	})
 }
 
 // GetStreamServers returns the stream servers of the upstream from NGINX.
 func (client *NginxClient) GetStreamServers(ctx context.Context, upstream string) ([]StreamUpstreamServer, error) {
-	path := fmt.Sprintf("stream/upstreams/%v/servers", upstream)
+	return invoke(ctx, client, &Operation{Name: "GetStreamServers", Upstream: upstream}, func(ctx context.Context, _ *Operation) ([]StreamUpstreamServer, error) {
+		path := fmt.Sprintf("stream/upstreams/%v/servers", upstream)
 
-	var servers []StreamUpstreamServer
-	err := client.get(ctx, path, &servers)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get stream servers of upstream server %v: %w", upstream, err)
-	}
-	return servers, nil
+		var servers []StreamUpstreamServer
+		err := client.get(ctx, path, &servers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stream servers of upstream server %v: %w", upstream, err)
+		}
+		cacheServers(client, streamContext, upstream, servers)
+		return servers, nil
+	})
 }
 
 // AddStreamServer adds the stream server to the upstream.
 func (client *NginxClient) AddStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer) error {
-	id, err := client.getIDOfStreamServer(ctx, upstream, server.Server)
-	if err != nil {
-		return fmt.Errorf("failed to add %v stream server to %v upstream: %w", server.Server, upstream, err)
-	}
-	if id != -1 {
-		return fmt.Errorf("failed to add %v stream server to %v upstream: %w", server.Server, upstream, ErrServerExists)
-	}
-	err = client.addStreamServer(ctx, upstream, server)
-	return err
+	return invokeErr(ctx, client, &Operation{Name: "AddStreamServer", Upstream: upstream, Server: server.Server, Request: server, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		server, err := requestAs[StreamUpstreamServer](op)
+		if err != nil {
+			return err
+		}
+		if err := server.Validate(); err != nil {
+			err = fmt.Errorf("failed to add %v stream server to %v upstream: %w", server.Server, upstream, err)
+			client.audit(ctx, AuditRecord{Action: AuditAddServer, Context: contextName(streamContext), Upstream: upstream, Server: server.Server, After: server}, err)
+			return err
+		}
+		id, err := client.getIDOfStreamServer(ctx, upstream, server.Server)
+		if err != nil {
+			return fmt.Errorf("failed to add %v stream server to %v upstream: %w", server.Server, upstream, err)
+		}
+		if id != -1 {
+			return fmt.Errorf("failed to add %v stream server to %v upstream: %w", server.Server, upstream, ErrServerExists)
+		}
+		err = client.addStreamServer(ctx, upstream, server)
+		return err
+	})
 }
 
 func (client *NginxClient) addStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer) error {
 	path := fmt.Sprintf("stream/upstreams/%v/servers/", upstream)
 	err := client.post(ctx, path, &server)
+	client.audit(ctx, AuditRecord{Action: AuditAddServer, Context: contextName(streamContext), Upstream: upstream, Server: server.Server, After: server}, err)
+	client.updateServerIDCache(streamContext, upstream, ServerAdded, server.Server, -1, err)
 	if err != nil {
 		return fmt.Errorf("failed to add %v stream server to %v upstream: %w", server.Server, upstream, err)
 	}
@@ -1172,22 +1405,63 @@ func (client *NginxClient) addStreamServer(ctx context.Context, upstream string,
 
 // DeleteStreamServer the server from the upstream.
 func (client *NginxClient) DeleteStreamServer(ctx context.Context, upstream string, server string) error {
-	id, err := client.getIDOfStreamServer(ctx, upstream, server)
-	if err != nil {
-		return fmt.Errorf("failed to remove %v stream server from  %v upstream: %w", server, upstream, err)
-	}
-	if id == -1 {
-		return fmt.Errorf("failed to remove %v stream server from %v upstream: %w", server, upstream, ErrServerNotFound)
-	}
-	err = client.deleteStreamServer(ctx, upstream, server, id)
-	return err
+	return invokeErr(ctx, client, &Operation{Name: "DeleteStreamServer", Upstream: upstream, Server: server, Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		for attempt := 1; ; attempt++ {
+			serverInNginx, found, err := client.findStreamServer(ctx, upstream, server)
+			if err != nil {
+				return fmt.Errorf("failed to remove %v stream server from  %v upstream: %w", server, upstream, err)
+			}
+			if !found {
+				return fmt.Errorf("failed to remove %v stream server from %v upstream: %w", server, upstream, ErrServerNotFound)
+			}
+			err = client.deleteStreamServer(ctx, upstream, serverInNginx)
+			// The ID may have come from a stale cache, which the failure has cleared, so look the server up again once.
+			if attempt == 1 && client.idCache != nil && isNotFound(err) {
+				continue
+			}
+			return err
+		}
+	})
+}
+
+// DeleteStreamServerByID removes the stream server with the ID from the upstream.
+func (client *NginxClient) DeleteStreamServerByID(ctx context.Context, upstream string, id int) error {
+	return invokeErr(ctx, client, &Operation{Name: "DeleteStreamServerByID", Upstream: upstream, Server: strconv.Itoa(id), Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		err := client.deleteStreamServer(ctx, upstream, StreamUpstreamServer{ID: id})
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %w", ErrServerNotFound, err)
+		}
+		return err
+	})
+}
+
+// GetStreamServerByID returns the stream server with the ID of the upstream.
+func (client *NginxClient) GetStreamServerByID(ctx context.Context, upstream string, id int) (StreamUpstreamServer, error) {
+	return invoke(ctx, client, &Operation{Name: "GetStreamServerByID", Upstream: upstream, Server: strconv.Itoa(id)}, func(ctx context.Context, _ *Operation) (StreamUpstreamServer, error) {
+		path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, id)
+
+		var server StreamUpstreamServer
+		err := client.get(ctx, path, &server)
+		if err != nil {
+			if isNotFound(err) {
+				err = fmt.Errorf("%w: %w", ErrServerNotFound, err)
+			}
+			return StreamUpstreamServer{}, fmt.Errorf("failed to get stream server %v of upstream %v: %w", id, upstream, err)
+		}
+		if client.idCache != nil {
+			client.idCache.set(upstreamKey{name: upstream, stream: streamContext}, server.Server, server.ID)
+		}
+		return server, nil
+	})
 }
 
-func (client *NginxClient) deleteStreamServer(ctx context.Context, upstream, server string, serverID int) error {
-	path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, serverID)
+func (client *NginxClient) deleteStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer) error {
+	path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, server.ID)
 	err := client.delete(ctx, path, http.StatusOK)
+	client.audit(ctx, AuditRecord{Action: AuditDeleteServer, Context: contextName(streamContext), Upstream: upstream, Server: server.Server, Before: server}, err)
+	client.updateServerIDCache(streamContext, upstream, ServerDeleted, server.Server, server.ID, err)
 	if err != nil {
-		return fmt.Errorf("failed to remove %v stream server from %v upstream: %w", server, upstream, err)
+		return fmt.Errorf("failed to remove %v stream server from %v upstream: %w", server.Server, upstream, err)
 	}
 	return nil
 }
@@ -1199,697 +1473,630 @@ func (client *NginxClient) deleteStreamServer(ctx context.Context, upstream, ser
 // The client will attempt to update all servers, returning all the errors that occurred.
 // If there are duplicate servers with equivalent parameters, the duplicates will be ignored.
 // If there are duplicate servers with different parameters, those server entries will be ignored and an error returned.
+// If any server fails validation, no changes are made and the validation errors of all servers are returned.
+// If the change violates the guards set with WithUpdateGuards, no changes are made and a GuardError is returned.
+// The changes are applied with the options set with WithReconcileDefaults.
 func (client *NginxClient) UpdateStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer) (added []StreamUpstreamServer, deleted []StreamUpstreamServer, updated []StreamUpstreamServer, err error) {
-	serversInNginx, err := client.GetStreamServers(ctx, upstream)
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to update stream servers of %v upstream: %w", upstream, err)
-	}
-
-	formattedServers := make([]StreamUpstreamServer, 0, len(servers))
-	for _, server := range servers {
-		server.Server = addPortToServer(server.Server)
-		formattedServers = append(formattedServers, server)
-	}
-
-	formattedServers, err = deduplicateStreamServers(upstream, formattedServers)
-
-	toAdd, toDelete, toUpdate := determineStreamUpdates(formattedServers, serversInNginx)
-
-	for _, server := range toAdd {
-		addErr := client.addStreamServer(ctx, upstream, server)
-		if addErr != nil {
-			err = errors.Join(err, addErr)
-			continue
+	op := &Operation{Name: "UpdateStreamServers", Upstream: upstream, Request: servers, Mutating: true}
+	result, err := invoke(ctx, client, op, func(ctx context.Context, op *Operation) (ServerUpdates[StreamUpstreamServer], error) {
+		servers, err := requestAs[[]StreamUpstreamServer](op)
+		if err != nil {
+			return ServerUpdates[StreamUpstreamServer]{}, err
 		}
-		added = append(added, server)
-	}
+		var result ServerUpdates[StreamUpstreamServer]
+		result.Added, result.Deleted, result.Updated, err = client.updateStreamServers(ctx, upstream, servers)
+		return result, err
+	})
+	return result.Added, result.Deleted, result.Updated, err
+}
 
-	for _, server := range toDelete {
-		deleteErr := client.deleteStreamServer(ctx, upstream, server.Server, server.ID)
-		if deleteErr != nil {
-			err = errors.Join(err, deleteErr)
-			continue
-		}
-		deleted = append(deleted, server)
-	}
+func (client *NginxClient) updateStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer) (added []StreamUpstreamServer, deleted []StreamUpstreamServer, updated []StreamUpstreamServer, err error) {
+	result, err := reconcile(ctx, client, client.streamServerOps(), upstream, servers, client.newReconcileConfig(nil))
+	return result.Added(), result.Deleted(), result.Updated(), err
+}
 
-	for _, server := range toUpdate {
-		updateErr := client.UpdateStreamServer(ctx, upstream, server)
-		if updateErr != nil {
-			err = errors.Join(err, updateErr)
-			continue
-		}
-		updated = append(updated, server)
+func (client *NginxClient) getIDOfStreamServer(ctx context.Context, upstream string, name string) (int, error) {
+	server, found, err := client.findStreamServer(ctx, upstream, name)
+	if err != nil || !found {
+		return -1, err
 	}
+	return server.ID, nil
+}
 
-	if err != nil {
-		err = fmt.Errorf("failed to update stream servers of %s upstream: %w", upstream, err)
+// findStreamServer returns the stream server of the upstream with the address name.
+// The ID cache is used if it is enabled, in which case only the ID and address of the server are known.
+func (client *NginxClient) findStreamServer(ctx context.Context, upstream string, name string) (StreamUpstreamServer, bool, error) {
+	if id, found, ok := client.cachedServerID(ctx, streamContext, upstream, name); ok {
+		return StreamUpstreamServer{ID: id, Server: name}, found, nil
 	}
 
-	return added, deleted, updated, err
-}
-
-func (client *NginxClient) getIDOfStreamServer(ctx context.Context, upstream string, name string) (int, error) {
 	servers, err := client.GetStreamServers(ctx, upstream)
 	if err != nil {
-		return -1, fmt.Errorf("error getting id of stream server %v of upstream %v: %w", name, upstream, err)
+		return StreamUpstreamServer{}, false, fmt.Errorf("error getting id of stream server %v of upstream %v: %w", name, upstream, err)
 	}
 
+	key := serverKey(name)
 	for _, s := range servers {
-		if s.Server == name {
-			return s.ID, nil
+		if serverKey(s.Server) == key {
+			return s, true, nil
 		}
 	}
 
-	return -1, nil
+	return StreamUpstreamServer{}, false, nil
 }
 
-func deduplicateStreamServers(upstream string, servers []StreamUpstreamServer) ([]StreamUpstreamServer, error) {
-	type serverCheck struct {
-		server StreamUpstreamServer
-		valid  bool
-	}
-
-	serverMap := make(map[string]*serverCheck, len(servers))
-	var err error
-	for _, server := range servers {
-		if prev, ok := serverMap[server.Server]; ok {
-			if !prev.valid {
-				continue
-			}
-			if !server.hasSameParametersAs(prev.server) {
-				prev.valid = false
-				err = errors.Join(err, fmt.Errorf(
-					"failed to update stream %s server to %s upstream: %w",
-					server.Server, upstream, ErrParameterMismatch))
-			}
-			continue
-		}
-		serverMap[server.Server] = &serverCheck{server, true}
-	}
-	retServers := make([]StreamUpstreamServer, 0, len(serverMap))
-	for _, server := range servers {
-		if check, ok := serverMap[server.Server]; ok && check.valid {
-			retServers = append(retServers, server)
-			delete(serverMap, server.Server)
-		}
-	}
-	return retServers, err
-}
+func deduplicateStreamServers(ctx context.Context, logger *slog.Logger, upstream string, servers []StreamUpstreamServer) ([]StreamUpstreamServer, error) {
+	return deduplicate(ctx, logger, upstream, "stream server", servers)
+}
 
 // hasSameParametersAs checks if a given server has the same parameters.
+// Parameters that aren't set are compared with their default values.
 func (s StreamUpstreamServer) hasSameParametersAs(compareServer StreamUpstreamServer) bool {
-	s.ID = compareServer.ID
-	s.applyDefaults()
-	compareServer.applyDefaults()
-	return reflect.DeepEqual(s, compareServer)
-}
-
-func (s *StreamUpstreamServer) applyDefaults() {
-	if s.MaxConns == nil {
-		s.MaxConns = &defaultMaxConns
-	}
-
-	if s.MaxFails == nil {
-		s.MaxFails = &defaultMaxFails
-	}
-
-	if s.FailTimeout == "" {
-		s.FailTimeout = defaultFailTimeout
-	}
-
-	if s.SlowStart == "" {
-		s.SlowStart = defaultSlowStart
-	}
-
-	if s.Backup == nil {
-		s.Backup = &defaultBackup
-	}
-
-	if s.Down == nil {
-		s.Down = &defaultDown
-	}
-
-	if s.Weight == nil {
-		s.Weight = &defaultWeight
-	}
+	return valueOr(s.MaxConns, defaultMaxConns) == valueOr(compareServer.MaxConns, defaultMaxConns) &&
+		valueOr(s.MaxFails, defaultMaxFails) == valueOr(compareServer.MaxFails, defaultMaxFails) &&
+		valueOr(s.Backup, defaultBackup) == valueOr(compareServer.Backup, defaultBackup) &&
+		valueOr(s.Down, defaultDown) == valueOr(compareServer.Down, defaultDown) &&
+		valueOr(s.Weight, defaultWeight) == valueOr(compareServer.Weight, defaultWeight) &&
+		stringOr(s.FailTimeout, defaultFailTimeout) == stringOr(compareServer.FailTimeout, defaultFailTimeout) &&
+		stringOr(s.SlowStart, defaultSlowStart) == stringOr(compareServer.SlowStart, defaultSlowStart) &&
+		s.Service == compareServer.Service &&
+		(s.Server == compareServer.Server || serverKey(s.Server) == serverKey(compareServer.Server))
 }
 
 func determineStreamUpdates(updatedServers []StreamUpstreamServer, nginxServers []StreamUpstreamServer) (toAdd []StreamUpstreamServer, toRemove []StreamUpstreamServer, toUpdate []StreamUpstreamServer) {
-	for _, server := range updatedServers {
-		updateFound := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server && !server.hasSameParametersAs(serverNGX) {
-				server.ID = serverNGX.ID
-				updateFound = true
-				break
-			}
-		}
-		if updateFound {
-			toUpdate = append(toUpdate, server)
-		}
-	}
-
-	for _, server := range updatedServers {
-		found := false
-		for _, serverNGX := range nginxServers {
-			if server.Server == serverNGX.Server {
-				found = true
-				break
-			}
-		}
-		if !found {
-			toAdd = append(toAdd, server)
-		}
-	}
-
-	for _, serverNGX := range nginxServers {
-		found := false
-		for _, server := range updatedServers {
-			if serverNGX.Server == server.Server {
-				found = true
-				break
-			}
-		}
-		if !found {
-			toRemove = append(toRemove, serverNGX)
-		}
-	}
-
-	return toAdd, toRemove, toUpdate
+	return determineServerUpdates(updatedServers, nginxServers)
 }
 
 // GetStats gets process, slab, connection, request, ssl, zone, stream zone, upstream and stream upstream related stats from the NGINX Plus API.
 func (client *NginxClient) GetStats(ctx context.Context) (*Stats, error) {
-	initialGroup, initialCtx := errgroup.WithContext(ctx)
-	var mu sync.Mutex
-	stats := defaultStats()
-	// Collecting initial stats
-	initialGroup.Go(func() error {
-		endpoints, err := client.GetAvailableEndpoints(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get available Endpoints: %w", err)
-		}
-
-		mu.Lock()
-		stats.endpoints = endpoints
-		mu.Unlock()
-		return nil
-	})
-
-	initialGroup.Go(func() error {
-		nginxInfo, err := client.GetNginxInfo(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get NGINX info: %w", err)
-		}
-
-		mu.Lock()
-		stats.NginxInfo = *nginxInfo
-		mu.Unlock()
-
-		return nil
-	})
-
-	initialGroup.Go(func() error {
-		caches, err := client.GetCaches(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Caches: %w", err)
-		}
+	return invoke(ctx, client, &Operation{Name: "GetStats"}, func(ctx context.Context, _ *Operation) (*Stats, error) {
+		initialGroup, initialCtx := errgroup.WithContext(ctx)
+		var mu sync.Mutex
+		stats := defaultStats()
+		// Collecting initial stats
+		initialGroup.Go(func() error {
+			endpoints, err := client.GetAvailableEndpoints(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get available Endpoints: %w", err)
+			}
 
-		mu.Lock()
-		stats.Caches = *caches
-		mu.Unlock()
+			mu.Lock()
+			stats.endpoints = endpoints
+			mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			nginxInfo, err := client.GetNginxInfo(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get NGINX info: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		processes, err := client.GetProcesses(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Process information: %w", err)
-		}
+			mu.Lock()
+			stats.NginxInfo = *nginxInfo
+			mu.Unlock()
 
-		mu.Lock()
-		stats.Processes = *processes
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			caches, err := client.GetCaches(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get Caches: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		slabs, err := client.GetSlabs(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Slabs: %w", err)
-		}
+			mu.Lock()
+			stats.Caches = *caches
+			mu.Unlock()
 
-		mu.Lock()
-		stats.Slabs = *slabs
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			processes, err := client.GetProcesses(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get Process information: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		httpRequests, err := client.GetHTTPRequests(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get HTTP Requests: %w", err)
-		}
+			mu.Lock()
+			stats.Processes = *processes
+			mu.Unlock()
 
-		mu.Lock()
-		stats.HTTPRequests = *httpRequests
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			slabs, err := client.GetSlabs(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get Slabs: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		ssl, err := client.GetSSL(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get SSL: %w", err)
-		}
+			mu.Lock()
+			stats.Slabs = *slabs
+			mu.Unlock()
 
-		mu.Lock()
-		stats.SSL = *ssl
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			httpRequests, err := client.GetHTTPRequests(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get HTTP Requests: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		serverZones, err := client.GetServerZones(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Server Zones: %w", err)
-		}
+			mu.Lock()
+			stats.HTTPRequests = *httpRequests
+			mu.Unlock()
 
-		mu.Lock()
-		stats.ServerZones = *serverZones
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			ssl, err := client.GetSSL(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get SSL: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		upstreams, err := client.GetUpstreams(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Upstreams: %w", err)
-		}
+			mu.Lock()
+			stats.SSL = *ssl
+			mu.Unlock()
 
-		mu.Lock()
-		stats.Upstreams = *upstreams
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			serverZones, err := client.GetServerZones(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get Server Zones: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		locationZones, err := client.GetLocationZones(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Location Zones: %w", err)
-		}
+			mu.Lock()
+			stats.ServerZones = *serverZones
+			mu.Unlock()
 
-		mu.Lock()
-		stats.LocationZones = *locationZones
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			upstreams, err := client.GetUpstreams(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get Upstreams: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		resolvers, err := client.GetResolvers(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Resolvers: %w", err)
-		}
+			mu.Lock()
+			stats.Upstreams = *upstreams
+			mu.Unlock()
 
-		mu.Lock()
-		stats.Resolvers = *resolvers
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			locationZones, err := client.GetLocationZones(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get Location Zones: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		httpLimitRequests, err := client.GetHTTPLimitReqs(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get HTTPLimitRequests: %w", err)
-		}
+			mu.Lock()
+			stats.LocationZones = *locationZones
+			mu.Unlock()
 
-		mu.Lock()
-		stats.HTTPLimitRequests = *httpLimitRequests
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			resolvers, err := client.GetResolvers(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get Resolvers: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		httpLimitConnections, err := client.GetHTTPConnectionsLimit(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get HTTPLimitConnections: %w", err)
-		}
+			mu.Lock()
+			stats.Resolvers = *resolvers
+			mu.Unlock()
 
-		mu.Lock()
-		stats.HTTPLimitConnections = *httpLimitConnections
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			httpLimitRequests, err := client.GetHTTPLimitReqs(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get HTTPLimitRequests: %w", err)
+			}
 
-	initialGroup.Go(func() error {
-		workers, err := client.GetWorkers(initialCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get Workers: %w", err)
-		}
+			mu.Lock()
+			stats.HTTPLimitRequests = *httpLimitRequests
+			mu.Unlock()
 
-		mu.Lock()
-		stats.Workers = workers
-		mu.Unlock()
+			return nil
+		})
 
-		return nil
-	})
+		initialGroup.Go(func() error {
+			httpLimitConnections, err := client.GetHTTPConnectionsLimit(initialCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get HTTPLimitConnections: %w", err)
+			}
 
-	if err := initialGroup.Wait(); err != nil {
-		return nil, fmt.Errorf("error returned from contacting Plus API: %w", err)
-	}
+			mu.Lock()
+			stats.HTTPLimitConnections = *httpLimitConnections
+			mu.Unlock()
 
-	// Process stream endpoints if they exist
-	if slices.Contains(stats.endpoints, "stream") {
-		availableStreamGroup, asgCtx := errgroup.WithContext(ctx)
+			return nil
+		})
 
-		availableStreamGroup.Go(func() error {
-			streamEndpoints, err := client.GetAvailableStreamEndpoints(asgCtx)
+		initialGroup.Go(func() error {
+			workers, err := client.GetWorkers(initialCtx)
 			if err != nil {
-				return fmt.Errorf("failed to get available Stream Endpoints: %w", err)
+				return fmt.Errorf("failed to get Workers: %w", err)
 			}
 
 			mu.Lock()
-			stats.streamEndpoints = streamEndpoints
+			stats.Workers = workers
 			mu.Unlock()
 
 			return nil
 		})
 
-		if err := availableStreamGroup.Wait(); err != nil {
-			return nil, fmt.Errorf("no useful metrics found in stream stats: %w", err)
+		if err := initialGroup.Wait(); err != nil {
+			return nil, fmt.Errorf("error returned from contacting Plus API: %w", err)
 		}
 
-		streamGroup, sgCtx := errgroup.WithContext(ctx)
+		// Process stream endpoints if they exist
+		if slices.Contains(stats.endpoints, "stream") {
+			availableStreamGroup, asgCtx := errgroup.WithContext(ctx)
 
-		if slices.Contains(stats.streamEndpoints, "server_zones") {
-			streamGroup.Go(func() error {
-				streamServerZones, err := client.GetStreamServerZones(sgCtx)
+			availableStreamGroup.Go(func() error {
+				streamEndpoints, err := client.GetAvailableStreamEndpoints(asgCtx)
 				if err != nil {
-					return fmt.Errorf("failed to get streamServerZones: %w", err)
+					return fmt.Errorf("failed to get available Stream Endpoints: %w", err)
 				}
 
 				mu.Lock()
-				stats.StreamServerZones = *streamServerZones
+				stats.streamEndpoints = streamEndpoints
 				mu.Unlock()
 
 				return nil
 			})
-		}
 
-		if slices.Contains(stats.streamEndpoints, "upstreams") {
-			streamGroup.Go(func() error {
-				streamUpstreams, err := client.GetStreamUpstreams(sgCtx)
-				if err != nil {
-					return fmt.Errorf("failed to get StreamUpstreams: %w", err)
-				}
+			if err := availableStreamGroup.Wait(); err != nil {
+				return nil, fmt.Errorf("no useful metrics found in stream stats: %w", err)
+			}
 
-				mu.Lock()
-				stats.StreamUpstreams = *streamUpstreams
-				mu.Unlock()
+			streamGroup, sgCtx := errgroup.WithContext(ctx)
 
-				return nil
-			})
-		}
+			if slices.Contains(stats.streamEndpoints, "server_zones") {
+				streamGroup.Go(func() error {
+					streamServerZones, err := client.GetStreamServerZones(sgCtx)
+					if err != nil {
+						return fmt.Errorf("failed to get streamServerZones: %w", err)
+					}
 
-		if slices.Contains(stats.streamEndpoints, "limit_conns") {
-			streamGroup.Go(func() error {
-				streamConnectionsLimit, err := client.GetStreamConnectionsLimit(sgCtx)
-				if err != nil {
-					return fmt.Errorf("failed to get StreamLimitConnections: %w", err)
-				}
+					mu.Lock()
+					stats.StreamServerZones = *streamServerZones
+					mu.Unlock()
 
-				mu.Lock()
-				stats.StreamLimitConnections = *streamConnectionsLimit
-				mu.Unlock()
+					return nil
+				})
+			}
 
-				return nil
-			})
+			if slices.Contains(stats.streamEndpoints, "upstreams") {
+				streamGroup.Go(func() error {
+					streamUpstreams, err := client.GetStreamUpstreams(sgCtx)
+					if err != nil {
+						return fmt.Errorf("failed to get StreamUpstreams: %w", err)
+					}
 
-			streamGroup.Go(func() error {
-				streamZoneSync, err := client.GetStreamZoneSync(sgCtx)
-				if err != nil {
-					return fmt.Errorf("failed to get StreamZoneSync: %w", err)
-				}
+					mu.Lock()
+					stats.StreamUpstreams = *streamUpstreams
+					mu.Unlock()
 
-				mu.Lock()
-				stats.StreamZoneSync = streamZoneSync
-				mu.Unlock()
+					return nil
+				})
+			}
 
-				return nil
-			})
-		}
+			if slices.Contains(stats.streamEndpoints, "limit_conns") {
+				streamGroup.Go(func() error {
+					streamConnectionsLimit, err := client.GetStreamConnectionsLimit(sgCtx)
+					if err != nil {
+						return fmt.Errorf("failed to get StreamLimitConnections: %w", err)
+					}
 
-		if err := streamGroup.Wait(); err != nil {
-			return nil, fmt.Errorf("no useful metrics found in stream stats: %w", err)
-		}
-	}
+					mu.Lock()
+					stats.StreamLimitConnections = *streamConnectionsLimit
+					mu.Unlock()
 
-	// Report connection metrics separately so it does not influence the results
-	connectionsGroup, cgCtx := errgroup.WithContext(ctx)
+					return nil
+				})
 
-	connectionsGroup.Go(func() error {
-		// replace this call with a context specific call
-		connections, err := client.GetConnections(cgCtx)
-		if err != nil {
-			return fmt.Errorf("failed to get connections: %w", err)
+				streamGroup.Go(func() error {
+					streamZoneSync, err := client.GetStreamZoneSync(sgCtx)
+					if err != nil {
+						return fmt.Errorf("failed to get StreamZoneSync: %w", err)
+					}
+
+					mu.Lock()
+					stats.StreamZoneSync = streamZoneSync
+					mu.Unlock()
+
+					return nil
+				})
+			}
+
+			if err := streamGroup.Wait(); err != nil {
+				return nil, fmt.Errorf("no useful metrics found in stream stats: %w", err)
+			}
 		}
 
-		mu.Lock()
-		stats.Connections = *connections
-		mu.Unlock()
+		// Report connection metrics separately so it does not influence the results
+		connectionsGroup, cgCtx := errgroup.WithContext(ctx)
 
-		return nil
-	})
+		connectionsGroup.Go(func() error {
+			// replace this call with a context specific call
+			connections, err := client.GetConnections(cgCtx)
+			if err != nil {
+				return fmt.Errorf("failed to get connections: %w", err)
+			}
 
-	if err := connectionsGroup.Wait(); err != nil {
-		return nil, fmt.Errorf("connections metrics not found: %w", err)
-	}
+			mu.Lock()
+			stats.Connections = *connections
+			mu.Unlock()
+
+			return nil
+		})
 
-	return &stats.Stats, nil
+		if err := connectionsGroup.Wait(); err != nil {
+			return nil, fmt.Errorf("connections metrics not found: %w", err)
+		}
+
+		return &stats.Stats, nil
+	})
 }
 
 // GetAvailableEndpoints returns available endpoints in the API.
 func (client *NginxClient) GetAvailableEndpoints(ctx context.Context) ([]string, error) {
-	var endpoints []string
-	err := client.get(ctx, "", &endpoints)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get endpoints: %w", err)
-	}
-	return endpoints, nil
+	return invoke(ctx, client, &Operation{Name: "GetAvailableEndpoints"}, func(ctx context.Context, _ *Operation) ([]string, error) {
+		var endpoints []string
+		err := client.get(ctx, "", &endpoints)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get endpoints: %w", err)
+		}
+		return endpoints, nil
+	})
 }
 
 // GetAvailableStreamEndpoints returns available stream endpoints in the API with a context.
 func (client *NginxClient) GetAvailableStreamEndpoints(ctx context.Context) ([]string, error) {
-	var endpoints []string
-	err := client.get(ctx, "stream", &endpoints)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get endpoints: %w", err)
-	}
-	return endpoints, nil
+	return invoke(ctx, client, &Operation{Name: "GetAvailableStreamEndpoints"}, func(ctx context.Context, _ *Operation) ([]string, error) {
+		var endpoints []string
+		err := client.get(ctx, "stream", &endpoints)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get endpoints: %w", err)
+		}
+		return endpoints, nil
+	})
 }
 
 // GetNginxInfo returns Nginx stats with a context.
 func (client *NginxClient) GetNginxInfo(ctx context.Context) (*NginxInfo, error) {
-	var info NginxInfo
-	err := client.get(ctx, "nginx", &info)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get info: %w", err)
-	}
-	return &info, nil
+	return invoke(ctx, client, &Operation{Name: "GetNginxInfo"}, func(ctx context.Context, _ *Operation) (*NginxInfo, error) {
+		var info NginxInfo
+		err := client.get(ctx, "nginx", &info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get info: %w", err)
+		}
+		if client.idCache != nil {
+			client.idCache.observeGeneration(info.Generation)
+		}
+		return &info, nil
+	})
 }
 
 // GetNginxLicense returns Nginx License data with a context.
 func (client *NginxClient) GetNginxLicense(ctx context.Context) (*NginxLicense, error) {
-	var data NginxLicense
+	return invoke(ctx, client, &Operation{Name: "GetNginxLicense"}, func(ctx context.Context, _ *Operation) (*NginxLicense, error) {
+		var data NginxLicense
 
-	info, err := client.GetNginxInfo(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get nginx info: %w", err)
-	}
-	release, err := extractPlusVersionValues(info.Build)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get nginx plus release: %w", err)
-	}
+		info, err := client.GetNginxInfo(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nginx info: %w", err)
+		}
+		release, err := extractPlusVersionValues(info.Build)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nginx plus release: %w", err)
+		}
 
-	if (client.apiVersion < 9) || (release < 33) {
-		return &data, nil
-	}
+		if (client.apiVersion < 9) || (release < 33) {
+			return &data, nil
+		}
 
-	err = client.get(ctx, "license", &data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get license: %w", err)
-	}
-	return &data, nil
+		err = client.get(ctx, "license", &data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get license: %w", err)
+		}
+		return &data, nil
+	})
 }
 
 // GetCaches returns Cache stats with a context.
 func (client *NginxClient) GetCaches(ctx context.Context) (*Caches, error) {
-	var caches Caches
-	err := client.get(ctx, "http/caches", &caches)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get caches: %w", err)
-	}
-	return &caches, nil
+	return invoke(ctx, client, &Operation{Name: "GetCaches"}, func(ctx context.Context, _ *Operation) (*Caches, error) {
+		var caches Caches
+		err := client.get(ctx, "http/caches", &caches)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get caches: %w", err)
+		}
+		return &caches, nil
+	})
 }
 
 // GetSlabs returns Slabs stats with a context.
 func (client *NginxClient) GetSlabs(ctx context.Context) (*Slabs, error) {
-	var slabs Slabs
-	err := client.get(ctx, "slabs", &slabs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get slabs: %w", err)
-	}
-	return &slabs, nil
+	return invoke(ctx, client, &Operation{Name: "GetSlabs"}, func(ctx context.Context, _ *Operation) (*Slabs, error) {
+		var slabs Slabs
+		err := client.get(ctx, "slabs", &slabs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get slabs: %w", err)
+		}
+		return &slabs, nil
+	})
 }
 
 // GetConnections returns Connections stats with a context.
 func (client *NginxClient) GetConnections(ctx context.Context) (*Connections, error) {
-	var cons Connections
-	err := client.get(ctx, "connections", &cons)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get connections: %w", err)
-	}
-	return &cons, nil
+	return invoke(ctx, client, &Operation{Name: "GetConnections"}, func(ctx context.Context, _ *Operation) (*Connections, error) {
+		var cons Connections
+		err := client.get(ctx, "connections", &cons)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get connections: %w", err)
+		}
+		return &cons, nil
+	})
 }
 
 // GetHTTPRequests returns http/requests stats with a context.
 func (client *NginxClient) GetHTTPRequests(ctx context.Context) (*HTTPRequests, error) {
-	var requests HTTPRequests
-	err := client.get(ctx, "http/requests", &requests)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get http requests: %w", err)
-	}
-	return &requests, nil
+	return invoke(ctx, client, &Operation{Name: "GetHTTPRequests"}, func(ctx context.Context, _ *Operation) (*HTTPRequests, error) {
+		var requests HTTPRequests
+		err := client.get(ctx, "http/requests", &requests)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get http requests: %w", err)
+		}
+		return &requests, nil
+	})
 }
 
 // GetSSL returns SSL stats with a context.
 func (client *NginxClient) GetSSL(ctx context.Context) (*SSL, error) {
-	var ssl SSL
-	err := client.get(ctx, "ssl", &ssl)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get ssl: %w", err)
-	}
-	return &ssl, nil
+	return invoke(ctx, client, &Operation{Name: "GetSSL"}, func(ctx context.Context, _ *Operation) (*SSL, error) {
+		var ssl SSL
+		err := client.get(ctx, "ssl", &ssl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ssl: %w", err)
+		}
+		return &ssl, nil
+	})
 }
 
 // GetServerZones returns http/server_zones stats with a context.
 func (client *NginxClient) GetServerZones(ctx context.Context) (*ServerZones, error) {
-	var zones ServerZones
-	err := client.get(ctx, "http/server_zones", &zones)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get server zones: %w", err)
-	}
-	return &zones, err
+	return invoke(ctx, client, &Operation{Name: "GetServerZones"}, func(ctx context.Context, _ *Operation) (*ServerZones, error) {
+		var zones ServerZones
+		err := client.get(ctx, "http/server_zones", &zones)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get server zones: %w", err)
+		}
+		return &zones, err
+	})
 }
 
 // GetStreamServerZones returns stream/server_zones stats with a context.
 func (client *NginxClient) GetStreamServerZones(ctx context.Context) (*StreamServerZones, error) {
-	var zones StreamServerZones
-	err := client.get(ctx, "stream/server_zones", &zones)
-	if err != nil {
-		var ie *internalError
-		if errors.As(err, &ie) {
-			if ie.Code() == pathNotFoundCode {
-				return &zones, nil
+	return invoke(ctx, client, &Operation{Name: "GetStreamServerZones"}, func(ctx context.Context, _ *Operation) (*StreamServerZones, error) {
+		var zones StreamServerZones
+		err := client.get(ctx, "stream/server_zones", &zones)
+		if err != nil {
+			var ie *internalError
+			if errors.As(err, &ie) {
+				if ie.Code() == pathNotFoundCode {
+					return &zones, nil
+				}
 			}
+			return nil, fmt.Errorf("failed to get stream server zones: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get stream server zones: %w", err)
-	}
-	return &zones, err
+		return &zones, err
+	})
 }
 
 // GetUpstreams returns http/upstreams stats with a context.
 func (client *NginxClient) GetUpstreams(ctx context.Context) (*Upstreams, error) {
-	var upstreams Upstreams
-	err := client.get(ctx, "http/upstreams", &upstreams)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get upstreams: %w", err)
-	}
-	return &upstreams, nil
+	return invoke(ctx, client, &Operation{Name: "GetUpstreams"}, func(ctx context.Context, _ *Operation) (*Upstreams, error) {
+		var upstreams Upstreams
+		err := client.get(ctx, "http/upstreams", &upstreams)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get upstreams: %w", err)
+		}
+		return &upstreams, nil
+	})
 }
 
 // GetStreamUpstreams returns stream/upstreams stats with a context.
 func (client *NginxClient) GetStreamUpstreams(ctx context.Context) (*StreamUpstreams, error) {
-	var upstreams StreamUpstreams
-	err := client.get(ctx, "stream/upstreams", &upstreams)
-	if err != nil {
-		var ie *internalError
-		if errors.As(err, &ie) {
-			if ie.Code() == pathNotFoundCode {
-				return &upstreams, nil
+	return invoke(ctx, client, &Operation{Name: "GetStreamUpstreams"}, func(ctx context.Context, _ *Operation) (*StreamUpstreams, error) {
+		var upstreams StreamUpstreams
+		err := client.get(ctx, "stream/upstreams", &upstreams)
+		if err != nil {
+			var ie *internalError
+			if errors.As(err, &ie) {
+				if ie.Code() == pathNotFoundCode {
+					return &upstreams, nil
+				}
 			}
+			return nil, fmt.Errorf("failed to get stream upstreams: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get stream upstreams: %w", err)
-	}
-	return &upstreams, nil
+		return &upstreams, nil
+	})
 }
 
 // GetStreamZoneSync returns stream/zone_sync stats with a context.
 func (client *NginxClient) GetStreamZoneSync(ctx context.Context) (*StreamZoneSync, error) {
-	var streamZoneSync StreamZoneSync
-	err := client.get(ctx, "stream/zone_sync", &streamZoneSync)
-	if err != nil {
-		var ie *internalError
-		if errors.As(err, &ie) {
-			if ie.Code() == pathNotFoundCode {
-				return nil, nil
+	return invoke(ctx, client, &Operation{Name: "GetStreamZoneSync"}, func(ctx context.Context, _ *Operation) (*StreamZoneSync, error) {
+		var streamZoneSync StreamZoneSync
+		err := client.get(ctx, "stream/zone_sync", &streamZoneSync)
+		if err != nil {
+			var ie *internalError
+			if errors.As(err, &ie) {
+				if ie.Code() == pathNotFoundCode {
+					return nil, nil
+				}
 			}
+			return nil, fmt.Errorf("failed to get stream zone sync: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get stream zone sync: %w", err)
-	}
 
-	return &streamZoneSync, err
+		return &streamZoneSync, err
+	})
 }
 
 // GetLocationZones returns http/location_zones stats with a context.
 func (client *NginxClient) GetLocationZones(ctx context.Context) (*LocationZones, error) {
-	var locationZones LocationZones
-	if client.apiVersion < 5 {
-		return &locationZones, nil
-	}
-	err := client.get(ctx, "http/location_zones", &locationZones)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get location zones: %w", err)
-	}
+	return invoke(ctx, client, &Operation{Name: "GetLocationZones"}, func(ctx context.Context, _ *Operation) (*LocationZones, error) {
+		var locationZones LocationZones
+		if client.apiVersion < 5 {
+			return &locationZones, nil
+		}
+		err := client.get(ctx, "http/location_zones", &locationZones)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get location zones: %w", err)
+		}
 
-	return &locationZones, err
+		return &locationZones, err
+	})
 }
 
 // GetResolvers returns Resolvers stats with a context.
 func (client *NginxClient) GetResolvers(ctx context.Context) (*Resolvers, error) {
-	var resolvers Resolvers
-	if client.apiVersion < 5 {
-		return &resolvers, nil
-	}
-	err := client.get(ctx, "resolvers", &resolvers)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get resolvers: %w", err)
-	}
+	return invoke(ctx, client, &Operation{Name: "GetResolvers"}, func(ctx context.Context, _ *Operation) (*Resolvers, error) {
+		var resolvers Resolvers
+		if client.apiVersion < 5 {
+			return &resolvers, nil
+		}
+		err := client.get(ctx, "resolvers", &resolvers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get resolvers: %w", err)
+		}
 
-	return &resolvers, err
+		return &resolvers, err
+	})
 }
 
 // GetProcesses returns Processes stats with a context.
 func (client *NginxClient) GetProcesses(ctx context.Context) (*Processes, error) {
-	var processes Processes
-	err := client.get(ctx, "processes", &processes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get processes: %w", err)
-	}
+	return invoke(ctx, client, &Operation{Name: "GetProcesses"}, func(ctx context.Context, _ *Operation) (*Processes, error) {
+		var processes Processes
+		err := client.get(ctx, "processes", &processes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get processes: %w", err)
+		}
 
-	return &processes, err
+		return &processes, err
+	})
 }
 
 // KeyValPairs are the key-value pairs stored in a zone.
@@ -1900,12 +2107,16 @@ type KeyValPairsByZone map[string]KeyValPairs
 
 // GetKeyValPairs fetches key/value pairs for a given HTTP zone.
 func (client *NginxClient) GetKeyValPairs(ctx context.Context, zone string) (KeyValPairs, error) {
-	return client.getKeyValPairs(ctx, zone, httpContext)
+	return invoke(ctx, client, &Operation{Name: "GetKeyValPairs", Zone: zone}, func(ctx context.Context, _ *Operation) (KeyValPairs, error) {
+		return client.getKeyValPairs(ctx, zone, httpContext)
+	})
 }
 
 // GetStreamKeyValPairs fetches key/value pairs for a given Stream zone.
 func (client *NginxClient) GetStreamKeyValPairs(ctx context.Context, zone string) (KeyValPairs, error) {
-	return client.getKeyValPairs(ctx, zone, streamContext)
+	return invoke(ctx, client, &Operation{Name: "GetStreamKeyValPairs", Zone: zone}, func(ctx context.Context, _ *Operation) (KeyValPairs, error) {
+		return client.getKeyValPairs(ctx, zone, streamContext)
+	})
 }
 
 func (client *NginxClient) getKeyValPairs(ctx context.Context, zone string, stream bool) (KeyValPairs, error) {
@@ -1928,12 +2139,16 @@ func (client *NginxClient) getKeyValPairs(ctx context.Context, zone string, stre
 
 // GetAllKeyValPairs fetches all key/value pairs for all HTTP zones.
 func (client *NginxClient) GetAllKeyValPairs(ctx context.Context) (KeyValPairsByZone, error) {
-	return client.getAllKeyValPairs(ctx, httpContext)
+	return invoke(ctx, client, &Operation{Name: "GetAllKeyValPairs"}, func(ctx context.Context, _ *Operation) (KeyValPairsByZone, error) {
+		return client.getAllKeyValPairs(ctx, httpContext)
+	})
 }
 
 // GetAllStreamKeyValPairs fetches all key/value pairs for all Stream zones.
 func (client *NginxClient) GetAllStreamKeyValPairs(ctx context.Context) (KeyValPairsByZone, error) {
-	return client.getAllKeyValPairs(ctx, streamContext)
+	return invoke(ctx, client, &Operation{Name: "GetAllStreamKeyValPairs"}, func(ctx context.Context, _ *Operation) (KeyValPairsByZone, error) {
+		return client.getAllKeyValPairs(ctx, streamContext)
+	})
 }
 
 func (client *NginxClient) getAllKeyValPairs(ctx context.Context, stream bool) (KeyValPairsByZone, error) {
@@ -1953,15 +2168,27 @@ func (client *NginxClient) getAllKeyValPairs(ctx context.Context, stream bool) (
 
 // AddKeyValPair adds a new key/value pair to a given HTTP zone.
 func (client *NginxClient) AddKeyValPair(ctx context.Context, zone string, key string, val string) error {
-	return client.addKeyValPair(ctx, zone, key, val, httpContext)
+	return invokeErr(ctx, client, &Operation{Name: "AddKeyValPair", Zone: zone, Key: key, Request: KeyValPairs{key: val}, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		input, err := requestAs[KeyValPairs](op)
+		if err != nil {
+			return err
+		}
+		return client.addKeyValPair(ctx, zone, input, httpContext)
+	})
 }
 
 // AddStreamKeyValPair adds a new key/value pair to a given Stream zone.
 func (client *NginxClient) AddStreamKeyValPair(ctx context.Context, zone string, key string, val string) error {
-	return client.addKeyValPair(ctx, zone, key, val, streamContext)
+	return invokeErr(ctx, client, &Operation{Name: "AddStreamKeyValPair", Zone: zone, Key: key, Request: KeyValPairs{key: val}, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		input, err := requestAs[KeyValPairs](op)
+		if err != nil {
+			return err
+		}
+		return client.addKeyValPair(ctx, zone, input, streamContext)
+	})
 }
 
-func (client *NginxClient) addKeyValPair(ctx context.Context, zone string, key string, val string, stream bool) error {
+func (client *NginxClient) addKeyValPair(ctx context.Context, zone string, input KeyValPairs, stream bool) error {
 	base := "http"
 	if stream {
 		base = "stream"
@@ -1971,8 +2198,8 @@ func (client *NginxClient) addKeyValPair(ctx context.Context, zone string, key s
 	}
 
 	path := fmt.Sprintf("%v/keyvals/%v", base, zone)
-	input := KeyValPairs{key: val}
 	err := client.post(ctx, path, &input)
+	client.auditKeyVals(ctx, AuditAddKeyVal, zone, input, stream, err)
 	if err != nil {
 		return fmt.Errorf("failed to add key value pair for %v/%v zone: %w", base, zone, err)
 	}
@@ -1981,15 +2208,27 @@ func (client *NginxClient) addKeyValPair(ctx context.Context, zone string, key s
 
 // ModifyKeyValPair modifies the value of an existing key in a given HTTP zone.
 func (client *NginxClient) ModifyKeyValPair(ctx context.Context, zone string, key string, val string) error {
-	return client.modifyKeyValPair(ctx, zone, key, val, httpContext)
+	return invokeErr(ctx, client, &Operation{Name: "ModifyKeyValPair", Zone: zone, Key: key, Request: KeyValPairs{key: val}, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		input, err := requestAs[KeyValPairs](op)
+		if err != nil {
+			return err
+		}
+		return client.modifyKeyValPair(ctx, zone, input, httpContext)
+	})
 }
 
 // ModifyStreamKeyValPair modifies the value of an existing key in a given Stream zone.
 func (client *NginxClient) ModifyStreamKeyValPair(ctx context.Context, zone string, key string, val string) error {
-	return client.modifyKeyValPair(ctx, zone, key, val, streamContext)
+	return invokeErr(ctx, client, &Operation{Name: "ModifyStreamKeyValPair", Zone: zone, Key: key, Request: KeyValPairs{key: val}, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		input, err := requestAs[KeyValPairs](op)
+		if err != nil {
+			return err
+		}
+		return client.modifyKeyValPair(ctx, zone, input, streamContext)
+	})
 }
 
-func (client *NginxClient) modifyKeyValPair(ctx context.Context, zone string, key string, val string, stream bool) error {
+func (client *NginxClient) modifyKeyValPair(ctx context.Context, zone string, input KeyValPairs, stream bool) error {
 	base := "http"
 	if stream {
 		base = "stream"
@@ -1999,8 +2238,8 @@ func (client *NginxClient) modifyKeyValPair(ctx context.Context, zone string, ke
 	}
 
 	path := fmt.Sprintf("%v/keyvals/%v", base, zone)
-	input := KeyValPairs{key: val}
 	err := client.patch(ctx, path, &input, http.StatusNoContent)
+	client.auditKeyVals(ctx, AuditModifyKeyVal, zone, input, stream, err)
 	if err != nil {
 		return fmt.Errorf("failed to update key value pair for %v/%v zone: %w", base, zone, err)
 	}
@@ -2009,12 +2248,16 @@ func (client *NginxClient) modifyKeyValPair(ctx context.Context, zone string, ke
 
 // DeleteKeyValuePair deletes the key/value pair for a key in a given HTTP zone.
 func (client *NginxClient) DeleteKeyValuePair(ctx context.Context, zone string, key string) error {
-	return client.deleteKeyValuePair(ctx, zone, key, httpContext)
+	return invokeErr(ctx, client, &Operation{Name: "DeleteKeyValuePair", Zone: zone, Key: key, Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		return client.deleteKeyValuePair(ctx, zone, key, httpContext)
+	})
 }
 
 // DeleteStreamKeyValuePair deletes the key/value pair for a key in a given Stream zone.
 func (client *NginxClient) DeleteStreamKeyValuePair(ctx context.Context, zone string, key string) error {
-	return client.deleteKeyValuePair(ctx, zone, key, streamContext)
+	return invokeErr(ctx, client, &Operation{Name: "DeleteStreamKeyValuePair", Zone: zone, Key: key, Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		return client.deleteKeyValuePair(ctx, zone, key, streamContext)
+	})
 }
 
 // To delete a key/value pair you set the value to null via the API,
@@ -2034,6 +2277,7 @@ func (client *NginxClient) deleteKeyValuePair(ctx context.Context, zone string,
 
 	path := fmt.Sprintf("%v/keyvals/%v", base, zone)
 	err := client.patch(ctx, path, &keyval, http.StatusNoContent)
+	client.audit(ctx, AuditRecord{Action: AuditDeleteKeyVal, Context: base, Zone: zone, Key: key}, err)
 	if err != nil {
 		return fmt.Errorf("failed to remove key values pair for %v/%v zone: %w", base, zone, err)
 	}
@@ -2042,12 +2286,16 @@ func (client *NginxClient) deleteKeyValuePair(ctx context.Context, zone string,
 
 // DeleteKeyValPairs deletes all the key-value pairs in a given HTTP zone.
 func (client *NginxClient) DeleteKeyValPairs(ctx context.Context, zone string) error {
-	return client.deleteKeyValPairs(ctx, zone, httpContext)
+	return invokeErr(ctx, client, &Operation{Name: "DeleteKeyValPairs", Zone: zone, Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		return client.deleteKeyValPairs(ctx, zone, httpContext)
+	})
 }
 
 // DeleteStreamKeyValPairs deletes all the key-value pairs in a given Stream zone.
 func (client *NginxClient) DeleteStreamKeyValPairs(ctx context.Context, zone string) error {
-	return client.deleteKeyValPairs(ctx, zone, streamContext)
+	return invokeErr(ctx, client, &Operation{Name: "DeleteStreamKeyValPairs", Zone: zone, Mutating: true}, func(ctx context.Context, _ *Operation) error {
+		return client.deleteKeyValPairs(ctx, zone, streamContext)
+	})
 }
 
 func (client *NginxClient) deleteKeyValPairs(ctx context.Context, zone string, stream bool) error {
@@ -2061,6 +2309,7 @@ func (client *NginxClient) deleteKeyValPairs(ctx context.Context, zone string, s
 
 	path := fmt.Sprintf("%v/keyvals/%v", base, zone)
 	err := client.delete(ctx, path, http.StatusNoContent)
+	client.audit(ctx, AuditRecord{Action: AuditDeleteKeyVals, Context: base, Zone: zone}, err)
 	if err != nil {
 		return fmt.Errorf("failed to remove all key value pairs for %v/%v zone: %w", base, zone, err)
 	}
@@ -2068,114 +2317,154 @@ func (client *NginxClient) deleteKeyValPairs(ctx context.Context, zone string, s
 }
 
 // UpdateHTTPServer updates the server of the upstream with the matching server ID.
+// The Server field can be left empty to update only the other parameters of the server.
 func (client *NginxClient) UpdateHTTPServer(ctx context.Context, upstream string, server UpstreamServer) error {
+	return invokeErr(ctx, client, &Operation{Name: "UpdateHTTPServer", Upstream: upstream, Server: server.Server, Request: server, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		server, err := requestAs[UpstreamServer](op)
+		if err != nil {
+			return err
+		}
+		if err := validateUpdate(server); err != nil {
+			err = fmt.Errorf("failed to update %v server to %v upstream: %w", serverName(server), upstream, err)
+			client.audit(ctx, AuditRecord{Action: AuditUpdateServer, Context: contextName(httpContext), Upstream: upstream, Server: server.Server, After: server}, err)
+			return err
+		}
+		return client.updateHTTPServer(ctx, upstream, server, nil)
+	})
+}
+
+// updateHTTPServer updates the server. The before state, if known, is passed on to the audit sink.
+func (client *NginxClient) updateHTTPServer(ctx context.Context, upstream string, server UpstreamServer, before any) error {
 	path := fmt.Sprintf("http/upstreams/%v/servers/%v", upstream, server.ID)
 	// The server ID is expected in the URI, but not expected in the body.
 	// The NGINX API will return
 	//   {"error":{"status":400,"text":"unknown parameter \"id\"","code":"UpstreamConfFormatError"}
 	// if the ID field is present.
+	after := server
 	server.ID = 0
 	err := client.patch(ctx, path, &server, http.StatusOK)
+	client.audit(ctx, AuditRecord{Action: AuditUpdateServer, Context: contextName(httpContext), Upstream: upstream, Server: server.Server, Before: before, After: after}, err)
+	client.updateServerIDCache(httpContext, upstream, ServerUpdated, server.Server, after.ID, err)
 	if err != nil {
-		return fmt.Errorf("failed to update %v server to %v upstream: %w", server.Server, upstream, err)
+		return fmt.Errorf("failed to update %v server to %v upstream: %w", serverName(after), upstream, err)
 	}
 
 	return nil
 }
 
 // UpdateStreamServer updates the stream server of the upstream with the matching server ID.
+// The Server field can be left empty to update only the other parameters of the server.
 func (client *NginxClient) UpdateStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer) error {
+	return invokeErr(ctx, client, &Operation{Name: "UpdateStreamServer", Upstream: upstream, Server: server.Server, Request: server, Mutating: true}, func(ctx context.Context, op *Operation) error {
+		server, err := requestAs[StreamUpstreamServer](op)
+		if err != nil {
+			return err
+		}
+		if err := validateUpdate(server); err != nil {
+			err = fmt.Errorf("failed to update %v stream server to %v upstream: %w", serverName(server), upstream, err)
+			client.audit(ctx, AuditRecord{Action: AuditUpdateServer, Context: contextName(streamContext), Upstream: upstream, Server: server.Server, After: server}, err)
+			return err
+		}
+		return client.updateStreamServer(ctx, upstream, server, nil)
+	})
+}
+
+// updateStreamServer updates the server. The before state, if known, is passed on to the audit sink.
+func (client *NginxClient) updateStreamServer(ctx context.Context, upstream string, server StreamUpstreamServer, before any) error {
 	path := fmt.Sprintf("stream/upstreams/%v/servers/%v", upstream, server.ID)
 	// The server ID is expected in the URI, but not expected in the body.
 	// The NGINX API will return
 	//   {"error":{"status":400,"text":"unknown parameter \"id\"","code":"UpstreamConfFormatError"}
 	// if the ID field is present.
+	after := server
 	server.ID = 0
 	err := client.patch(ctx, path, &server, http.StatusOK)
+	client.audit(ctx, AuditRecord{Action: AuditUpdateServer, Context: contextName(streamContext), Upstream: upstream, Server: server.Server, Before: before, After: after}, err)
+	client.updateServerIDCache(streamContext, upstream, ServerUpdated, server.Server, after.ID, err)
 	if err != nil {
-		return fmt.Errorf("failed to update %v stream server to %v upstream: %w", server.Server, upstream, err)
+		return fmt.Errorf("failed to update %v stream server to %v upstream: %w", serverName(after), upstream, err)
 	}
 
 	return nil
 }
 
+// serverName returns the address of the server for errors, or its ID if the address is not set.
+func serverName[S Server](server S) string {
+	if server.Address() == "" {
+		return fmt.Sprintf("ID %v", server.ServerID())
+	}
+	return server.Address()
+}
+
 // Version returns client's current N+ API version.
 func (client *NginxClient) Version() int {
 	return client.apiVersion
 }
 
-func addPortToServer(server string) string {
-	if len(strings.Split(server, ":")) == 2 {
-		return server
-	}
-
-	if len(strings.Split(server, "]:")) == 2 {
-		return server
-	}
-
-	if strings.HasPrefix(server, "unix:") {
-		return server
-	}
-
-	return fmt.Sprintf("%v:%v", server, defaultServerPort)
-}
-
 // GetHTTPLimitReqs returns http/limit_reqs stats with a context.
 func (client *NginxClient) GetHTTPLimitReqs(ctx context.Context) (*HTTPLimitRequests, error) {
-	var limitReqs HTTPLimitRequests
-	if client.apiVersion < 6 {
+	return invoke(ctx, client, &Operation{Name: "GetHTTPLimitReqs"}, func(ctx context.Context, _ *Operation) (*HTTPLimitRequests, error) {
+		var limitReqs HTTPLimitRequests
+		if client.apiVersion < 6 {
+			return &limitReqs, nil
+		}
+		err := client.get(ctx, "http/limit_reqs", &limitReqs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get http limit requests: %w", err)
+		}
 		return &limitReqs, nil
-	}
-	err := client.get(ctx, "http/limit_reqs", &limitReqs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get http limit requests: %w", err)
-	}
-	return &limitReqs, nil
+	})
 }
 
 // GetHTTPConnectionsLimit returns http/limit_conns stats with a context.
 func (client *NginxClient) GetHTTPConnectionsLimit(ctx context.Context) (*HTTPLimitConnections, error) {
-	var limitConns HTTPLimitConnections
-	if client.apiVersion < 6 {
+	return invoke(ctx, client, &Operation{Name: "GetHTTPConnectionsLimit"}, func(ctx context.Context, _ *Operation) (*HTTPLimitConnections, error) {
+		var limitConns HTTPLimitConnections
+		if client.apiVersion < 6 {
+			return &limitConns, nil
+		}
+		err := client.get(ctx, "http/limit_conns", &limitConns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get http connections limit: %w", err)
+		}
 		return &limitConns, nil
-	}
-	err := client.get(ctx, "http/limit_conns", &limitConns)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get http connections limit: %w", err)
-	}
-	return &limitConns, nil
+	})
 }
 
 // GetStreamConnectionsLimit returns stream/limit_conns stats with a context.
 func (client *NginxClient) GetStreamConnectionsLimit(ctx context.Context) (*StreamLimitConnections, error) {
-	var limitConns StreamLimitConnections
-	if client.apiVersion < 6 {
-		return &limitConns, nil
-	}
-	err := client.get(ctx, "stream/limit_conns", &limitConns)
-	if err != nil {
-		var ie *internalError
-		if errors.As(err, &ie) {
-			if ie.Code() == pathNotFoundCode {
-				return &limitConns, nil
+	return invoke(ctx, client, &Operation{Name: "GetStreamConnectionsLimit"}, func(ctx context.Context, _ *Operation) (*StreamLimitConnections, error) {
+		var limitConns StreamLimitConnections
+		if client.apiVersion < 6 {
+			return &limitConns, nil
+		}
+		err := client.get(ctx, "stream/limit_conns", &limitConns)
+		if err != nil {
+			var ie *internalError
+			if errors.As(err, &ie) {
+				if ie.Code() == pathNotFoundCode {
+					return &limitConns, nil
+				}
 			}
+			return nil, fmt.Errorf("failed to get stream connections limit: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get stream connections limit: %w", err)
-	}
-	return &limitConns, nil
+		return &limitConns, nil
+	})
 }
 
 // GetWorkers returns workers stats.
 func (client *NginxClient) GetWorkers(ctx context.Context) ([]*Workers, error) {
-	var workers []*Workers
-	if client.apiVersion < 9 {
+	return invoke(ctx, client, &Operation{Name: "GetWorkers"}, func(ctx context.Context, _ *Operation) ([]*Workers, error) {
+		var workers []*Workers
+		if client.apiVersion < 9 {
+			return workers, nil
+		}
+		err := client.get(ctx, "workers", &workers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workers: %w", err)
+		}
 		return workers, nil
-	}
-	err := client.get(ctx, "workers", &workers)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get workers: %w", err)
-	}
-	return workers, nil
+	})
 }
 
 var rePlus = regexp.MustCompile(`-r(\d+)`)
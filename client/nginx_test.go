@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -161,6 +163,47 @@ func TestDetermineUpdates(t *testing.T) {
 			},
 			name: "update field and delete",
 		},
+		{
+			updated: []UpstreamServer{
+				{
+					Server: "[::1]:80",
+				},
+			},
+			nginx: []UpstreamServer{
+				{
+					ID:     1,
+					Server: "[0:0::1]:80",
+				},
+			},
+			name: "same ipv6 server with different spelling",
+		},
+		{
+			updated: []UpstreamServer{
+				{
+					Server:  "backend.example.com",
+					Service: "http",
+				},
+			},
+			nginx: []UpstreamServer{
+				{
+					ID:     1,
+					Server: "backend.example.com:80",
+				},
+			},
+			expectedToAdd: []UpstreamServer{
+				{
+					Server:  "backend.example.com",
+					Service: "http",
+				},
+			},
+			expectedToDelete: []UpstreamServer{
+				{
+					ID:     1,
+					Server: "backend.example.com:80",
+				},
+			},
+			name: "same host with a service",
+		},
 	}
 
 	for _, test := range tests {
@@ -339,62 +382,6 @@ func TestStreamDetermineUpdates(t *testing.T) {
 	}
 }
 
-func TestAddPortToServer(t *testing.T) {
-	t.Parallel()
-	// More info about addresses http://nginx.org/en/docs/http/ngx_http_upstream_module.html#server
-	tests := []struct {
-		address  string
-		expected string
-		msg      string
-	}{
-		{
-			address:  "example.com:8080",
-			expected: "example.com:8080",
-			msg:      "host and port",
-		},
-		{
-			address:  "127.0.0.1:8080",
-			expected: "127.0.0.1:8080",
-			msg:      "ipv4 and port",
-		},
-		{
-			address:  "[::]:8080",
-			expected: "[::]:8080",
-			msg:      "ipv6 and port",
-		},
-		{
-			address:  "unix:/path/to/socket",
-			expected: "unix:/path/to/socket",
-			msg:      "unix socket",
-		},
-		{
-			address:  "example.com",
-			expected: "example.com:80",
-			msg:      "host without port",
-		},
-		{
-			address:  "127.0.0.1",
-			expected: "127.0.0.1:80",
-			msg:      "ipv4 without port",
-		},
-		{
-			address:  "[::]",
-			expected: "[::]:80",
-			msg:      "ipv6 without port",
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.msg, func(t *testing.T) {
-			t.Parallel()
-			result := addPortToServer(test.address)
-			if result != test.expected {
-				t.Errorf("addPortToServer(%v) returned %v but expected %v for %v", test.address, result, test.expected, test.msg)
-			}
-		})
-	}
-}
-
 func TestHaveSameParameters(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -473,6 +460,30 @@ func TestHaveSameParameters(t *testing.T) {
 			expected:  false,
 			msg:       "different SlowStart 3",
 		},
+		{
+			server:    UpstreamServer{Route: "a"},
+			serverNGX: UpstreamServer{},
+			expected:  false,
+			msg:       "different Route",
+		},
+		{
+			server:    UpstreamServer{Drain: true},
+			serverNGX: UpstreamServer{},
+			expected:  false,
+			msg:       "different Drain",
+		},
+		{
+			server:    UpstreamServer{Server: "[::1]:80"},
+			serverNGX: UpstreamServer{Server: "[0::1]:80"},
+			expected:  true,
+			msg:       "same address with different spelling",
+		},
+		{
+			server:    UpstreamServer{Server: "127.0.0.1:80"},
+			serverNGX: UpstreamServer{Server: "127.0.0.2:80"},
+			expected:  false,
+			msg:       "different Server",
+		},
 	}
 
 	for _, test := range tests {
@@ -1712,3 +1723,71 @@ type fakeHandler struct {
 func (h *fakeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.handler(w, r)
 }
+
+func BenchmarkDetermineUpdates(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		updated := make([]UpstreamServer, n)
+		nginx := make([]UpstreamServer, n)
+		weight := 2
+		for i := range n {
+			updated[i] = UpstreamServer{Server: fmt.Sprintf("10.%d.%d.%d:80", i>>16, (i>>8)&0xff, i&0xff)}
+			// A tenth of the servers are updated, a tenth are replaced by other servers.
+			switch i % 10 {
+			case 0:
+				updated[i].Weight = &weight
+			case 1:
+				updated[i].Server = fmt.Sprintf("10.%d.%d.%d:8080", i>>16, (i>>8)&0xff, i&0xff)
+			}
+			nginx[i] = UpstreamServer{ID: i, Server: fmt.Sprintf("10.%d.%d.%d:80", i>>16, (i>>8)&0xff, i&0xff)}
+		}
+
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for b.Loop() {
+				toAdd, toRemove, toUpdate := determineUpdates(updated, nginx)
+				if len(toAdd) != n/10 || len(toRemove) != n/10 || len(toUpdate) != n/10 {
+					b.Fatalf("unexpected updates %v %v %v", len(toAdd), len(toRemove), len(toUpdate))
+				}
+			}
+			// The time per server stays the same as the number of servers grows.
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(n), "ns/server")
+		})
+	}
+}
+
+func BenchmarkDeduplicateServers(b *testing.B) {
+	logger := slog.New(slog.DiscardHandler)
+	for _, n := range []int{100, 1000, 10000} {
+		servers := make([]UpstreamServer, 0, 2*n)
+		for i := range n {
+			server := UpstreamServer{Server: fmt.Sprintf("10.%d.%d.%d:80", i>>16, (i>>8)&0xff, i&0xff)}
+			servers = append(servers, server, server)
+		}
+
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for b.Loop() {
+				deduplicated, err := deduplicateServers(context.Background(), logger, "foo", servers)
+				if err != nil || len(deduplicated) != n {
+					b.Fatalf("unexpected result %v %v", len(deduplicated), err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(n), "ns/server")
+		})
+	}
+}
+
+func TestDiffServers(t *testing.T) {
+	t.Parallel()
+	weight := 2
+	desired := []StreamUpstreamServer{{Server: "10.0.0.1:80", Weight: &weight}, {Server: "10.0.0.2"}}
+	actual := []StreamUpstreamServer{{ID: 1, Server: "10.0.0.1:80"}, {ID: 3, Server: "10.0.0.3:80"}}
+
+	diff := DiffServers(desired, actual)
+	expected := ServerUpdates[StreamUpstreamServer]{
+		Added:   []StreamUpstreamServer{{Server: "10.0.0.2"}},
+		Deleted: []StreamUpstreamServer{{ID: 3, Server: "10.0.0.3:80"}},
+		Updated: []StreamUpstreamServer{{ID: 1, Server: "10.0.0.1:80", Weight: &weight}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, diff)
+	}
+}
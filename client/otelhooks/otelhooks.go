@@ -0,0 +1,174 @@
+// Package otelhooks implements client.Hooks with OpenTelemetry.
+// It records a span for every operation of the NGINX Plus client, metrics for the duration of operations and HTTP requests,
+// errors by NGINX error code and retries, and propagates the trace context to the NGINX Plus API.
+package otelhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/nginx/nginx-plus-go-client/v3/client/otelhooks"
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option configures the Hooks.
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider. The global TracerProvider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the MeterProvider. The global MeterProvider is used by default.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagator sets the propagator used to send the trace context to the API.
+// The W3C Trace Context propagator, which sets the traceparent header, is used by default.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// Hooks implements client.Hooks with OpenTelemetry.
+type Hooks struct {
+	tracer            trace.Tracer
+	propagator        propagation.TextMapPropagator
+	operationDuration metric.Float64Histogram
+	operationErrors   metric.Int64Counter
+	requestDuration   metric.Float64Histogram
+	retries           metric.Int64Counter
+}
+
+var _ client.Hooks = (*Hooks)(nil)
+
+// New creates Hooks. Pass them to the client with client.WithHooks.
+func New(opts ...Option) (*Hooks, error) {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator:     propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+	h := &Hooks{
+		tracer:     c.tracerProvider.Tracer(instrumentationName),
+		propagator: c.propagator,
+	}
+
+	var err error
+	h.operationDuration, err = meter.Float64Histogram("nginx_plus.client.operation.duration",
+		metric.WithDescription("Duration of NGINX Plus client operations."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation duration histogram: %w", err)
+	}
+	h.operationErrors, err = meter.Int64Counter("nginx_plus.client.operation.errors",
+		metric.WithDescription("Number of failed NGINX Plus client operations by NGINX error code."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation errors counter: %w", err)
+	}
+	h.requestDuration, err = meter.Float64Histogram("nginx_plus.client.request.duration",
+		metric.WithDescription("Duration of HTTP requests sent to the NGINX Plus API."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request duration histogram: %w", err)
+	}
+	h.retries, err = meter.Int64Counter("nginx_plus.client.operation.retries",
+		metric.WithDescription("Number of retried NGINX Plus client operations."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retries counter: %w", err)
+	}
+
+	return h, nil
+}
+
+// OperationStart starts a span for the operation.
+func (h *Hooks) OperationStart(ctx context.Context, op *client.Operation) context.Context {
+	ctx, _ = h.tracer.Start(ctx, "nginx_plus."+op.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(operationAttributes(op)...))
+	return ctx
+}
+
+// OperationEnd ends the span of the operation and records its duration and error.
+func (h *Hooks) OperationEnd(ctx context.Context, op *client.Operation, duration time.Duration, err error) {
+	attrs := []attribute.KeyValue{attribute.String("nginx_plus.operation", op.Name)}
+	h.operationDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+
+	span := trace.SpanFromContext(ctx)
+	if err != nil {
+		code := errorCode(err)
+		h.operationErrors.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("nginx_plus.error.code", code))...))
+		span.SetAttributes(attribute.String("nginx_plus.error.code", code))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RequestStart injects the trace context into the headers of the request.
+func (h *Hooks) RequestStart(ctx context.Context, req *http.Request) {
+	h.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// RequestEnd records the duration of the request.
+func (h *Hooks) RequestEnd(ctx context.Context, info client.RequestInfo) {
+	h.requestDuration.Record(ctx, info.Duration.Seconds(), metric.WithAttributes(
+		attribute.String("http.request.method", info.Method),
+		attribute.Int("http.response.status_code", info.StatusCode),
+	))
+}
+
+// Retry records the retry of the operation.
+func (h *Hooks) Retry(ctx context.Context, op *client.Operation, attempt int, _ error) {
+	h.retries.Add(ctx, 1, metric.WithAttributes(attribute.String("nginx_plus.operation", op.Name)))
+	trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(attribute.Int("nginx_plus.attempt", attempt)))
+}
+
+func operationAttributes(op *client.Operation) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("nginx_plus.operation", op.Name)}
+	for _, a := range []struct{ key, value string }{
+		{"nginx_plus.upstream", op.Upstream},
+		{"nginx_plus.server", op.Server},
+		{"nginx_plus.zone", op.Zone},
+		{"nginx_plus.key", op.Key},
+	} {
+		if a.value != "" {
+			attrs = append(attrs, attribute.String(a.key, a.value))
+		}
+	}
+	return attrs
+}
+
+// errorCode returns the NGINX error code of the error, or "unknown" for errors that didn't come from the API.
+func errorCode(err error) string {
+	var statusErr client.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code() != "" {
+		return statusErr.Code()
+	}
+	return "unknown"
+}
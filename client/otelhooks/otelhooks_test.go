@@ -0,0 +1,92 @@
+package otelhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+	var traceparents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"status":400,"text":"invalid","code":"UpstreamConfFormatError"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	hooks, err := New(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := client.NewNginxClient(ts.URL, client.WithHooks(hooks))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.AddHTTPServer(context.Background(), "foo", client.UpstreamServer{Server: "10.0.0.1:80"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("expected 2 spans, got %v", len(ended))
+	}
+	child, parent := ended[0], ended[1]
+	if child.Name() != "nginx_plus.GetHTTPServers" || parent.Name() != "nginx_plus.AddHTTPServer" {
+		t.Fatalf("unexpected spans %v and %v", child.Name(), parent.Name())
+	}
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("expected GetHTTPServers span to be a child of AddHTTPServer span")
+	}
+
+	if len(traceparents) != 2 || traceparents[0] == "" || traceparents[1] == "" {
+		t.Fatalf("expected the traceparent header on every request, got %v", traceparents)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	if code := errorCodeMetric(rm); code != "UpstreamConfFormatError" {
+		t.Fatalf("expected an error recorded with code UpstreamConfFormatError, got %q", code)
+	}
+}
+
+func errorCodeMetric(rm metricdata.ResourceMetrics) string {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "nginx_plus.client.operation.errors" {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || len(sum.DataPoints) == 0 {
+				return ""
+			}
+			v, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("nginx_plus.error.code"))
+			return v.AsString()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOperationNotPermitted is returned for changes the client isn't allowed to make.
+var ErrOperationNotPermitted = errors.New("operation not permitted")
+
+// PermissionError is returned, before any request is sent, for a change that the client is not allowed to make
+// because it is read-only or because the upstream or key-value zone isn't in its allowlist.
+type PermissionError struct {
+	Operation string
+	Reason    string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("%v: %v: %v", e.Operation, ErrOperationNotPermitted, e.Reason)
+}
+
+func (e *PermissionError) Unwrap() error {
+	return ErrOperationNotPermitted
+}
+
+// WithReadOnly makes the client read-only: methods that change the state of NGINX fail with a PermissionError.
+func WithReadOnly() Option {
+	return func(o *NginxClient) {
+		o.readOnly = true
+	}
+}
+
+// WithUpstreamAllowlist restricts the changes of upstream servers to the given upstreams.
+// It can be passed several times to allow more upstreams. Reading the servers and stats of other upstreams is still allowed.
+func WithUpstreamAllowlist(upstreams ...string) Option {
+	return func(o *NginxClient) {
+		o.upstreamAllowlist = addToAllowlist(o.upstreamAllowlist, upstreams)
+	}
+}
+
+// WithKeyValZoneAllowlist restricts the changes of key-value pairs to the given zones, in both the http and stream contexts.
+// It can be passed several times to allow more zones. Reading the key-value pairs of other zones is still allowed.
+func WithKeyValZoneAllowlist(zones ...string) Option {
+	return func(o *NginxClient) {
+		o.zoneAllowlist = addToAllowlist(o.zoneAllowlist, zones)
+	}
+}
+
+func addToAllowlist(allowlist map[string]struct{}, names []string) map[string]struct{} {
+	if allowlist == nil {
+		allowlist = make(map[string]struct{}, len(names))
+	}
+	for _, name := range names {
+		allowlist[name] = struct{}{}
+	}
+	return allowlist
+}
+
+// checkPermission returns a PermissionError if the client isn't allowed to run the operation.
+func (client *NginxClient) checkPermission(op *Operation) error {
+	if !op.Mutating {
+		return nil
+	}
+	if client.readOnly {
+		return &PermissionError{Operation: op.Name, Reason: "client is read-only"}
+	}
+	if op.Upstream != "" && client.upstreamAllowlist != nil {
+		if _, ok := client.upstreamAllowlist[op.Upstream]; !ok {
+			return &PermissionError{Operation: op.Name, Reason: fmt.Sprintf("upstream %q is not allowed", op.Upstream)}
+		}
+	}
+	if op.Zone != "" && client.zoneAllowlist != nil {
+		if _, ok := client.zoneAllowlist[op.Zone]; !ok {
+			return &PermissionError{Operation: op.Name, Reason: fmt.Sprintf("key-value zone %q is not allowed", op.Zone)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		call    func(context.Context, *NginxClient) error
+		msg     string
+		options []Option
+		allowed bool
+	}{
+		{
+			msg:     "read-only client reads",
+			options: []Option{WithReadOnly()},
+			call: func(ctx context.Context, c *NginxClient) error {
+				_, err := c.GetHTTPServers(ctx, "foo")
+				return err
+			},
+			allowed: true,
+		},
+		{
+			msg:     "read-only client adds server",
+			options: []Option{WithReadOnly()},
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.AddHTTPServer(ctx, "foo", UpstreamServer{Server: "10.0.0.1:80"})
+			},
+		},
+		{
+			msg:     "read-only client deletes key-value pairs",
+			options: []Option{WithReadOnly()},
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.DeleteStreamKeyValPairs(ctx, "zone")
+			},
+		},
+		{
+			msg:     "allowed upstream",
+			options: []Option{WithUpstreamAllowlist("foo"), WithUpstreamAllowlist("bar")},
+			call: func(ctx context.Context, c *NginxClient) error {
+				_, _, _, err := c.UpdateStreamServers(ctx, "bar", nil)
+				return err
+			},
+			allowed: true,
+		},
+		{
+			msg:     "upstream not in allowlist",
+			options: []Option{WithUpstreamAllowlist("foo")},
+			call: func(ctx context.Context, c *NginxClient) error {
+				_, _, _, err := c.UpdateHTTPServers(ctx, "baz", nil)
+				return err
+			},
+		},
+		{
+			msg:     "reading upstream not in allowlist",
+			options: []Option{WithUpstreamAllowlist("foo")},
+			call: func(ctx context.Context, c *NginxClient) error {
+				_, err := c.GetHTTPServers(ctx, "baz")
+				return err
+			},
+			allowed: true,
+		},
+		{
+			msg:     "allowed zone",
+			options: []Option{WithKeyValZoneAllowlist("zone")},
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.DeleteKeyValuePair(ctx, "zone", "key")
+			},
+			allowed: true,
+		},
+		{
+			msg:     "zone not in allowlist",
+			options: []Option{WithKeyValZoneAllowlist("zone")},
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.ModifyKeyValPair(ctx, "other", "key", "val")
+			},
+		},
+		{
+			msg:     "upstream allowlist doesn't restrict zones",
+			options: []Option{WithUpstreamAllowlist("foo")},
+			call: func(ctx context.Context, c *NginxClient) error {
+				return c.AddKeyValPair(ctx, "zone", "key", "val")
+			},
+			allowed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			var requests atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				switch r.Method {
+				case http.MethodGet:
+					_, _ = w.Write([]byte(`[]`))
+				case http.MethodPost:
+					w.WriteHeader(http.StatusCreated)
+				default:
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			defer ts.Close()
+
+			c, err := NewNginxClient(ts.URL, test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = test.call(context.Background(), c)
+			if test.allowed {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var permErr *PermissionError
+			if !errors.As(err, &permErr) || !errors.Is(err, ErrOperationNotPermitted) {
+				t.Fatalf("expected a PermissionError, got %v", err)
+			}
+			if n := requests.Load(); n != 0 {
+				t.Fatalf("expected no requests, got %v", n)
+			}
+		})
+	}
+}
+
+func TestPermissionsIgnoreChangedTargets(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected %v request to %v", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL,
+		WithUpstreamAllowlist("foo"),
+		WithKeyValZoneAllowlist("zone"),
+		WithInterceptors(func(ctx context.Context, op *Operation, next Invoker) (any, error) {
+			op.Upstream, op.Zone, op.Mutating = "foo", "zone", false
+			return next(ctx, op)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := c.DeleteHTTPServer(ctx, "baz", "10.0.0.1:80"); !errors.Is(err, ErrOperationNotPermitted) {
+		t.Fatalf("expected %v for the upstream of the call, got %v", ErrOperationNotPermitted, err)
+	}
+	if err := c.DeleteKeyValuePair(ctx, "other", "key"); !errors.Is(err, ErrOperationNotPermitted) {
+		t.Fatalf("expected %v for the zone of the call, got %v", ErrOperationNotPermitted, err)
+	}
+}
@@ -0,0 +1,458 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultHealthyWaitTimeout  = 30 * time.Second
+	defaultHealthyWaitInterval = time.Second
+)
+
+// ErrServersNotHealthy is returned for the servers that weren't removed because the added servers didn't become healthy in time.
+var ErrServersNotHealthy = errors.New("added servers did not become healthy")
+
+// ServerChange is the kind of change made to a server by ReconcileHTTPServers or ReconcileStreamServers.
+type ServerChange string
+
+const (
+	// ServerAdded means the server was added to the upstream.
+	ServerAdded ServerChange = "add"
+	// ServerDeleted means the server was removed from the upstream.
+	ServerDeleted ServerChange = "delete"
+	// ServerUpdated means the parameters of the server were updated.
+	ServerUpdated ServerChange = "update"
+)
+
+// UpdateOrder is the order in which ReconcileHTTPServers and ReconcileStreamServers apply the changes.
+type UpdateOrder int
+
+const (
+	// OrderAddDeleteUpdate adds servers, then removes servers, then updates servers, like UpdateHTTPServers.
+	OrderAddDeleteUpdate UpdateOrder = iota
+	// OrderUpdateAddDelete updates servers, for example their weights, before adding and removing servers.
+	OrderUpdateAddDelete
+	// OrderAddWaitHealthyDelete adds and updates servers, waits until the added servers are healthy and only then removes servers.
+	// If the added servers don't become healthy in time, no servers are removed.
+	OrderAddWaitHealthyDelete
+)
+
+// ServerOutcome is the outcome of the change of a server.
+type ServerOutcome[S Server] struct {
+	// Server is the server as sent to NGINX, or, for removed servers, as returned by NGINX.
+	Server S
+	// Err is the error of the change, or nil if the change succeeded.
+	Err    error
+	Change ServerChange
+}
+
+// ReconcileResult holds the outcomes of the changes made by ReconcileHTTPServers or ReconcileStreamServers, in the order they were made.
+type ReconcileResult[S Server] struct {
+	Outcomes []ServerOutcome[S]
+}
+
+// Added returns the servers that were added.
+func (r ReconcileResult[S]) Added() []S {
+	return r.succeeded(ServerAdded)
+}
+
+// Deleted returns the servers that were removed.
+func (r ReconcileResult[S]) Deleted() []S {
+	return r.succeeded(ServerDeleted)
+}
+
+// Updated returns the servers that were updated.
+func (r ReconcileResult[S]) Updated() []S {
+	return r.succeeded(ServerUpdated)
+}
+
+// Failed returns the outcomes of the changes that failed.
+func (r ReconcileResult[S]) Failed() []ServerOutcome[S] {
+	var failed []ServerOutcome[S]
+	for _, o := range r.Outcomes {
+		if o.Err != nil {
+			failed = append(failed, o)
+		}
+	}
+	return failed
+}
+
+func (r ReconcileResult[S]) succeeded(change ServerChange) []S {
+	var servers []S
+	for _, o := range r.Outcomes {
+		if o.Change == change && o.Err == nil {
+			servers = append(servers, o.Server)
+		}
+	}
+	return servers
+}
+
+type reconcileConfig struct {
+	healthyWaitTimeout  time.Duration
+	healthyWaitInterval time.Duration
+	parallelism         int
+	order               UpdateOrder
+	conflictRetries     int
+	checkConflicts      bool
+}
+
+// ReconcileOption configures ReconcileHTTPServers and ReconcileStreamServers.
+type ReconcileOption func(*reconcileConfig)
+
+// WithParallelism sets the maximum number of changes sent to NGINX at the same time. The default is 1.
+// With more than one, the AuditSink of the client may be called concurrently.
+func WithParallelism(n int) ReconcileOption {
+	return func(c *reconcileConfig) {
+		c.parallelism = max(n, 1)
+	}
+}
+
+// WithUpdateOrder sets the order in which the changes are applied. The default is OrderAddDeleteUpdate.
+func WithUpdateOrder(order UpdateOrder) ReconcileOption {
+	return func(c *reconcileConfig) {
+		c.order = order
+	}
+}
+
+// WithHealthyWait sets how long OrderAddWaitHealthyDelete waits for the added servers to become healthy,
+// and how often it checks their state. The defaults are 30 seconds and 1 second.
+func WithHealthyWait(timeout, interval time.Duration) ReconcileOption {
+	return func(c *reconcileConfig) {
+		c.healthyWaitTimeout = timeout
+		c.healthyWaitInterval = interval
+	}
+}
+
+// WithConflictCheck makes every server change check first that the servers of the upstream haven't been changed
+// by another client since they were read, at the cost of reading them again before each change.
+// Changes based on a stale view of the servers are not made and fail with a ConflictError.
+func WithConflictCheck() ReconcileOption {
+	return func(c *reconcileConfig) {
+		c.checkConflicts = true
+	}
+}
+
+// WithConflictRetry checks for conflicts like WithConflictCheck and, when there are conflicts, reads the servers again,
+// determines the changes still needed and applies them, up to retries times.
+func WithConflictRetry(retries int) ReconcileOption {
+	return func(c *reconcileConfig) {
+		c.checkConflicts = true
+		c.conflictRetries = max(retries, 0)
+	}
+}
+
+// WithReconcileDefaults sets the options used by UpdateHTTPServers, UpdateStreamServers,
+// ReconcileHTTPServers and ReconcileStreamServers. The options passed to the Reconcile methods are applied after them.
+func WithReconcileDefaults(opts ...ReconcileOption) Option {
+	return func(o *NginxClient) {
+		o.reconcileDefaults = append(o.reconcileDefaults, opts...)
+	}
+}
+
+func (client *NginxClient) newReconcileConfig(opts []ReconcileOption) reconcileConfig {
+	cfg := reconcileConfig{
+		parallelism:         1,
+		order:               OrderAddDeleteUpdate,
+		healthyWaitTimeout:  defaultHealthyWaitTimeout,
+		healthyWaitInterval: defaultHealthyWaitInterval,
+	}
+	for _, opt := range client.reconcileDefaults {
+		opt(&cfg)
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// ReconcileHTTPServers updates the servers of the upstream like UpdateHTTPServers,
+// with the parallelism and order set by the options, and returns the outcome of the change of every server.
+func (client *NginxClient) ReconcileHTTPServers(ctx context.Context, upstream string, servers []UpstreamServer, opts ...ReconcileOption) (ReconcileResult[UpstreamServer], error) {
+	op := &Operation{Name: "ReconcileHTTPServers", Upstream: upstream, Request: servers, Mutating: true}
+	return invoke(ctx, client, op, func(ctx context.Context, op *Operation) (ReconcileResult[UpstreamServer], error) {
+		servers, err := requestAs[[]UpstreamServer](op)
+		if err != nil {
+			return ReconcileResult[UpstreamServer]{}, err
+		}
+		return reconcile(ctx, client, client.httpServerOps(), upstream, servers, client.newReconcileConfig(opts))
+	})
+}
+
+// ReconcileStreamServers updates the servers of the stream upstream like UpdateStreamServers,
+// with the parallelism and order set by the options, and returns the outcome of the change of every server.
+func (client *NginxClient) ReconcileStreamServers(ctx context.Context, upstream string, servers []StreamUpstreamServer, opts ...ReconcileOption) (ReconcileResult[StreamUpstreamServer], error) {
+	op := &Operation{Name: "ReconcileStreamServers", Upstream: upstream, Request: servers, Mutating: true}
+	return invoke(ctx, client, op, func(ctx context.Context, op *Operation) (ReconcileResult[StreamUpstreamServer], error) {
+		servers, err := requestAs[[]StreamUpstreamServer](op)
+		if err != nil {
+			return ReconcileResult[StreamUpstreamServer]{}, err
+		}
+		return reconcile(ctx, client, client.streamServerOps(), upstream, servers, client.newReconcileConfig(opts))
+	})
+}
+
+// serverOps are the operations on the servers of an HTTP or stream upstream used by reconcile.
+type serverOps[S Server] struct {
+	get         func(ctx context.Context, upstream string) ([]S, error)
+	normalize   func(upstream string, server S) (S, error)
+	deduplicate func(ctx context.Context, logger *slog.Logger, upstream string, servers []S) ([]S, error)
+	determine   func(updatedServers []S, nginxServers []S) (toAdd []S, toRemove []S, toUpdate []S)
+	add         func(ctx context.Context, upstream string, server S) error
+	delete      func(ctx context.Context, upstream string, server S) error
+	update      func(ctx context.Context, upstream string, server S, before any) error
+	// noun is how the servers are called in errors, either "server" or "stream server".
+	noun   string
+	stream bool
+}
+
+func (client *NginxClient) httpServerOps() serverOps[UpstreamServer] {
+	return serverOps[UpstreamServer]{
+		get: client.GetHTTPServers,
+		normalize: func(upstream string, server UpstreamServer) (UpstreamServer, error) {
+			// We assume port 80 if no port is set for servers.
+			address, err := normalizeServerAddress(server.Server, server.Service)
+			if err != nil {
+				return server, fmt.Errorf("failed to update %s server to %s upstream: %w", server.Server, upstream, err)
+			}
+			server.Server = address
+			return server, nil
+		},
+		deduplicate: deduplicateServers,
+		determine:   determineUpdates,
+		add:         client.addHTTPServer,
+		delete:      client.deleteHTTPServer,
+		update:      client.updateHTTPServer,
+		noun:        "server",
+		stream:      httpContext,
+	}
+}
+
+func (client *NginxClient) streamServerOps() serverOps[StreamUpstreamServer] {
+	return serverOps[StreamUpstreamServer]{
+		get: client.GetStreamServers,
+		normalize: func(upstream string, server StreamUpstreamServer) (StreamUpstreamServer, error) {
+			address, err := normalizeServerAddress(server.Server, server.Service)
+			if err != nil {
+				return server, fmt.Errorf("failed to update stream %s server to %s upstream: %w", server.Server, upstream, err)
+			}
+			server.Server = address
+			return server, nil
+		},
+		deduplicate: deduplicateStreamServers,
+		determine:   determineStreamUpdates,
+		add:         client.addStreamServer,
+		delete:      client.deleteStreamServer,
+		update:      client.updateStreamServer,
+		noun:        "stream server",
+		stream:      streamContext,
+	}
+}
+
+// reconcile makes the changes needed for the upstream to have the servers.
+// The errors of all servers that couldn't be changed are returned along with the outcomes.
+// On conflicts, the changes still needed are determined and applied again, up to the configured number of retries.
+func reconcile[S Server](ctx context.Context, client *NginxClient, ops serverOps[S], upstream string, servers []S, cfg reconcileConfig) (ReconcileResult[S], error) {
+	var succeeded []ServerOutcome[S]
+	for attempt := 1; ; attempt++ {
+		result, err := reconcileOnce(ctx, client, ops, upstream, servers, cfg)
+		var conflict *ConflictError
+		if err == nil || attempt > cfg.conflictRetries || !errors.As(err, &conflict) {
+			result.Outcomes = append(succeeded, result.Outcomes...)
+			return result, err
+		}
+
+		for _, o := range result.Outcomes {
+			if o.Err == nil {
+				succeeded = append(succeeded, o)
+			}
+		}
+		if client.idCache != nil {
+			client.idCache.forget(upstreamKey{name: upstream, stream: ops.stream})
+		}
+		if op, ok := OperationFromContext(ctx); ok && client.hooks != nil {
+			client.hooks.Retry(ctx, op, attempt, err)
+		}
+		client.logger.InfoContext(ctx, "retrying update of servers after conflict", "upstream", upstream, "attempt", attempt, "error", err)
+	}
+}
+
+func reconcileOnce[S Server](ctx context.Context, client *NginxClient, ops serverOps[S], upstream string, servers []S, cfg reconcileConfig) (ReconcileResult[S], error) {
+	var result ReconcileResult[S]
+	// A change rejected before any server is changed is recorded as a whole, since no server change is recorded for it.
+	reject := func(before any, err error) error {
+		client.audit(ctx, AuditRecord{Action: AuditUpdateServers, Context: contextName(ops.stream), Upstream: upstream, Before: before, After: servers}, err)
+		return err
+	}
+	var err error
+	for _, server := range servers {
+		if validationErr := server.Validate(); validationErr != nil {
+			err = errors.Join(err, fmt.Errorf("%v %v: %w", ops.noun, server.Address(), validationErr))
+		}
+	}
+	if err != nil {
+		return result, reject(nil, fmt.Errorf("failed to update %vs of %v upstream: %w", ops.noun, upstream, err))
+	}
+
+	// A server whose address can't be normalized would be missing from the desired servers,
+	// and the server with that address in NGINX would be deleted, so nothing is changed.
+	formattedServers := make([]S, 0, len(servers))
+	for _, server := range servers {
+		formatted, addrErr := ops.normalize(upstream, server)
+		if addrErr != nil {
+			err = errors.Join(err, addrErr)
+			continue
+		}
+		formattedServers = append(formattedServers, formatted)
+	}
+	if err != nil {
+		return result, reject(nil, err)
+	}
+
+	serversInNginx, err := ops.get(ctx, upstream)
+	if err != nil {
+		return result, fmt.Errorf("failed to update %vs of %v upstream: %w", ops.noun, upstream, err)
+	}
+
+	formattedServers, dedupErr := ops.deduplicate(ctx, client.logger, upstream, formattedServers)
+	err = errors.Join(err, dedupErr)
+
+	toAdd, toDelete, toUpdate := ops.determine(formattedServers, serversInNginx)
+	if guardErr := checkUpdateGuards(ctx, client, upstream, ops.stream, serversInNginx, len(formattedServers), toDelete, toUpdate); guardErr != nil {
+		return result, reject(serversInNginx, fmt.Errorf("failed to update %vs of %v upstream: %w", ops.noun, upstream, errors.Join(err, guardErr)))
+	}
+	logServerUpdates(ctx, client.logger, upstream, toAdd, toDelete, toUpdate)
+
+	before := make(map[int]S, len(serversInNginx))
+	for _, server := range serversInNginx {
+		before[server.ServerID()] = server
+	}
+
+	check := func(ctx context.Context, change ServerChange, server S) error {
+		if !cfg.checkConflicts {
+			return nil
+		}
+		return checkConflict(ctx, ops, upstream, change, server, before)
+	}
+	add := func() {
+		result.Outcomes = append(result.Outcomes, applyChanges(ctx, cfg.parallelism, ServerAdded, toAdd, func(ctx context.Context, server S) error {
+			if err := check(ctx, ServerAdded, server); err != nil {
+				return err
+			}
+			return ops.add(ctx, upstream, server)
+		})...)
+	}
+	remove := func() {
+		result.Outcomes = append(result.Outcomes, applyChanges(ctx, cfg.parallelism, ServerDeleted, toDelete, func(ctx context.Context, server S) error {
+			if err := check(ctx, ServerDeleted, server); err != nil {
+				return err
+			}
+			return ops.delete(ctx, upstream, server)
+		})...)
+	}
+	update := func() {
+		result.Outcomes = append(result.Outcomes, applyChanges(ctx, cfg.parallelism, ServerUpdated, toUpdate, func(ctx context.Context, server S) error {
+			if err := check(ctx, ServerUpdated, server); err != nil {
+				return err
+			}
+			return ops.update(ctx, upstream, server, before[server.ServerID()])
+		})...)
+	}
+
+	switch cfg.order {
+	case OrderUpdateAddDelete:
+		update()
+		add()
+		remove()
+	case OrderAddWaitHealthyDelete:
+		add()
+		update()
+		if len(toDelete) == 0 {
+			break
+		}
+		if waitErr := waitHealthy(ctx, client, upstream, ops.stream, result.Added(), cfg); waitErr != nil {
+			for _, server := range toDelete {
+				result.Outcomes = append(result.Outcomes, ServerOutcome[S]{Server: server, Change: ServerDeleted, Err: fmt.Errorf(
+					"failed to remove %v %v from %v upstream: %w", server.Address(), ops.noun, upstream, waitErr)})
+			}
+			break
+		}
+		remove()
+	default:
+		add()
+		remove()
+		update()
+	}
+
+	for _, o := range result.Outcomes {
+		err = errors.Join(err, o.Err)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to update %vs of %s upstream: %w", ops.noun, upstream, err)
+	}
+
+	return result, err
+}
+
+// applyChanges applies the change to the servers, at most parallelism at a time.
+func applyChanges[S Server](ctx context.Context, parallelism int, change ServerChange, servers []S, apply func(context.Context, S) error) []ServerOutcome[S] {
+	outcomes := make([]ServerOutcome[S], len(servers))
+	var g errgroup.Group
+	g.SetLimit(parallelism)
+	for i, server := range servers {
+		g.Go(func() error {
+			outcomes[i] = ServerOutcome[S]{Server: server, Change: change, Err: apply(ctx, server)}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return outcomes
+}
+
+// waitHealthy waits until every server has a peer in the "up" state.
+func waitHealthy[S Server](ctx context.Context, client *NginxClient, upstream string, stream bool, servers []S, cfg reconcileConfig) error {
+	if len(servers) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.healthyWaitTimeout)
+	defer cancel()
+	ticker := time.NewTicker(cfg.healthyWaitInterval)
+	defer ticker.Stop()
+
+	for {
+		peers, err := client.peerStates(ctx, upstream, stream)
+		if err == nil && allHealthy(servers, peers) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return errors.Join(ErrServersNotHealthy, err)
+			}
+			return ErrServersNotHealthy
+		case <-ticker.C:
+		}
+	}
+}
+
+func allHealthy[S Server](servers []S, peers []peerState) bool {
+	up := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		if peer.state == peerStateUp {
+			up[serverKey(peer.name)] = true
+		}
+	}
+	for _, server := range servers {
+		if !up[serverKey(server.Address())] {
+			return false
+		}
+	}
+	return true
+}
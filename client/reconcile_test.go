@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeUpstream struct {
+	peers    string
+	requests []string
+	mu       sync.Mutex
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+}
+
+func (f *fakeUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/servers"):
+		_, _ = w.Write([]byte(`[{"id":1,"server":"10.0.0.1:80"},{"id":2,"server":"10.0.0.2:80"}]`))
+		return
+	case r.Method == http.MethodGet:
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_, _ = w.Write([]byte(f.peers))
+		return
+	}
+
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		seen := f.maxSeen.Load()
+		if n <= seen || f.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method)
+	f.mu.Unlock()
+	if r.Method == http.MethodPost {
+		if strings.Contains(r.URL.Path, "bad") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	_, _ = w.Write([]byte(`{}`))
+}
+
+func (f *fakeUpstream) methods() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return strings.Join(f.requests, ",")
+}
+
+func TestReconcileHTTPServers(t *testing.T) {
+	t.Parallel()
+	weight := 2
+	servers := []UpstreamServer{
+		{Server: "10.0.0.2", Weight: &weight},
+		{Server: "10.0.0.3"},
+		{Server: "10.0.0.4"},
+	}
+	tests := []struct {
+		msg         string
+		peers       string
+		methods     string
+		options     []ReconcileOption
+		maxInFlight int32
+		wantErr     error
+	}{
+		{
+			msg:         "default order",
+			methods:     "POST,POST,DELETE,PATCH",
+			maxInFlight: 1,
+		},
+		{
+			msg:         "update first",
+			options:     []ReconcileOption{WithUpdateOrder(OrderUpdateAddDelete)},
+			methods:     "PATCH,POST,POST,DELETE",
+			maxInFlight: 1,
+		},
+		{
+			msg:         "parallel",
+			options:     []ReconcileOption{WithParallelism(4)},
+			methods:     "POST,POST,DELETE,PATCH",
+			maxInFlight: 2,
+		},
+		{
+			msg:         "wait healthy",
+			options:     []ReconcileOption{WithUpdateOrder(OrderAddWaitHealthyDelete), WithHealthyWait(time.Second, time.Millisecond)},
+			peers:       `{"foo":{"peers":[{"id":3,"name":"10.0.0.3:80","state":"up"},{"id":4,"name":"10.0.0.4:80","state":"up"}]}}`,
+			methods:     "POST,POST,PATCH,DELETE",
+			maxInFlight: 1,
+		},
+		{
+			msg:         "added servers not healthy",
+			options:     []ReconcileOption{WithUpdateOrder(OrderAddWaitHealthyDelete), WithHealthyWait(50*time.Millisecond, time.Millisecond)},
+			peers:       `{"foo":{"peers":[{"id":3,"name":"10.0.0.3:80","state":"up"},{"id":4,"name":"10.0.0.4:80","state":"unhealthy"}]}}`,
+			methods:     "POST,POST,PATCH",
+			maxInFlight: 1,
+			wantErr:     ErrServersNotHealthy,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			fake := &fakeUpstream{peers: test.peers}
+			ts := httptest.NewServer(fake)
+			defer ts.Close()
+
+			c, err := NewNginxClient(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := c.ReconcileHTTPServers(context.Background(), "foo", servers, test.options...)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+
+			methods := fake.methods()
+			if test.maxInFlight == 1 && methods != test.methods {
+				t.Errorf("expected requests %v, got %v", test.methods, methods)
+			}
+			if n := fake.maxSeen.Load(); n != test.maxInFlight {
+				t.Errorf("expected at most %v requests at a time, got %v", test.maxInFlight, n)
+			}
+
+			if len(result.Outcomes) != 4 {
+				t.Fatalf("expected 4 outcomes, got %+v", result.Outcomes)
+			}
+			if len(result.Added()) != 2 || len(result.Updated()) != 1 {
+				t.Errorf("unexpected outcomes %+v", result.Outcomes)
+			}
+			if test.wantErr == nil && (len(result.Deleted()) != 1 || result.Deleted()[0].ID != 1) {
+				t.Errorf("expected server 1 to be deleted, got %+v", result.Outcomes)
+			}
+			if test.wantErr != nil && (len(result.Failed()) != 1 || result.Failed()[0].Change != ServerDeleted) {
+				t.Errorf("expected the deletion to fail, got %+v", result.Outcomes)
+			}
+		})
+	}
+}
+
+func TestReconcileHTTPServersOutcomes(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(&fakeUpstream{})
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	servers := []UpstreamServer{{Server: "10.0.0.1"}, {Server: "10.0.0.2"}, {Server: "10.0.0.3"}}
+	ctx := context.Background()
+	result, err := c.ReconcileHTTPServers(ctx, "bad", servers)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	failed := result.Failed()
+	if len(result.Outcomes) != 1 || len(failed) != 1 {
+		t.Fatalf("expected one failed outcome, got %+v", result.Outcomes)
+	}
+	if failed[0].Server.Server != "10.0.0.3:80" || failed[0].Change != ServerAdded {
+		t.Fatalf("unexpected outcome %+v", failed[0])
+	}
+}
+
+func TestReconcileNormalizeErrorChangesNothing(t *testing.T) {
+	t.Parallel()
+	fake := &fakeUpstream{}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errNormalize := errors.New("normalize failed")
+	ops := c.httpServerOps()
+	ops.normalize = func(_ string, server UpstreamServer) (UpstreamServer, error) {
+		if server.Server == "10.0.0.1:80" {
+			return server, errNormalize
+		}
+		return server, nil
+	}
+	servers := []UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.3:80"}}
+	result, err := reconcileOnce(context.Background(), c, ops, "foo", servers, c.newReconcileConfig(nil))
+	if !errors.Is(err, errNormalize) {
+		t.Fatalf("expected %v, got %v", errNormalize, err)
+	}
+	if len(result.Outcomes) != 0 || fake.methods() != "" {
+		t.Fatalf("expected no changes, got outcomes %+v and requests %v", result.Outcomes, fake.methods())
+	}
+}
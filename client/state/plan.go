@@ -0,0 +1,298 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// Client is the part of the NGINX Plus client that Reconcile needs. *client.NginxClient implements it.
+type Client interface {
+	client.UpstreamManager
+	client.StreamUpstreamManager
+	client.KeyValStore
+}
+
+// Kind is the kind of a resource of the state.
+type Kind string
+
+const (
+	KindHTTPUpstream     Kind = "http_upstream"
+	KindStreamUpstream   Kind = "stream_upstream"
+	KindKeyValZone       Kind = "keyval_zone"
+	KindStreamKeyValZone Kind = "stream_keyval_zone"
+)
+
+// ResourcePlan is the plan for an upstream or a keyval zone.
+type ResourcePlan struct {
+	apply func(ctx context.Context, c Client) error
+	err   error
+	Kind  Kind   `json:"kind"`
+	Name  string `json:"name"`
+	// Add, Update and Delete are the addresses of the servers or the keys to add, update and delete.
+	Add    []string `json:"add,omitempty"`
+	Update []string `json:"update,omitempty"`
+	Delete []string `json:"delete,omitempty"`
+	// Keep are the servers or keys that are not in the state but are kept, because Prune is false.
+	Keep []string `json:"keep,omitempty"`
+	// Error is the error reading the resource or, after Apply, applying the plan.
+	Error string `json:"error,omitempty"`
+	// Applied is true if the plan was applied successfully.
+	Applied bool `json:"applied,omitempty"`
+}
+
+// HasChanges reports whether the plan changes the resource.
+func (r *ResourcePlan) HasChanges() bool {
+	return len(r.Add)+len(r.Update)+len(r.Delete) > 0
+}
+
+// Plan is the plan to reconcile NGINX Plus with a state. It can be printed or encoded in JSON before it is applied.
+type Plan struct {
+	Resources []*ResourcePlan `json:"resources"`
+}
+
+// HasChanges reports whether the plan changes any resource.
+func (p *Plan) HasChanges() bool {
+	for _, r := range p.Resources {
+		if r.HasChanges() {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the changes of the plan, one per line, prefixed with + for additions, ~ for updates and - for deletions.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, r := range p.Resources {
+		if !r.HasChanges() && r.Error == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%v %v:\n", r.Kind, r.Name)
+		if r.Error != "" {
+			fmt.Fprintf(&b, "  error: %v\n", r.Error)
+		}
+		for _, change := range []struct {
+			prefix string
+			items  []string
+		}{{"+", r.Add}, {"~", r.Update}, {"-", r.Delete}} {
+			for _, item := range change.items {
+				fmt.Fprintf(&b, "  %v %v\n", change.prefix, item)
+			}
+		}
+	}
+	return b.String()
+}
+
+type config struct {
+	owner    string
+	hasOwner bool
+}
+
+// Option configures NewPlan and Reconcile.
+type Option func(*config)
+
+// WithOwner only plans the upstreams and keyval zones of the state whose owner, or the owner of the state if they have none, is the owner.
+// It lets several tools, or several state files, manage different upstreams of the same NGINX Plus.
+// By default, all the upstreams and keyval zones of the state are planned.
+func WithOwner(owner string) Option {
+	return func(c *config) {
+		c.owner = owner
+		c.hasOwner = true
+	}
+}
+
+// NewPlan reads the servers of the upstreams and the pairs of the keyval zones of the state from NGINX Plus
+// and returns the plan to reconcile them with the state. Resources that can't be read have an Error,
+// and the errors are returned too.
+func NewPlan(ctx context.Context, c Client, s *State, opts ...Option) (*Plan, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	owned := func(owner string) bool {
+		return !cfg.hasOwner || s.owner(owner) == cfg.owner
+	}
+
+	plan := &Plan{Resources: []*ResourcePlan{}}
+	for _, name := range slices.Sorted(maps.Keys(s.HTTPUpstreams)) {
+		if u := s.HTTPUpstreams[name]; owned(u.Owner) {
+			plan.Resources = append(plan.Resources, planUpstream(ctx, c, KindHTTPUpstream, name, u.Servers, s.prune(u.Prune), httpServers))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.StreamUpstreams)) {
+		if u := s.StreamUpstreams[name]; owned(u.Owner) {
+			plan.Resources = append(plan.Resources, planUpstream(ctx, c, KindStreamUpstream, name, u.Servers, s.prune(u.Prune), streamServers))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.KeyValZones)) {
+		if z := s.KeyValZones[name]; owned(z.Owner) {
+			plan.Resources = append(plan.Resources, planKeyVals(ctx, c, KindKeyValZone, name, z.Pairs, s.prune(z.Prune), httpKeyVals))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.StreamKeyValZones)) {
+		if z := s.StreamKeyValZones[name]; owned(z.Owner) {
+			plan.Resources = append(plan.Resources, planKeyVals(ctx, c, KindStreamKeyValZone, name, z.Pairs, s.prune(z.Prune), streamKeyVals))
+		}
+	}
+
+	return plan, plan.err()
+}
+
+// Apply applies the changes of the plan. Upstreams are updated with UpdateHTTPServers or UpdateStreamServers.
+// If Prune is false, the servers of the upstream are read again and those that are not in the state are kept.
+// Keyval zones are changed one key at a time. Resources with an Error or without changes are skipped.
+// The errors of the resources are set in the plan and returned.
+func (p *Plan) Apply(ctx context.Context, c Client) error {
+	for _, r := range p.Resources {
+		if r.Error != "" || !r.HasChanges() {
+			continue
+		}
+		if err := r.apply(ctx, c); err != nil {
+			r.fail(err)
+			continue
+		}
+		r.Applied = true
+	}
+	return p.err()
+}
+
+// Reconcile plans and applies the changes to reconcile NGINX Plus with the state.
+// The plan is not applied if any resource can't be read.
+func Reconcile(ctx context.Context, c Client, s *State, opts ...Option) (*Plan, error) {
+	plan, err := NewPlan(ctx, c, s, opts...)
+	if err != nil {
+		return plan, err
+	}
+	return plan, plan.Apply(ctx, c)
+}
+
+func (p *Plan) err() error {
+	var errs []error
+	for _, r := range p.Resources {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%v %v: %w", r.Kind, r.Name, r.err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ResourcePlan) fail(err error) {
+	r.err = err
+	r.Error = err.Error()
+}
+
+type upstreamAPI[S client.Server] struct {
+	get    func(ctx context.Context, upstream string) ([]S, error)
+	update func(ctx context.Context, upstream string, servers []S) ([]S, []S, []S, error)
+}
+
+func httpServers(c Client) upstreamAPI[client.UpstreamServer] {
+	return upstreamAPI[client.UpstreamServer]{get: c.GetHTTPServers, update: c.UpdateHTTPServers}
+}
+
+func streamServers(c Client) upstreamAPI[client.StreamUpstreamServer] {
+	return upstreamAPI[client.StreamUpstreamServer]{get: c.GetStreamServers, update: c.UpdateStreamServers}
+}
+
+func planUpstream[S client.Server](ctx context.Context, c Client, kind Kind, name string, desired []S, prune bool, api func(Client) upstreamAPI[S]) *ResourcePlan {
+	r := &ResourcePlan{Kind: kind, Name: name}
+	actual, err := api(c).get(ctx, name)
+	if err != nil {
+		r.fail(err)
+		return r
+	}
+	diff := client.DiffServers(desired, actual)
+	r.Add, r.Update = addresses(diff.Added), addresses(diff.Updated)
+	if prune {
+		r.Delete = addresses(diff.Deleted)
+	} else {
+		r.Keep = addresses(diff.Deleted)
+	}
+
+	r.apply = func(ctx context.Context, c Client) error {
+		servers := desired
+		if !prune {
+			actual, err := api(c).get(ctx, name)
+			if err != nil {
+				return err
+			}
+			servers = append(slices.Clone(desired), client.DiffServers(desired, actual).Deleted...)
+		}
+		_, _, _, err := api(c).update(ctx, name, servers)
+		return err
+	}
+	return r
+}
+
+type keyValAPI struct {
+	get    func(ctx context.Context, zone string) (client.KeyValPairs, error)
+	add    func(ctx context.Context, zone string, key string, val string) error
+	modify func(ctx context.Context, zone string, key string, val string) error
+	delete func(ctx context.Context, zone string, key string) error
+}
+
+func httpKeyVals(c Client) keyValAPI {
+	return keyValAPI{get: c.GetKeyValPairs, add: c.AddKeyValPair, modify: c.ModifyKeyValPair, delete: c.DeleteKeyValuePair}
+}
+
+func streamKeyVals(c Client) keyValAPI {
+	return keyValAPI{get: c.GetStreamKeyValPairs, add: c.AddStreamKeyValPair, modify: c.ModifyStreamKeyValPair, delete: c.DeleteStreamKeyValuePair}
+}
+
+func planKeyVals(ctx context.Context, c Client, kind Kind, zone string, desired client.KeyValPairs, prune bool, api func(Client) keyValAPI) *ResourcePlan {
+	r := &ResourcePlan{Kind: kind, Name: zone}
+	actual, err := api(c).get(ctx, zone)
+	if err != nil {
+		r.fail(err)
+		return r
+	}
+	for _, key := range slices.Sorted(maps.Keys(desired)) {
+		val, ok := actual[key]
+		switch {
+		case !ok:
+			r.Add = append(r.Add, key)
+		case val != desired[key]:
+			r.Update = append(r.Update, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(actual)) {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if prune {
+			r.Delete = append(r.Delete, key)
+		} else {
+			r.Keep = append(r.Keep, key)
+		}
+	}
+
+	r.apply = func(ctx context.Context, c Client) error {
+		kv := api(c)
+		var errs []error
+		for _, key := range r.Add {
+			errs = append(errs, kv.add(ctx, zone, key, desired[key]))
+		}
+		for _, key := range r.Update {
+			errs = append(errs, kv.modify(ctx, zone, key, desired[key]))
+		}
+		for _, key := range r.Delete {
+			errs = append(errs, kv.delete(ctx, zone, key))
+		}
+		return errors.Join(errs...)
+	}
+	return r
+}
+
+func addresses[S client.Server](servers []S) []string {
+	var result []string
+	for _, s := range servers {
+		result = append(result, s.Address())
+	}
+	return result
+}
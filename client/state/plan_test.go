@@ -0,0 +1,244 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+var errFailed = errors.New("failed")
+
+// fakeClient is an NGINX Plus with HTTP upstreams, stream upstreams and HTTP keyval zones in memory.
+type fakeClient struct {
+	*clientmock.Client
+	upstreams       map[string][]client.UpstreamServer
+	streamUpstreams map[string][]client.StreamUpstreamServer
+	zones           map[string]client.KeyValPairs
+	mu              sync.Mutex
+}
+
+func newFakeClient() *fakeClient {
+	f := &fakeClient{
+		Client:          &clientmock.Client{},
+		upstreams:       map[string][]client.UpstreamServer{},
+		streamUpstreams: map[string][]client.StreamUpstreamServer{},
+		zones:           map[string]client.KeyValPairs{},
+	}
+	f.GetHTTPServersFunc = func(_ context.Context, upstream string) ([]client.UpstreamServer, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		servers, ok := f.upstreams[upstream]
+		if !ok {
+			return nil, errFailed
+		}
+		return servers, nil
+	}
+	f.UpdateHTTPServersFunc = func(_ context.Context, upstream string, servers []client.UpstreamServer) ([]client.UpstreamServer, []client.UpstreamServer, []client.UpstreamServer, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.upstreams[upstream] = servers
+		return nil, nil, nil, nil
+	}
+	f.GetStreamServersFunc = func(_ context.Context, upstream string) ([]client.StreamUpstreamServer, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.streamUpstreams[upstream], nil
+	}
+	f.UpdateStreamServersFunc = func(_ context.Context, upstream string, servers []client.StreamUpstreamServer) ([]client.StreamUpstreamServer, []client.StreamUpstreamServer, []client.StreamUpstreamServer, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.streamUpstreams[upstream] = servers
+		return nil, nil, nil, nil
+	}
+	f.GetKeyValPairsFunc = func(_ context.Context, zone string) (client.KeyValPairs, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.zones[zone], nil
+	}
+	set := func(_ context.Context, zone, key, val string) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.zones[zone] == nil {
+			f.zones[zone] = client.KeyValPairs{}
+		}
+		f.zones[zone][key] = val
+		return nil
+	}
+	f.AddKeyValPairFunc = set
+	f.ModifyKeyValPairFunc = set
+	f.DeleteKeyValuePairFunc = func(_ context.Context, zone, key string) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.zones[zone], key)
+		return nil
+	}
+	return f
+}
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+	weight := 2
+	no := false
+	tests := []struct {
+		name              string
+		state             *State
+		opts              []Option
+		expectedServers   []client.UpstreamServer
+		expectedPairs     client.KeyValPairs
+		expectedResources int
+	}{
+		{
+			name: "prune",
+			state: &State{
+				HTTPUpstreams: map[string]Upstream[client.UpstreamServer]{
+					"backend": {Servers: []client.UpstreamServer{{Server: "10.0.0.1:80", Weight: &weight}, {Server: "10.0.0.3:80"}}},
+				},
+				KeyValZones: map[string]KeyValZone{"zone": {Pairs: client.KeyValPairs{"a": "changed", "c": "3"}}},
+			},
+			expectedServers:   []client.UpstreamServer{{Server: "10.0.0.1:80", Weight: &weight}, {Server: "10.0.0.3:80"}},
+			expectedPairs:     client.KeyValPairs{"a": "changed", "c": "3"},
+			expectedResources: 2,
+		},
+		{
+			name: "no prune",
+			state: &State{
+				Prune: &no,
+				HTTPUpstreams: map[string]Upstream[client.UpstreamServer]{
+					"backend": {Servers: []client.UpstreamServer{{Server: "10.0.0.3:80"}}},
+				},
+				KeyValZones: map[string]KeyValZone{"zone": {Pairs: client.KeyValPairs{"c": "3"}}},
+			},
+			expectedServers:   []client.UpstreamServer{{Server: "10.0.0.3:80"}, {Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}},
+			expectedPairs:     client.KeyValPairs{"a": "1", "b": "2", "c": "3"},
+			expectedResources: 2,
+		},
+		{
+			name: "owner",
+			state: &State{
+				Owner: "team-a",
+				HTTPUpstreams: map[string]Upstream[client.UpstreamServer]{
+					"backend": {Owner: "team-b", Servers: []client.UpstreamServer{{Server: "10.0.0.3:80"}}},
+				},
+				KeyValZones: map[string]KeyValZone{"zone": {Pairs: client.KeyValPairs{"c": "3"}}},
+			},
+			opts:              []Option{WithOwner("team-a")},
+			expectedServers:   []client.UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}},
+			expectedPairs:     client.KeyValPairs{"c": "3"},
+			expectedResources: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			fake := newFakeClient()
+			fake.upstreams["backend"] = []client.UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}}
+			fake.zones["zone"] = client.KeyValPairs{"a": "1", "b": "2"}
+			test.state.Version = SchemaVersion
+
+			plan, err := Reconcile(context.Background(), fake, test.state, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Resources) != test.expectedResources {
+				t.Fatalf("expected %v resources, got %+v", test.expectedResources, plan.Resources)
+			}
+			for _, r := range plan.Resources {
+				if !r.Applied {
+					t.Fatalf("expected %v %v to be applied", r.Kind, r.Name)
+				}
+			}
+			if !reflect.DeepEqual(fake.upstreams["backend"], test.expectedServers) {
+				t.Fatalf("expected servers %+v, got %+v", test.expectedServers, fake.upstreams["backend"])
+			}
+			if !reflect.DeepEqual(fake.zones["zone"], test.expectedPairs) {
+				t.Fatalf("expected pairs %v, got %v", test.expectedPairs, fake.zones["zone"])
+			}
+
+			plan, err = NewPlan(context.Background(), fake, test.state, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.HasChanges() {
+				t.Fatalf("expected no changes after reconcile, got\n%v", plan)
+			}
+		})
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	t.Parallel()
+	fake := newFakeClient()
+	fake.upstreams["backend"] = []client.UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80"}}
+	fake.streamUpstreams["dns"] = []client.StreamUpstreamServer{{Server: "10.0.0.1:53"}}
+	s := &State{
+		Version: SchemaVersion,
+		HTTPUpstreams: map[string]Upstream[client.UpstreamServer]{
+			"backend": {Servers: []client.UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.3:80"}}},
+			"missing": {Servers: []client.UpstreamServer{{Server: "10.0.0.1:80"}}},
+		},
+		StreamUpstreams: map[string]Upstream[client.StreamUpstreamServer]{
+			"dns": {Servers: []client.StreamUpstreamServer{{Server: "10.0.0.1:53"}}},
+		},
+	}
+
+	plan, err := NewPlan(context.Background(), fake, s)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected the error reading the missing upstream, got %v", err)
+	}
+	expected := []ResourcePlan{
+		{Kind: KindHTTPUpstream, Name: "backend", Add: []string{"10.0.0.3:80"}, Delete: []string{"10.0.0.2:80"}},
+		{Kind: KindHTTPUpstream, Name: "missing", Error: errFailed.Error()},
+		{Kind: KindStreamUpstream, Name: "dns"},
+	}
+	if len(plan.Resources) != len(expected) {
+		t.Fatalf("expected %v resources, got %+v", len(expected), plan.Resources)
+	}
+	for i, r := range plan.Resources {
+		r.apply, r.err = nil, nil
+		if !reflect.DeepEqual(*r, expected[i]) {
+			t.Errorf("expected resource %+v, got %+v", expected[i], *r)
+		}
+	}
+	expectedString := "http_upstream backend:\n  + 10.0.0.3:80\n  - 10.0.0.2:80\nhttp_upstream missing:\n  error: failed\n"
+	if plan.String() != expectedString {
+		t.Fatalf("expected plan\n%v\ngot\n%v", expectedString, plan.String())
+	}
+
+	if _, err := Reconcile(context.Background(), fake, s); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(fake.UpstreamManager.CallsOf("UpdateHTTPServers")) != 0 {
+		t.Fatal("expected the plan not to be applied when a resource can't be read")
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	t.Parallel()
+	fake := newFakeClient()
+	fake.zones["zone"] = client.KeyValPairs{"a": "1"}
+	fake.ModifyKeyValPairFunc = func(context.Context, string, string, string) error {
+		return errFailed
+	}
+	s := &State{
+		Version:     SchemaVersion,
+		KeyValZones: map[string]KeyValZone{"zone": {Pairs: client.KeyValPairs{"a": "2", "b": "2"}}},
+	}
+
+	plan, err := Reconcile(context.Background(), fake, s)
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected errFailed, got %v", err)
+	}
+	r := plan.Resources[0]
+	if r.Applied || !strings.Contains(r.Error, "failed") {
+		t.Fatalf("unexpected resource %+v", r)
+	}
+	if fake.zones["zone"]["b"] != "2" {
+		t.Fatal("expected the other keys to be applied")
+	}
+}
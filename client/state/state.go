@@ -0,0 +1,157 @@
+// Package state describes the runtime state of NGINX Plus, the servers of upstreams and the key-value pairs of keyval zones,
+// in a JSON file that can be kept in version control, and reconciles NGINX Plus with it.
+//
+// A state file looks like this:
+//
+//	{
+//	  "version": 1,
+//	  "owner": "team-a",
+//	  "http_upstreams": {
+//	    "backend": {"servers": [{"server": "10.0.0.1:80", "weight": 2}, {"server": "10.0.0.2:80"}]}
+//	  },
+//	  "keyval_zones": {
+//	    "flags": {"pairs": {"feature": "on"}, "prune": false}
+//	  }
+//	}
+//
+// Schema is the JSON Schema of the file.
+package state
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// SchemaVersion is the version of the state file this package reads.
+const SchemaVersion = 1
+
+// Schema is the JSON Schema of the state file.
+//
+//go:embed schema.json
+var Schema []byte
+
+// ErrInvalidState is returned for state files that don't match the schema.
+var ErrInvalidState = errors.New("invalid state")
+
+// State is the desired runtime state of NGINX Plus.
+// Upstreams and keyval zones that are not in the state are not managed and never changed.
+type State struct {
+	HTTPUpstreams     map[string]Upstream[client.UpstreamServer]       `json:"http_upstreams,omitempty"`
+	StreamUpstreams   map[string]Upstream[client.StreamUpstreamServer] `json:"stream_upstreams,omitempty"`
+	KeyValZones       map[string]KeyValZone                            `json:"keyval_zones,omitempty"`
+	StreamKeyValZones map[string]KeyValZone                            `json:"stream_keyval_zones,omitempty"`
+	// Prune is the default of Prune of the upstreams and keyval zones. It is true if not set.
+	Prune *bool `json:"prune,omitempty"`
+	// Owner is the default owner of the upstreams and keyval zones.
+	Owner string `json:"owner,omitempty"`
+	// Version is the version of the schema, SchemaVersion.
+	Version int `json:"version"`
+}
+
+// Upstream is the desired state of an HTTP or stream upstream.
+type Upstream[S client.Server] struct {
+	// Prune deletes the servers of the upstream that are not in Servers. It overrides Prune of the State.
+	Prune *bool `json:"prune,omitempty"`
+	// Owner marks who manages the upstream. It overrides Owner of the State. See WithOwner.
+	Owner   string `json:"owner,omitempty"`
+	Servers []S    `json:"servers"`
+}
+
+// KeyValZone is the desired state of an HTTP or stream keyval zone.
+type KeyValZone struct {
+	Pairs client.KeyValPairs `json:"pairs"`
+	// Prune deletes the keys of the zone that are not in Pairs. It overrides Prune of the State.
+	Prune *bool `json:"prune,omitempty"`
+	// Owner marks who manages the zone. It overrides Owner of the State. See WithOwner.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Load reads and validates a state in JSON. Unknown fields are errors.
+func Load(r io.Reader) (*State, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var s State
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadFile reads and validates a state file in JSON.
+func LoadFile(name string) (*State, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	defer f.Close()
+	s, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state file %v: %w", name, err)
+	}
+	return s, nil
+}
+
+// Validate checks the version of the state and the servers of the upstreams,
+// which must be valid and must not have the same address more than once.
+func (s *State) Validate() error {
+	if s.Version != SchemaVersion {
+		return fmt.Errorf("%w: unsupported version %v, expected %v", ErrInvalidState, s.Version, SchemaVersion)
+	}
+	var errs []error
+	for name, u := range s.HTTPUpstreams {
+		errs = append(errs, validateServers("http upstream "+name, u.Servers)...)
+	}
+	for name, u := range s.StreamUpstreams {
+		errs = append(errs, validateServers("stream upstream "+name, u.Servers)...)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidState, errors.Join(errs...))
+	}
+	return nil
+}
+
+func validateServers[S client.Server](upstream string, servers []S) []error {
+	var errs []error
+	seen := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		if err := server.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", upstream, err))
+			continue
+		}
+		key := client.ServerKey(server)
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("%v: duplicate server %v", upstream, server.Address()))
+		}
+		seen[key] = true
+	}
+	return errs
+}
+
+// prune returns the effective prune setting of an upstream or zone.
+func (s *State) prune(prune *bool) bool {
+	switch {
+	case prune != nil:
+		return *prune
+	case s.Prune != nil:
+		return *s.Prune
+	default:
+		return true
+	}
+}
+
+// owner returns the effective owner of an upstream or zone.
+func (s *State) owner(owner string) string {
+	if owner != "" {
+		return owner
+	}
+	return s.Owner
+}
@@ -0,0 +1,138 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			input: `{"version": 1, "owner": "team-a",
+				"http_upstreams": {"backend": {"servers": [{"server": "10.0.0.1:80", "weight": 2}, {"server": "10.0.0.2"}]}},
+				"stream_upstreams": {"dns": {"servers": [{"server": "10.0.0.1:53"}], "prune": false}},
+				"keyval_zones": {"flags": {"pairs": {"feature": "on"}}}}`,
+		},
+		{
+			name:    "unknown field",
+			input:   `{"version": 1, "http_upstreams": {"backend": {"servers": [{"server": "10.0.0.1:80", "wieght": 2}]}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported version",
+			input:   `{"version": 2}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing version",
+			input:   `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid server",
+			input:   `{"version": 1, "http_upstreams": {"backend": {"servers": [{"server": ""}]}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate server with the default port",
+			input:   `{"version": 1, "http_upstreams": {"backend": {"servers": [{"server": "10.0.0.1"}, {"server": "10.0.0.1:80"}]}}}`,
+			wantErr: true,
+		},
+		{
+			name:  "same host with and without a service",
+			input: `{"version": 1, "http_upstreams": {"backend": {"servers": [{"server": "backend.example.com", "service": "http"}, {"server": "backend.example.com"}]}}}`,
+		},
+		{
+			name:    "duplicate server with a service",
+			input:   `{"version": 1, "http_upstreams": {"backend": {"servers": [{"server": "backend.example.com", "service": "http"}, {"server": "Backend.example.com", "service": "HTTP"}]}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			input:   `{"version": 1`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := Load(strings.NewReader(test.input))
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidState) {
+					t.Fatalf("expected ErrInvalidState, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Version != SchemaVersion {
+				t.Fatalf("unexpected version %v", s.Version)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(name, []byte(`{"version": 1, "keyval_zones": {"zone": {"pairs": {"a": "1"}}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.KeyValZones["zone"].Pairs["a"] != "1" {
+		t.Fatalf("unexpected state %+v", s)
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+	var schema map[string]any
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := schema["$defs"]; !ok {
+		t.Fatal("expected the schema to have definitions")
+	}
+}
+
+func TestPruneAndOwner(t *testing.T) {
+	t.Parallel()
+	no, yes := false, true
+	tests := []struct {
+		state    *State
+		prune    *bool
+		expected bool
+	}{
+		{state: &State{}, expected: true},
+		{state: &State{Prune: &no}, expected: false},
+		{state: &State{Prune: &no}, prune: &yes, expected: true},
+		{state: &State{}, prune: &no, expected: false},
+	}
+	for _, test := range tests {
+		if got := test.state.prune(test.prune); got != test.expected {
+			t.Errorf("expected prune %v, got %v", test.expected, got)
+		}
+	}
+
+	s := &State{Owner: "team-a"}
+	if s.owner("") != "team-a" || s.owner("team-b") != "team-b" {
+		t.Fatal("unexpected owner")
+	}
+}
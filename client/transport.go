@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// unixSocketHost is the host used in the URLs of requests sent over a UNIX-domain socket.
+// NGINX ignores it, but net/http requires one.
+const unixSocketHost = "localhost"
+
+// parseUnixEndpoint splits an API endpoint such as "unix:///run/nginx-api.sock:/api"
+// into the socket path and the HTTP endpoint used to build request URLs.
+func parseUnixEndpoint(apiEndpoint string) (socketPath, httpEndpoint string, ok bool) {
+	rest, ok := strings.CutPrefix(apiEndpoint, unixPrefix)
+	if !ok {
+		return "", "", false
+	}
+	rest = strings.TrimPrefix(rest, "//")
+
+	socketPath, apiPath := rest, ""
+	if i := strings.LastIndex(rest, ":/"); i != -1 {
+		socketPath, apiPath = rest[:i], rest[i+1:]
+	}
+
+	return socketPath, "http://" + unixSocketHost + strings.TrimSuffix(apiPath, "/"), true
+}
+
+// configureTransport returns a copy of the HTTP client with its transport adjusted for the client options.
+// The HTTP client passed by the caller is never modified.
+func (client *NginxClient) configureTransport(httpClient *http.Client) (*http.Client, error) {
+	if client.socketPath == "" && client.certReloader == nil {
+		return httpClient, nil
+	}
+
+	transport, err := cloneTransport(httpClient.Transport)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.socketPath != "" {
+		socketPath := client.socketPath
+		dialer := &net.Dialer{}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+	}
+
+	if client.certReloader != nil {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		transport.TLSClientConfig.GetClientCertificate = client.certReloader.GetClientCertificate
+	}
+
+	configured := *httpClient
+	configured.Transport = transport
+	return &configured, nil
+}
+
+// cloneTransport returns a copy of the transport that can be adjusted without affecting the original.
+func cloneTransport(rt http.RoundTripper) (*http.Transport, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("transport of type %T: %w", rt, ErrNotSupported)
+	}
+	return transport.Clone(), nil
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseUnixEndpoint(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		endpoint     string
+		socketPath   string
+		httpEndpoint string
+		msg          string
+		ok           bool
+	}{
+		{
+			endpoint:     "unix:///run/nginx-api.sock:/api",
+			socketPath:   "/run/nginx-api.sock",
+			httpEndpoint: "http://localhost/api",
+			ok:           true,
+			msg:          "socket and api path",
+		},
+		{
+			endpoint:     "unix:/run/nginx-api.sock:/api/",
+			socketPath:   "/run/nginx-api.sock",
+			httpEndpoint: "http://localhost/api",
+			ok:           true,
+			msg:          "single slash and trailing slash",
+		},
+		{
+			endpoint:     "unix:///run/nginx-api.sock",
+			socketPath:   "/run/nginx-api.sock",
+			httpEndpoint: "http://localhost",
+			ok:           true,
+			msg:          "socket without api path",
+		},
+		{
+			endpoint: "http://127.0.0.1:8080/api",
+			msg:      "http endpoint",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			socketPath, httpEndpoint, ok := parseUnixEndpoint(test.endpoint)
+			if ok != test.ok || socketPath != test.socketPath || httpEndpoint != test.httpEndpoint {
+				t.Errorf("parseUnixEndpoint(%v) = (%v, %v, %v) but expected (%v, %v, %v)",
+					test.endpoint, socketPath, httpEndpoint, ok, test.socketPath, test.httpEndpoint, test.ok)
+			}
+		})
+	}
+}
+
+func TestClientWithUnixSocket(t *testing.T) {
+	t.Parallel()
+	socketPath := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := (&net.ListenConfig{}).Listen(context.Background(), "unix", socketPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.URL.Path {
+		case "/api":
+			_, err = w.Write([]byte(`[4, 5, 6, 7, 8]`))
+		case "/api/8/nginx":
+			_, err = w.Write([]byte(`{"version": "1.25.3", "build": "nginx-plus-r31-p1"}`))
+		default:
+			t.Errorf("unexpected request to %v", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}))
+	ts.Listener = listener
+	ts.Start()
+	defer ts.Close()
+
+	for _, c := range []struct {
+		endpoint string
+		msg      string
+		opts     []Option
+	}{
+		{endpoint: "unix://" + socketPath + ":/api", msg: "unix endpoint"},
+		{endpoint: "http://nginx/api", opts: []Option{WithUnixSocket(socketPath)}, msg: "unix socket option"},
+	} {
+		opts := append(c.opts, WithCheckAPI(), WithMaxAPIVersion(), WithHTTPClient(&http.Client{}))
+		client, err := NewNginxClient(c.endpoint, opts...)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.msg, err)
+		}
+		if client.Version() != 8 {
+			t.Fatalf("%v: expected API version 8, got %v", c.msg, client.Version())
+		}
+
+		info, err := client.GetNginxInfo(context.Background())
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.msg, err)
+		}
+		if info.Version != "1.25.3" {
+			t.Fatalf("%v: expected version 1.25.3, got %v", c.msg, info.Version)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UpstreamHandle is a handle to an HTTP or stream upstream that offers the same methods for both kinds of upstreams.
+// Create it with HTTPUpstream or StreamUpstream. Its methods call the corresponding methods of the NginxClient,
+// for example Add calls AddHTTPServer or AddStreamServer.
+type UpstreamHandle[S Server] struct {
+	client *NginxClient
+	api    upstreamAPI[S]
+	name   string
+	stream bool
+}
+
+// upstreamAPI holds the methods of the NginxClient for one kind of upstream.
+type upstreamAPI[S Server] struct {
+	list       func(ctx context.Context, upstream string) ([]S, error)
+	get        func(ctx context.Context, upstream string, id int) (S, error)
+	add        func(ctx context.Context, upstream string, server S) error
+	delete     func(ctx context.Context, upstream string, server string) error
+	deleteByID func(ctx context.Context, upstream string, id int) error
+	update     func(ctx context.Context, upstream string, server S) error
+	reconcile  func(ctx context.Context, upstream string, servers []S, opts ...ReconcileOption) (ReconcileResult[S], error)
+}
+
+// HTTPUpstream returns a handle to the HTTP upstream.
+func (client *NginxClient) HTTPUpstream(name string) *UpstreamHandle[UpstreamServer] {
+	return &UpstreamHandle[UpstreamServer]{
+		client: client,
+		name:   name,
+		stream: httpContext,
+		api: upstreamAPI[UpstreamServer]{
+			list:       client.GetHTTPServers,
+			get:        client.GetHTTPServerByID,
+			add:        client.AddHTTPServer,
+			delete:     client.DeleteHTTPServer,
+			deleteByID: client.DeleteHTTPServerByID,
+			update:     client.UpdateHTTPServer,
+			reconcile:  client.ReconcileHTTPServers,
+		},
+	}
+}
+
+// StreamUpstream returns a handle to the stream upstream.
+func (client *NginxClient) StreamUpstream(name string) *UpstreamHandle[StreamUpstreamServer] {
+	return &UpstreamHandle[StreamUpstreamServer]{
+		client: client,
+		name:   name,
+		stream: streamContext,
+		api: upstreamAPI[StreamUpstreamServer]{
+			list:       client.GetStreamServers,
+			get:        client.GetStreamServerByID,
+			add:        client.AddStreamServer,
+			delete:     client.DeleteStreamServer,
+			deleteByID: client.DeleteStreamServerByID,
+			update:     client.UpdateStreamServer,
+			reconcile:  client.ReconcileStreamServers,
+		},
+	}
+}
+
+// Name returns the name of the upstream.
+func (u *UpstreamHandle[S]) Name() string {
+	return u.name
+}
+
+// Stream reports whether the upstream is a stream upstream.
+func (u *UpstreamHandle[S]) Stream() bool {
+	return u.stream
+}
+
+// Servers returns the servers of the upstream.
+func (u *UpstreamHandle[S]) Servers(ctx context.Context) ([]S, error) {
+	return u.api.list(ctx, u.name)
+}
+
+// Server returns the server of the upstream with the ID.
+func (u *UpstreamHandle[S]) Server(ctx context.Context, id int) (S, error) {
+	return u.api.get(ctx, u.name, id)
+}
+
+// Add adds the server to the upstream.
+func (u *UpstreamHandle[S]) Add(ctx context.Context, server S) error {
+	return u.api.add(ctx, u.name, server)
+}
+
+// Delete removes the server with the address from the upstream.
+func (u *UpstreamHandle[S]) Delete(ctx context.Context, address string) error {
+	return u.api.delete(ctx, u.name, address)
+}
+
+// DeleteByID removes the server with the ID from the upstream.
+func (u *UpstreamHandle[S]) DeleteByID(ctx context.Context, id int) error {
+	return u.api.deleteByID(ctx, u.name, id)
+}
+
+// Update updates the server of the upstream with the matching server ID.
+func (u *UpstreamHandle[S]) Update(ctx context.Context, server S) error {
+	return u.api.update(ctx, u.name, server)
+}
+
+// Reconcile updates the servers of the upstream to be the servers, see ReconcileHTTPServers.
+func (u *UpstreamHandle[S]) Reconcile(ctx context.Context, servers []S, opts ...ReconcileOption) (ReconcileResult[S], error) {
+	return u.api.reconcile(ctx, u.name, servers, opts...)
+}
+
+// UpstreamStats are the stats shared by HTTP and stream upstreams.
+type UpstreamStats struct {
+	Zone    string
+	Peers   []PeerStats
+	Zombies int
+}
+
+// PeerStats are the stats shared by the peers of HTTP and stream upstreams.
+type PeerStats struct {
+	Server   string
+	Name     string
+	State    string
+	Sent     uint64
+	Received uint64
+	Fails    uint64
+	Unavail  uint64
+	Active   uint64
+	Downtime uint64
+	ID       int
+	MaxConns int
+	Weight   int
+	Backup   bool
+}
+
+// Stats returns the stats of the upstream, from GetUpstreams or GetStreamUpstreams.
+func (u *UpstreamHandle[S]) Stats(ctx context.Context) (UpstreamStats, error) {
+	if u.stream {
+		upstreams, err := u.client.GetStreamUpstreams(ctx)
+		if err != nil {
+			return UpstreamStats{}, err
+		}
+		upstream, ok := (*upstreams)[u.name]
+		if !ok {
+			return UpstreamStats{}, fmt.Errorf("stream upstream %v: %w", u.name, ErrUpstreamNotFound)
+		}
+		return newUpstreamStats(upstream.Zone, upstream.Zombies, upstream.Peers), nil
+	}
+
+	upstreams, err := u.client.GetUpstreams(ctx)
+	if err != nil {
+		return UpstreamStats{}, err
+	}
+	upstream, ok := (*upstreams)[u.name]
+	if !ok {
+		return UpstreamStats{}, fmt.Errorf("upstream %v: %w", u.name, ErrUpstreamNotFound)
+	}
+	return newUpstreamStats(upstream.Zone, upstream.Zombies, upstream.Peers), nil
+}
+
+// peer is the type of the peers of HTTP and stream upstreams, like Server is for their servers.
+type peer interface {
+	Peer | StreamPeer
+}
+
+// newUpstreamStats returns the stats shared by HTTP and stream upstreams.
+func newUpstreamStats[P peer](zone string, zombies int, peers []P) UpstreamStats {
+	stats := UpstreamStats{Zone: zone, Zombies: zombies, Peers: make([]PeerStats, 0, len(peers))}
+	for _, p := range peers {
+		stats.Peers = append(stats.Peers, newPeerStats(p))
+	}
+	return stats
+}
+
+// newPeerStats returns the stats shared by the peers of HTTP and stream upstreams.
+func newPeerStats[P peer](p P) PeerStats {
+	switch p := any(p).(type) {
+	case StreamPeer:
+		return PeerStats{
+			Server: p.Server, Name: p.Name, State: p.State, Sent: p.Sent, Received: p.Received, Fails: p.Fails,
+			Unavail: p.Unavail, Active: p.Active, Downtime: p.Downtime, ID: p.ID, MaxConns: p.MaxConns, Weight: p.Weight, Backup: p.Backup,
+		}
+	default:
+		hp := any(p).(Peer)
+		return PeerStats{
+			Server: hp.Server, Name: hp.Name, State: hp.State, Sent: hp.Sent, Received: hp.Received, Fails: hp.Fails,
+			Unavail: hp.Unavail, Active: hp.Active, Downtime: hp.Downtime, ID: hp.ID, MaxConns: hp.MaxConns, Weight: hp.Weight, Backup: hp.Backup,
+		}
+	}
+}
+
+// Wait checks the stats of the upstream every interval until the condition is true or the context is done.
+func (u *UpstreamHandle[S]) Wait(ctx context.Context, interval time.Duration, condition func(UpstreamStats) bool) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats, err := u.Stats(ctx)
+		if err == nil && condition(stats) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for upstream %v: %w", u.name, context.Cause(ctx))
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitHealthy waits until each of the servers has a peer in the "up" state.
+func (u *UpstreamHandle[S]) WaitHealthy(ctx context.Context, interval time.Duration, servers ...S) error {
+	return u.Wait(ctx, interval, func(stats UpstreamStats) bool {
+		peers := make([]peerState, 0, len(stats.Peers))
+		for _, p := range stats.Peers {
+			peers = append(peers, peerState{id: p.ID, name: p.Name, state: p.State})
+		}
+		return allHealthy(servers, peers)
+	})
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpstreamHandle(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/9/"), "/")
+		requests = append(requests, r.Method+" "+path)
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(path, "/servers"):
+			_, _ = w.Write([]byte(`[{"id":1,"server":"10.0.0.1:80"}]`))
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	httpUpstream := c.HTTPUpstream("foo")
+	streamUpstream := c.StreamUpstream("foo")
+	if httpUpstream.Name() != "foo" || httpUpstream.Stream() || !streamUpstream.Stream() {
+		t.Fatal("unexpected name or kind of upstream")
+	}
+
+	servers, err := httpUpstream.Servers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Address() != "10.0.0.1:80" || servers[0].ServerID() != 1 {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+	if err := streamUpstream.Add(ctx, StreamUpstreamServer{Server: "10.0.0.2:80"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := httpUpstream.DeleteByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	result, err := streamUpstream.Reconcile(ctx, []StreamUpstreamServer{{Server: "10.0.0.3:80"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Added()) != 1 || len(result.Deleted()) != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{
+		"GET http/upstreams/foo/servers",
+		"GET stream/upstreams/foo/servers",
+		"POST stream/upstreams/foo/servers",
+		"DELETE http/upstreams/foo/servers/1",
+		"GET stream/upstreams/foo/servers",
+	}
+	if got := strings.Join(requests[:len(expected)], ","); got != strings.Join(expected, ",") {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+}
+
+func TestUpstreamHandleStats(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	state := "unhealthy"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/9/"), "/") {
+		case "http/upstreams":
+			_, _ = w.Write([]byte(`{"foo":{"zone":"foo","zombies":1,"peers":[{"id":0,"server":"10.0.0.1:80","name":"10.0.0.1:80","state":"` + state + `","weight":2}]}}`))
+			state = "up"
+		case "stream/upstreams":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	upstream := c.HTTPUpstream("foo")
+
+	stats, err := upstream.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Zone != "foo" || stats.Zombies != 1 || len(stats.Peers) != 1 || stats.Peers[0].Weight != 2 || stats.Peers[0].State != "unhealthy" {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := upstream.WaitHealthy(ctx, time.Millisecond, UpstreamServer{Server: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.StreamUpstream("foo").Stats(ctx); !errors.Is(err, ErrUpstreamNotFound) {
+		t.Fatalf("expected ErrUpstreamNotFound, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = upstream.Wait(canceled, time.Millisecond, func(UpstreamStats) bool { return false })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
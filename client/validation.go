@@ -0,0 +1,134 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxRouteLength = 32
+
+// ErrInvalidServer is returned when an upstream server has invalid parameters.
+var ErrInvalidServer = errors.New("invalid server")
+
+// reTime matches the NGINX time format, for example "10", "10s", "1m30s" or "500ms".
+// https://nginx.org/en/docs/syntax.html
+var reTime = regexp.MustCompile(`^(?:\d+(?:ms|[smhdwMy])?\s*)+$`)
+
+// FieldError describes an invalid parameter of an upstream server.
+type FieldError struct {
+	// Field is the name of the parameter as used by the NGINX Plus API, for example "weight".
+	Field  string
+	Value  string
+	Reason string
+}
+
+// Error allows FieldError to match the Error interface.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// Unwrap allows FieldError to match ErrInvalidServer with errors.Is.
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidServer
+}
+
+// Validate checks the parameters of the server against the rules NGINX applies,
+// returning a *FieldError for every invalid parameter.
+func (s UpstreamServer) Validate() error {
+	errs := validateCommonParameters(s.Server, s.Service, s.MaxConns, s.MaxFails, s.Weight, s.FailTimeout, s.SlowStart)
+
+	if len(s.Route) > maxRouteLength {
+		errs = append(errs, &FieldError{Field: "route", Value: s.Route, Reason: fmt.Sprintf("must be at most %d characters", maxRouteLength)})
+	}
+	if s.Route != "" && s.Backup != nil && *s.Backup {
+		errs = append(errs, &FieldError{Field: "route", Value: s.Route, Reason: "can't be used with backup"})
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the parameters of the stream server against the rules NGINX applies,
+// returning a *FieldError for every invalid parameter.
+// Stream servers have no route or drain parameters, so those can't be set by construction.
+func (s StreamUpstreamServer) Validate() error {
+	return errors.Join(validateCommonParameters(s.Server, s.Service, s.MaxConns, s.MaxFails, s.Weight, s.FailTimeout, s.SlowStart)...)
+}
+
+// validateUpdate validates a server passed to UpdateHTTPServer or UpdateStreamServer.
+// A server with an ID can leave the address empty to update only its other parameters.
+func validateUpdate[S Server](server S) error {
+	err := server.Validate()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || server.Address() != "" || server.ServerID() == 0 {
+		return err
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		var fieldErr *FieldError
+		if errors.As(e, &fieldErr) && fieldErr.Field == "server" {
+			continue
+		}
+		errs = append(errs, e)
+	}
+	return errors.Join(errs...)
+}
+
+func validateCommonParameters(server, service string, maxConns, maxFails, weight *int, failTimeout, slowStart string) []error {
+	var errs []error
+
+	address, err := ParseServerAddress(server)
+	if err != nil {
+		errs = append(errs, &FieldError{Field: "server", Value: server, Reason: err.Error()})
+	}
+
+	if service != "" {
+		if !isValidServiceName(service) {
+			errs = append(errs, &FieldError{Field: "service", Value: service, Reason: "invalid service name"})
+		}
+		if err == nil {
+			if _, hasPort := address.Port(); hasPort {
+				errs = append(errs, &FieldError{Field: "server", Value: server, Reason: "can't have a port when service is set"})
+			}
+			if address.Kind() != ServerAddressHostname {
+				errs = append(errs, &FieldError{Field: "server", Value: server, Reason: "must be a hostname when service is set"})
+			}
+		}
+	}
+
+	if maxConns != nil && *maxConns < 0 {
+		errs = append(errs, &FieldError{Field: "max_conns", Value: strconv.Itoa(*maxConns), Reason: "must not be negative"})
+	}
+	if maxFails != nil && *maxFails < 0 {
+		errs = append(errs, &FieldError{Field: "max_fails", Value: strconv.Itoa(*maxFails), Reason: "must not be negative"})
+	}
+	if weight != nil && *weight < 1 {
+		errs = append(errs, &FieldError{Field: "weight", Value: strconv.Itoa(*weight), Reason: "must be positive"})
+	}
+	if failTimeout != "" && !reTime.MatchString(failTimeout) {
+		errs = append(errs, &FieldError{Field: "fail_timeout", Value: failTimeout, Reason: ErrInvalidTimeout.Error()})
+	}
+	if slowStart != "" && !reTime.MatchString(slowStart) {
+		errs = append(errs, &FieldError{Field: "slow_start", Value: slowStart, Reason: ErrInvalidTimeout.Error()})
+	}
+
+	return errs
+}
+
+// isValidServiceName checks the name used for SRV lookups.
+// NGINX accepts either a plain name such as "http", to which it adds the "_" prefix and the "._tcp" suffix,
+// or a full name such as "_http._tcp".
+func isValidServiceName(service string) bool {
+	service = strings.ToLower(service)
+	if !strings.HasPrefix(service, "_") {
+		return isValidHostname(service) && !strings.Contains(service, ".")
+	}
+	for label := range strings.SplitSeq(service, ".") {
+		if !strings.HasPrefix(label, "_") || !isValidHostname(label) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpstreamServerValidate(t *testing.T) {
+	t.Parallel()
+	negative := -1
+	zero := 0
+	backup := true
+	tests := []struct {
+		msg    string
+		fields []string
+		server UpstreamServer
+	}{
+		{
+			msg:    "valid",
+			server: UpstreamServer{Server: "10.0.0.1:80", Weight: &defaultWeight, FailTimeout: "1m30s", SlowStart: "500ms", Route: "a"},
+		},
+		{
+			msg:    "valid service",
+			server: UpstreamServer{Server: "backend.example.com", Service: "_http._tcp"},
+		},
+		{
+			msg:    "invalid address",
+			server: UpstreamServer{Server: "example.com:http"},
+			fields: []string{"server"},
+		},
+		{
+			msg:    "negative values",
+			server: UpstreamServer{Server: "10.0.0.1", MaxConns: &negative, MaxFails: &negative, Weight: &zero},
+			fields: []string{"max_conns", "max_fails", "weight"},
+		},
+		{
+			msg:    "malformed timeouts",
+			server: UpstreamServer{Server: "10.0.0.1", FailTimeout: "10 seconds", SlowStart: "-1s"},
+			fields: []string{"fail_timeout", "slow_start"},
+		},
+		{
+			msg:    "backup with route",
+			server: UpstreamServer{Server: "10.0.0.1", Backup: &backup, Route: "a"},
+			fields: []string{"route"},
+		},
+		{
+			msg:    "service with ip address and port",
+			server: UpstreamServer{Server: "10.0.0.1:80", Service: "http"},
+			fields: []string{"server", "server"},
+		},
+		{
+			msg:    "invalid service name",
+			server: UpstreamServer{Server: "backend.example.com", Service: "_http.tcp"},
+			fields: []string{"service"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.msg, func(t *testing.T) {
+			t.Parallel()
+			err := test.server.Validate()
+			checkFieldErrors(t, err, test.fields)
+		})
+	}
+}
+
+func TestStreamUpstreamServerValidate(t *testing.T) {
+	t.Parallel()
+	zero := 0
+	err := StreamUpstreamServer{Server: "unix:/tmp/sock", Service: "dns", Weight: &zero}.Validate()
+	checkFieldErrors(t, err, []string{"server", "weight"})
+
+	err = StreamUpstreamServer{Server: "10.0.0.1:53"}.Validate()
+	checkFieldErrors(t, err, nil)
+}
+
+func TestUpdateHTTPServersValidatesBeforeChanges(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected %v request to %v", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zero := 0
+	servers := []UpstreamServer{{Server: "10.0.0.1:80"}, {Server: "10.0.0.2:80", Weight: &zero}}
+	added, deleted, updated, err := c.UpdateHTTPServers(context.Background(), "fakeUpstream", servers)
+	if !errors.Is(err, ErrInvalidServer) {
+		t.Fatalf("expected %v, got %v", ErrInvalidServer, err)
+	}
+	if len(added) != 0 || len(deleted) != 0 || len(updated) != 0 {
+		t.Fatalf("expected no changes, got added=%v deleted=%v updated=%v", added, deleted, updated)
+	}
+}
+
+func TestUpdateServerValidatesBeforeChanges(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected %v request to %v", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zero, negative := 0, -1
+	err = c.UpdateHTTPServer(context.Background(), "fakeUpstream", UpstreamServer{ID: 1, Server: "10.0.0.1:80", Weight: &zero})
+	if !errors.Is(err, ErrInvalidServer) {
+		t.Fatalf("expected %v, got %v", ErrInvalidServer, err)
+	}
+	err = c.UpdateStreamServer(context.Background(), "fakeUpstream", StreamUpstreamServer{ID: 1, Server: "10.0.0.1:53", MaxFails: &negative})
+	if !errors.Is(err, ErrInvalidServer) {
+		t.Fatalf("expected %v, got %v", ErrInvalidServer, err)
+	}
+}
+
+func TestUpdateServerWithoutAddress(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode the request: %v", err)
+		}
+		if _, ok := body["server"]; ok || r.Method != http.MethodPatch || !strings.HasSuffix(r.URL.Path, "/upstreams/fakeUpstream/servers/1/") {
+			t.Errorf("unexpected %v request to %v with %v", r.Method, r.URL, body)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c, err := NewNginxClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	weight, zero := 2, 0
+	if err := c.UpdateHTTPServer(ctx, "fakeUpstream", UpstreamServer{ID: 1, Weight: &weight}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.UpdateStreamServer(ctx, "fakeUpstream", StreamUpstreamServer{ID: 1, Weight: &weight}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		server UpstreamServer
+		msg    string
+	}{
+		{server: UpstreamServer{Weight: &weight}, msg: "no ID"},
+		{server: UpstreamServer{ID: 1, Weight: &zero}, msg: "invalid weight"},
+	}
+	for _, test := range tests {
+		if err := c.UpdateHTTPServer(ctx, "fakeUpstream", test.server); !errors.Is(err, ErrInvalidServer) {
+			t.Errorf("%v: expected %v, got %v", test.msg, ErrInvalidServer, err)
+		}
+	}
+}
+
+func checkFieldErrors(t *testing.T, err error, expected []string) {
+	t.Helper()
+	var fields []string
+	if err != nil {
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			t.Fatalf("expected joined errors, got %v", err)
+		}
+		for _, e := range joined.Unwrap() {
+			var fieldErr *FieldError
+			if !errors.As(e, &fieldErr) {
+				t.Fatalf("expected *FieldError, got %v", e)
+			}
+			fields = append(fields, fieldErr.Field)
+		}
+	}
+	if len(fields) != len(expected) {
+		t.Fatalf("expected errors for fields %v, got %v", expected, err)
+	}
+	for i := range fields {
+		if fields[i] != expected[i] {
+			t.Fatalf("expected errors for fields %v, got %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListen         = ":9113"
+	defaultScrapeInterval = 15 * time.Second
+	defaultScrapeTimeout  = 10 * time.Second
+)
+
+// errInvalidConfig is returned for configurations that can't be used.
+var errInvalidConfig = errors.New("invalid config")
+
+// labelName is the syntax of Prometheus label names.
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// config is the configuration of the exporter, read from a JSON file:
+//
+//	{
+//	  "listen": ":9113",
+//	  "scrape_interval": "15s",
+//	  "targets": [
+//	    {"name": "edge-1", "api": "http://10.0.0.1:8080/api", "labels": {"dc": "eu"}},
+//	    {"name": "edge-2", "api": "https://10.0.0.2/api", "token_file": "/run/secrets/token", "scrape_interval": "5s"}
+//	  ]
+//	}
+type config struct {
+	Listen string `json:"listen"`
+	// ScrapeInterval and ScrapeTimeout are the defaults of the targets.
+	ScrapeInterval duration `json:"scrape_interval"`
+	ScrapeTimeout  duration `json:"scrape_timeout"`
+	Targets        []target `json:"targets"`
+}
+
+// target is an NGINX Plus API to scrape.
+type target struct {
+	// Labels are added to all the metrics of the target.
+	Labels map[string]string `json:"labels"`
+	// Name is the value of the target label of the metrics of the target.
+	Name string `json:"name"`
+	API  string `json:"api"`
+	// Username and Password are for basic authentication. PasswordFile is read instead of Password if it is set.
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	PasswordFile string `json:"password_file"`
+	// TokenFile is a file with a bearer token, which is read again when it changes.
+	TokenFile      string   `json:"token_file"`
+	ScrapeInterval duration `json:"scrape_interval"`
+	ScrapeTimeout  duration `json:"scrape_timeout"`
+	// APIVersion is the API version. If it is 0, the highest version supported by NGINX Plus and the client is used.
+	APIVersion int `json:"api_version"`
+}
+
+// duration is a time.Duration written as a string in JSON, for example "15s".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// loadConfig reads and validates the configuration and sets the defaults.
+func loadConfig(r io.Reader) (*config, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var cfg config
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidConfig, err)
+	}
+	if err := cfg.setDefaults(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidConfig, err)
+	}
+	return &cfg, nil
+}
+
+// loadConfigFile reads and validates the configuration file.
+func loadConfigFile(name string) (*config, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+	cfg, err := loadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file %v: %w", name, err)
+	}
+	return cfg, nil
+}
+
+func (cfg *config) setDefaults() error {
+	if cfg.Listen == "" {
+		cfg.Listen = defaultListen
+	}
+	if cfg.ScrapeInterval == 0 {
+		cfg.ScrapeInterval = duration(defaultScrapeInterval)
+	}
+	if cfg.ScrapeTimeout == 0 {
+		cfg.ScrapeTimeout = duration(defaultScrapeTimeout)
+	}
+	if len(cfg.Targets) == 0 {
+		return errors.New("no targets")
+	}
+
+	var errs []error
+	names := make(map[string]bool, len(cfg.Targets))
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if t.ScrapeInterval == 0 {
+			t.ScrapeInterval = cfg.ScrapeInterval
+		}
+		if t.ScrapeTimeout == 0 {
+			t.ScrapeTimeout = min(cfg.ScrapeTimeout, t.ScrapeInterval)
+		}
+		if t.Name == "" {
+			t.Name = t.API
+		}
+		errs = append(errs, t.validate(names))
+		names[t.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+func (t *target) validate(names map[string]bool) error {
+	var errs []error
+	if t.API == "" {
+		errs = append(errs, errors.New("api is required"))
+	}
+	if names[t.Name] {
+		errs = append(errs, errors.New("duplicate name"))
+	}
+	if t.ScrapeInterval < 0 || t.ScrapeTimeout < 0 {
+		errs = append(errs, errors.New("scrape_interval and scrape_timeout must be positive"))
+	}
+	if (t.Username != "" || t.Password != "" || t.PasswordFile != "") && t.TokenFile != "" {
+		errs = append(errs, errors.New("only one of basic authentication and token_file can be set"))
+	}
+	for name := range t.Labels {
+		// Prometheus reserves the label names that start with __.
+		if !labelName.MatchString(name) || strings.HasPrefix(name, "__") || slices.Contains(reservedLabels, name) {
+			errs = append(errs, fmt.Errorf("invalid label name %q", name))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("target %q: %w", t.Name, err)
+	}
+	return nil
+}
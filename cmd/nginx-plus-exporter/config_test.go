@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "no targets", input: `{}`, err: "no targets"},
+		{name: "unknown field", input: `{"targets": [{"api": "http://a/api", "foo": 1}]}`, err: "unknown field"},
+		{name: "invalid duration", input: `{"scrape_interval": 15, "targets": [{"api": "http://a/api"}]}`, err: "duration must be a string"},
+		{name: "missing api", input: `{"targets": [{"name": "a"}]}`, err: "api is required"},
+		{name: "duplicate name", input: `{"targets": [{"api": "http://a/api"}, {"api": "http://a/api"}]}`, err: "duplicate name"},
+		{name: "negative interval", input: `{"targets": [{"api": "http://a/api", "scrape_interval": "-1s"}]}`, err: "must be positive"},
+		{
+			name:  "conflicting authentication",
+			input: `{"targets": [{"api": "http://a/api", "username": "user", "token_file": "token"}]}`,
+			err:   "only one of basic authentication and token_file",
+		},
+		{name: "invalid label", input: `{"targets": [{"api": "http://a/api", "labels": {"1dc": "eu"}}]}`, err: `invalid label name "1dc"`},
+		{name: "reserved label", input: `{"targets": [{"api": "http://a/api", "labels": {"target": "a"}}]}`, err: `invalid label name "target"`},
+		{name: "label of metrics", input: `{"targets": [{"api": "http://a/api", "labels": {"upstream": "a"}}]}`, err: `invalid label name "upstream"`},
+		{name: "label reserved by Prometheus", input: `{"targets": [{"api": "http://a/api", "labels": {"__dc": "eu"}}]}`, err: `invalid label name "__dc"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := loadConfig(strings.NewReader(test.input))
+			if !errors.Is(err, errInvalidConfig) || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected %v with %q, got %v", errInvalidConfig, test.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	t.Parallel()
+	cfg, err := loadConfig(strings.NewReader(`{
+		"scrape_timeout": "20s",
+		"targets": [
+			{"api": "http://a/api"},
+			{"name": "b", "api": "http://b/api", "scrape_interval": "5s", "labels": {"dc": "eu"}},
+			{"name": "c", "api": "http://c/api", "scrape_interval": "30s", "scrape_timeout": "3s"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Listen != defaultListen || time.Duration(cfg.ScrapeInterval) != defaultScrapeInterval {
+		t.Fatalf("expected the default listen address and interval, got %v and %v", cfg.Listen, cfg.ScrapeInterval)
+	}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		timeout  time.Duration
+	}{
+		{name: "http://a/api", interval: defaultScrapeInterval, timeout: defaultScrapeInterval},
+		{name: "b", interval: 5 * time.Second, timeout: 5 * time.Second},
+		{name: "c", interval: 30 * time.Second, timeout: 3 * time.Second},
+	}
+	for i, test := range tests {
+		got := cfg.Targets[i]
+		if got.Name != test.name || time.Duration(got.ScrapeInterval) != test.interval || time.Duration(got.ScrapeTimeout) != test.timeout {
+			t.Errorf("expected target %v with interval %v and timeout %v, got %v with %v and %v",
+				test.name, test.interval, test.timeout, got.Name, time.Duration(got.ScrapeInterval), time.Duration(got.ScrapeTimeout))
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(name, []byte(`{"listen": ":9999", "targets": [{"api": "http://a/api"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfigFile(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Listen != ":9999" {
+		t.Fatalf("expected the listen address :9999, got %v", cfg.Listen)
+	}
+	if _, err := loadConfigFile(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected %v, got %v", os.ErrNotExist, err)
+	}
+}
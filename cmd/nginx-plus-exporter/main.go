@@ -0,0 +1,251 @@
+// Command nginx-plus-exporter scrapes the stats of one or more NGINX Plus instances through the NGINX Plus API
+// and serves them as Prometheus metrics.
+//
+// Usage:
+//
+//	nginx-plus-exporter -config nginx-plus-exporter.json
+//
+// The targets, their credentials, labels and scrape intervals are read from the JSON config file, see config.
+// Each target is scraped in the background at its interval, and the metrics of the last scrape are served at /metrics.
+// The status of the last scrape of each target is served as JSON at /health, with the status 503 if any target is down.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the exporter until the context is done and returns the exit code: 0 on success, 1 on errors and 2 on
+// invalid usage.
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("nginx-plus-exporter", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", "nginx-plus-exporter.json", "config file")
+	debug := fs.Bool("debug", false, "log the requests to the API")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "nginx-plus-exporter: unexpected arguments %v\n", fs.Args())
+		return 2
+	}
+
+	level := slog.LevelInfo
+	if *debug {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	cfg, err := loadConfigFile(*configFile)
+	if err != nil {
+		logger.Error("failed to start", "error", err)
+		return 1
+	}
+	exp, err := newExporter(cfg, func(t target) (statsReader, error) { return connect(t, logger) }, logger)
+	if err != nil {
+		logger.Error("failed to start", "error", err)
+		return 1
+	}
+	if err := exp.serve(ctx, cfg.Listen); err != nil {
+		logger.Error("failed to serve", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// connect creates the client of a target.
+// Without an API version in the config, the version is negotiated with NGINX Plus by the scrapes, see versionReader.
+func connect(t target, logger *slog.Logger) (statsReader, error) {
+	opts := []client.Option{
+		client.WithHTTPClient(&http.Client{Timeout: time.Duration(t.ScrapeTimeout)}),
+		client.WithLogger(logger.With("target", t.Name)),
+	}
+	switch {
+	case t.PasswordFile != "":
+		password, err := os.ReadFile(t.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the password file of target %q: %w", t.Name, err)
+		}
+		opts = append(opts, client.WithAuthenticator(client.BasicAuth(t.Username, strings.TrimSpace(string(password)))))
+	case t.Username != "" || t.Password != "":
+		opts = append(opts, client.WithAuthenticator(client.BasicAuth(t.Username, t.Password)))
+	case t.TokenFile != "":
+		opts = append(opts, client.WithAuthenticator(client.BearerToken(client.NewFileTokenSource(t.TokenFile))))
+	}
+
+	newClient := func(version int) (statsReader, error) {
+		c, err := client.NewNginxClient(t.API, append(slices.Clip(opts), client.WithAPIVersion(version))...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the client of target %q: %w", t.Name, err)
+		}
+		return c, nil
+	}
+	if t.APIVersion != 0 {
+		return newClient(t.APIVersion)
+	}
+
+	// The client of the default version only gets the versions of the API.
+	probe, err := client.NewNginxClient(t.API, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the client of target %q: %w", t.Name, err)
+	}
+	return &versionReader{
+		negotiate: probe.GetMaxAPIVersion,
+		connect:   newClient,
+		logger:    logger.With("target", t.Name),
+	}, nil
+}
+
+// versionReader reads the stats with the highest API version supported by NGINX Plus and the client.
+// The version is negotiated by the first scrape that reaches NGINX Plus, and the scrapes fail until then,
+// so that a target that is down at startup isn't scraped with a version it may not support.
+type versionReader struct {
+	negotiate func(ctx context.Context) (int, error)
+	connect   func(version int) (statsReader, error)
+	reader    statsReader
+	logger    *slog.Logger
+	mu        sync.Mutex
+}
+
+func (r *versionReader) GetStats(ctx context.Context) (*client.Stats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reader == nil {
+		version, err := r.negotiate(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to negotiate the API version: %w", err)
+		}
+		reader, err := r.connect(version)
+		if err != nil {
+			return nil, err
+		}
+		r.logger.Info("negotiated the API version", "version", version)
+		r.reader = reader
+	}
+	return r.reader.GetStats(ctx)
+}
+
+// exporter serves the metrics of the scrapers of the targets.
+type exporter struct {
+	logger   *slog.Logger
+	scrapers []*scraper
+}
+
+// newExporter creates the scrapers of the targets of the config. connect is replaced in tests.
+func newExporter(cfg *config, connect func(target) (statsReader, error), logger *slog.Logger) (*exporter, error) {
+	exp := &exporter{logger: logger}
+	var errs []error
+	for _, t := range cfg.Targets {
+		reader, err := connect(t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		exp.scrapers = append(exp.scrapers, newScraper(t, reader, logger))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return exp, nil
+}
+
+// serve scrapes the targets and serves the metrics until the context is done.
+func (exp *exporter) serve(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	for _, s := range exp.scrapers {
+		wg.Go(func() { s.run(ctx) })
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           exp.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	exp.logger.Info("listening", "address", addr, "targets", len(exp.scrapers))
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down the server: %w", err)
+	}
+	return nil
+}
+
+func (exp *exporter) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", exp.serveMetrics)
+	mux.HandleFunc("GET /health", exp.serveHealth)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>NGINX Plus Exporter</title></head><body><h1>NGINX Plus Exporter</h1>`+
+			`<p><a href="/metrics">Metrics</a></p><p><a href="/health">Health</a></p></body></html>`)
+	})
+	return mux
+}
+
+func (exp *exporter) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	m := newMetrics()
+	for _, s := range exp.scrapers {
+		s.collect(m)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.write(w); err != nil {
+		exp.logger.Warn("failed to write the metrics", "error", err)
+	}
+}
+
+func (exp *exporter) serveHealth(w http.ResponseWriter, _ *http.Request) {
+	statuses := make([]status, 0, len(exp.scrapers))
+	code := http.StatusOK
+	for _, s := range exp.scrapers {
+		_, st := s.last()
+		if !st.Up {
+			code = http.StatusServiceUnavailable
+		}
+		statuses = append(statuses, st)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(map[string][]status{"targets": statuses}); err != nil {
+		exp.logger.Warn("failed to write the health", "error", err)
+	}
+}
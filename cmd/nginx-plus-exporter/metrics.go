@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+const namespace = "nginxplus"
+
+type metricType string
+
+const (
+	counter metricType = "counter"
+	gauge   metricType = "gauge"
+)
+
+// reservedLabels are the names of the labels set by the exporter, which the labels of a target can't use.
+var reservedLabels = []string{
+	"target", "version", "build", "code", "server_zone", "location_zone", "upstream", "server", "id",
+	"cache", "status", "zone", "resolver", "type",
+}
+
+// label is a label of a sample.
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	labels []label
+	value  float64
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	samples []sample
+}
+
+// metrics collects samples and writes them in the Prometheus text format, grouped by metric family.
+type metrics struct {
+	families map[string]*family
+}
+
+func newMetrics() *metrics {
+	return &metrics{families: make(map[string]*family)}
+}
+
+// add adds a sample. The name is prefixed with the namespace.
+func (m *metrics) add(name string, typ metricType, help string, value float64, labels ...label) {
+	name = namespace + "_" + name
+	f, ok := m.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		m.families[name] = f
+	}
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// write writes the metric families sorted by name.
+func (m *metrics) write(w io.Writer) error {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(m.families)) {
+		f := m.families[name]
+		fmt.Fprintf(&b, "# HELP %v %v\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %v %v\n", f.name, f.typ)
+		for _, s := range f.samples {
+			b.WriteString(f.name)
+			if len(s.labels) > 0 {
+				b.WriteByte('{')
+				for i, l := range s.labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%v=\"%v\"", l.name, escapeLabelValue(l.value))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.value))
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// with returns the labels of the target followed by more labels.
+func with(base []label, more ...label) []label {
+	return append(slices.Clip(base), more...)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// peerStates are the values of the state metric of peers.
+var peerStates = map[string]float64{
+	"up":        1,
+	"draining":  2,
+	"down":      3,
+	"unavail":   4,
+	"checking":  5,
+	"unhealthy": 6,
+}
+
+const peerStateHelp = "Current state of the peer: 1 up, 2 draining, 3 down, 4 unavail, 5 checking, 6 unhealthy"
+
+// collect adds the metrics of the stats of a target.
+func (m *metrics) collect(stats *client.Stats, base []label) {
+	m.add("info", gauge, "Version and build of NGINX", 1, with(base, label{"version", stats.NginxInfo.Version}, label{"build", stats.NginxInfo.Build})...)
+	m.add("generation", gauge, "Number of configuration reloads", float64(stats.NginxInfo.Generation), base...)
+	m.add("connections_accepted_total", counter, "Accepted client connections", float64(stats.Connections.Accepted), base...)
+	m.add("connections_dropped_total", counter, "Dropped client connections", float64(stats.Connections.Dropped), base...)
+	m.add("connections_active", gauge, "Active client connections", float64(stats.Connections.Active), base...)
+	m.add("connections_idle", gauge, "Idle client connections", float64(stats.Connections.Idle), base...)
+	m.add("http_requests_total", counter, "Total HTTP requests", float64(stats.HTTPRequests.Total), base...)
+	m.add("http_requests_current", gauge, "Current HTTP requests", float64(stats.HTTPRequests.Current), base...)
+	m.add("ssl_handshakes_total", counter, "Successful SSL handshakes", float64(stats.SSL.Handshakes), base...)
+	m.add("ssl_handshakes_failed_total", counter, "Failed SSL handshakes", float64(stats.SSL.HandshakesFailed), base...)
+	m.add("ssl_session_reuses_total", counter, "Session reuses during SSL handshakes", float64(stats.SSL.SessionReuses), base...)
+	m.add("processes_respawned_total", counter, "Child processes that terminated abnormally and were respawned", float64(stats.Processes.Respawned), base...)
+
+	m.collectServerZones(stats, base)
+	m.collectUpstreams(stats, base)
+	m.collectStream(stats, base)
+	m.collectCaches(stats, base)
+	m.collectLimits(stats, base)
+	m.collectOthers(stats, base)
+}
+
+func (m *metrics) responses(prefix string, responses client.Responses, labels []label) {
+	for _, r := range []struct {
+		code  string
+		value uint64
+	}{
+		{"1xx", responses.Responses1xx},
+		{"2xx", responses.Responses2xx},
+		{"3xx", responses.Responses3xx},
+		{"4xx", responses.Responses4xx},
+		{"5xx", responses.Responses5xx},
+	} {
+		m.add(prefix+"_responses_total", counter, "Responses by status code class", float64(r.value), with(labels, label{"code", r.code})...)
+	}
+}
+
+func (m *metrics) collectServerZones(stats *client.Stats, base []label) {
+	for _, name := range slices.Sorted(maps.Keys(stats.ServerZones)) {
+		z := stats.ServerZones[name]
+		labels := with(base, label{"server_zone", name})
+		m.add("server_zone_processing", gauge, "Client requests that are being processed", float64(z.Processing), labels...)
+		m.add("server_zone_requests_total", counter, "Client requests received", float64(z.Requests), labels...)
+		m.responses("server_zone", z.Responses, labels)
+		m.add("server_zone_discarded_total", counter, "Requests completed without sending a response", float64(z.Discarded), labels...)
+		m.add("server_zone_received_total", counter, "Bytes received from clients", float64(z.Received), labels...)
+		m.add("server_zone_sent_total", counter, "Bytes sent to clients", float64(z.Sent), labels...)
+	}
+	for _, name := range slices.Sorted(maps.Keys(stats.LocationZones)) {
+		z := stats.LocationZones[name]
+		labels := with(base, label{"location_zone", name})
+		m.add("location_zone_requests_total", counter, "Client requests received", float64(z.Requests), labels...)
+		m.responses("location_zone", z.Responses, labels)
+		m.add("location_zone_discarded_total", counter, "Requests completed without sending a response", float64(z.Discarded), labels...)
+		m.add("location_zone_received_total", counter, "Bytes received from clients", float64(z.Received), labels...)
+		m.add("location_zone_sent_total", counter, "Bytes sent to clients", float64(z.Sent), labels...)
+	}
+}
+
+func (m *metrics) healthChecks(prefix string, hc client.HealthChecks, labels []label) {
+	m.add(prefix+"_health_checks_checks_total", counter, "Health check requests made", float64(hc.Checks), labels...)
+	m.add(prefix+"_health_checks_fails_total", counter, "Failed health checks", float64(hc.Fails), labels...)
+	m.add(prefix+"_health_checks_unhealthy_total", counter, "Times the peer became unhealthy", float64(hc.Unhealthy), labels...)
+}
+
+func (m *metrics) collectUpstreams(stats *client.Stats, base []label) {
+	for _, name := range slices.Sorted(maps.Keys(stats.Upstreams)) {
+		u := stats.Upstreams[name]
+		labels := with(base, label{"upstream", name})
+		m.add("upstream_keepalive", gauge, "Idle keepalive connections", float64(u.Keepalive), labels...)
+		m.add("upstream_zombies", gauge, "Servers removed from the group but still processing active client requests", float64(u.Zombies), labels...)
+		m.add("upstream_queue_size", gauge, "Requests in the queue", float64(u.Queue.Size), labels...)
+		m.add("upstream_queue_overflows_total", counter, "Requests rejected because the queue was full", float64(u.Queue.Overflows), labels...)
+		for _, p := range u.Peers {
+			labels := with(labels, label{"server", p.Server}, label{"id", strconv.Itoa(p.ID)})
+			m.add("upstream_server_state", gauge, peerStateHelp, peerStates[p.State], labels...)
+			m.add("upstream_server_active", gauge, "Active connections", float64(p.Active), labels...)
+			m.add("upstream_server_limit", gauge, "Limit of connections of the server, 0 for no limit", float64(p.MaxConns), labels...)
+			m.add("upstream_server_requests_total", counter, "Client requests forwarded to the server", float64(p.Requests), labels...)
+			m.responses("upstream_server", p.Responses, labels)
+			m.add("upstream_server_sent_total", counter, "Bytes sent to the server", float64(p.Sent), labels...)
+			m.add("upstream_server_received_total", counter, "Bytes received from the server", float64(p.Received), labels...)
+			m.add("upstream_server_fails_total", counter, "Unsuccessful attempts to communicate with the server", float64(p.Fails), labels...)
+			m.add("upstream_server_unavail_total", counter, "Times the server became unavailable because of max_fails", float64(p.Unavail), labels...)
+			m.add("upstream_server_header_time", gauge, "Average time to get the response header from the server in milliseconds", float64(p.HeaderTime), labels...)
+			m.add("upstream_server_response_time", gauge, "Average time to get the full response from the server in milliseconds", float64(p.ResponseTime), labels...)
+			m.healthChecks("upstream_server", p.HealthChecks, labels)
+		}
+	}
+}
+
+func (m *metrics) collectStream(stats *client.Stats, base []label) {
+	for _, name := range slices.Sorted(maps.Keys(stats.StreamServerZones)) {
+		z := stats.StreamServerZones[name]
+		labels := with(base, label{"server_zone", name})
+		m.add("stream_server_zone_processing", gauge, "Client connections that are being processed", float64(z.Processing), labels...)
+		m.add("stream_server_zone_connections_total", counter, "Connections accepted from clients", float64(z.Connections), labels...)
+		for _, s := range []struct {
+			code  string
+			value uint64
+		}{{"2xx", z.Sessions.Sessions2xx}, {"4xx", z.Sessions.Sessions4xx}, {"5xx", z.Sessions.Sessions5xx}} {
+			m.add("stream_server_zone_sessions_total", counter, "Completed sessions by status code class", float64(s.value), with(labels, label{"code", s.code})...)
+		}
+		m.add("stream_server_zone_discarded_total", counter, "Connections completed without creating a session", float64(z.Discarded), labels...)
+		m.add("stream_server_zone_received_total", counter, "Bytes received from clients", float64(z.Received), labels...)
+		m.add("stream_server_zone_sent_total", counter, "Bytes sent to clients", float64(z.Sent), labels...)
+	}
+	for _, name := range slices.Sorted(maps.Keys(stats.StreamUpstreams)) {
+		u := stats.StreamUpstreams[name]
+		labels := with(base, label{"upstream", name})
+		m.add("stream_upstream_zombies", gauge, "Servers removed from the group but still processing active client connections", float64(u.Zombies), labels...)
+		for _, p := range u.Peers {
+			labels := with(labels, label{"server", p.Server}, label{"id", strconv.Itoa(p.ID)})
+			m.add("stream_upstream_server_state", gauge, peerStateHelp, peerStates[p.State], labels...)
+			m.add("stream_upstream_server_active", gauge, "Active connections", float64(p.Active), labels...)
+			m.add("stream_upstream_server_limit", gauge, "Limit of connections of the server, 0 for no limit", float64(p.MaxConns), labels...)
+			m.add("stream_upstream_server_connections_total", counter, "Client connections forwarded to the server", float64(p.Connections), labels...)
+			m.add("stream_upstream_server_connect_time", gauge, "Average time to connect to the server in milliseconds", float64(p.ConnectTime), labels...)
+			m.add("stream_upstream_server_first_byte_time", gauge, "Average time to receive the first byte of data in milliseconds", float64(p.FirstByteTime), labels...)
+			m.add("stream_upstream_server_response_time", gauge, "Average time to receive the last byte of data in milliseconds", float64(p.ResponseTime), labels...)
+			m.add("stream_upstream_server_sent_total", counter, "Bytes sent to the server", float64(p.Sent), labels...)
+			m.add("stream_upstream_server_received_total", counter, "Bytes received from the server", float64(p.Received), labels...)
+			m.add("stream_upstream_server_fails_total", counter, "Unsuccessful attempts to communicate with the server", float64(p.Fails), labels...)
+			m.add("stream_upstream_server_unavail_total", counter, "Times the server became unavailable because of max_fails", float64(p.Unavail), labels...)
+			m.healthChecks("stream_upstream_server", p.HealthChecks, labels)
+		}
+	}
+	if zs := stats.StreamZoneSync; zs != nil {
+		m.add("stream_zone_sync_status_bytes_in_total", counter, "Bytes received by this node", float64(zs.Status.BytesIn), base...)
+		m.add("stream_zone_sync_status_bytes_out_total", counter, "Bytes sent by this node", float64(zs.Status.BytesOut), base...)
+		m.add("stream_zone_sync_status_msgs_in_total", counter, "Messages received by this node", float64(zs.Status.MsgsIn), base...)
+		m.add("stream_zone_sync_status_msgs_out_total", counter, "Messages sent by this node", float64(zs.Status.MsgsOut), base...)
+		m.add("stream_zone_sync_status_nodes_online", gauge, "Peers this node is connected to", float64(zs.Status.NodesOnline), base...)
+		for _, name := range slices.Sorted(maps.Keys(zs.Zones)) {
+			z := zs.Zones[name]
+			labels := with(base, label{"zone", name})
+			m.add("stream_zone_sync_zone_records_pending", gauge, "Records that need to be sent to the cluster", float64(z.RecordsPending), labels...)
+			m.add("stream_zone_sync_zone_records_total", gauge, "Records stored in the shared memory zone", float64(z.RecordsTotal), labels...)
+		}
+	}
+}
+
+func (m *metrics) collectCaches(stats *client.Stats, base []label) {
+	for _, name := range slices.Sorted(maps.Keys(stats.Caches)) {
+		c := stats.Caches[name]
+		labels := with(base, label{"cache", name})
+		m.add("cache_size", gauge, "Current size of the cache in bytes", float64(c.Size), labels...)
+		m.add("cache_max_size", gauge, "Limit on the maximum size of the cache in bytes", float64(c.MaxSize), labels...)
+		m.add("cache_cold", gauge, "Whether the cache loader is still loading data from disk", boolValue(c.Cold), labels...)
+		for _, s := range []struct {
+			status string
+			stats  client.CacheStats
+		}{
+			{"hit", c.Hit},
+			{"stale", c.Stale},
+			{"updating", c.Updating},
+			{"revalidated", c.Revalidated},
+			{"miss", c.Miss},
+			{"expired", c.Expired.CacheStats},
+			{"bypass", c.Bypass.CacheStats},
+		} {
+			labels := with(labels, label{"status", s.status})
+			m.add("cache_responses_total", counter, "Responses by cache status", float64(s.stats.Responses), labels...)
+			m.add("cache_bytes_total", counter, "Bytes of the responses by cache status", float64(s.stats.Bytes), labels...)
+		}
+	}
+}
+
+func (m *metrics) collectLimits(stats *client.Stats, base []label) {
+	for _, name := range slices.Sorted(maps.Keys(stats.HTTPLimitRequests)) {
+		l := stats.HTTPLimitRequests[name]
+		labels := with(base, label{"zone", name})
+		m.add("limit_request_passed_total", counter, "Requests that were neither limited nor accounted as limited", float64(l.Passed), labels...)
+		m.add("limit_request_delayed_total", counter, "Requests that were delayed", float64(l.Delayed), labels...)
+		m.add("limit_request_rejected_total", counter, "Requests that were rejected", float64(l.Rejected), labels...)
+		m.add("limit_request_delayed_dry_run_total", counter, "Requests accounted as delayed in the dry run mode", float64(l.DelayedDryRun), labels...)
+		m.add("limit_request_rejected_dry_run_total", counter, "Requests accounted as rejected in the dry run mode", float64(l.RejectedDryRun), labels...)
+	}
+	for _, limits := range []struct {
+		prefix string
+		zones  map[string]client.LimitConnection
+	}{{"limit_connection", stats.HTTPLimitConnections}, {"stream_limit_connection", stats.StreamLimitConnections}} {
+		for _, name := range slices.Sorted(maps.Keys(limits.zones)) {
+			l := limits.zones[name]
+			labels := with(base, label{"zone", name})
+			m.add(limits.prefix+"_passed_total", counter, "Connections that were neither limited nor accounted as limited", float64(l.Passed), labels...)
+			m.add(limits.prefix+"_rejected_total", counter, "Connections that were rejected", float64(l.Rejected), labels...)
+			m.add(limits.prefix+"_rejected_dry_run_total", counter, "Connections accounted as rejected in the dry run mode", float64(l.RejectedDryRun), labels...)
+		}
+	}
+}
+
+func (m *metrics) collectOthers(stats *client.Stats, base []label) {
+	for _, name := range slices.Sorted(maps.Keys(stats.Slabs)) {
+		s := stats.Slabs[name]
+		labels := with(base, label{"zone", name})
+		m.add("slab_pages_used", gauge, "Used memory pages", float64(s.Pages.Used), labels...)
+		m.add("slab_pages_free", gauge, "Free memory pages", float64(s.Pages.Free), labels...)
+	}
+	for _, name := range slices.Sorted(maps.Keys(stats.Resolvers)) {
+		r := stats.Resolvers[name]
+		labels := with(base, label{"resolver", name})
+		for _, req := range []struct {
+			typ   string
+			value int64
+		}{{"name", r.Requests.Name}, {"srv", r.Requests.Srv}, {"addr", r.Requests.Addr}} {
+			m.add("resolver_requests_total", counter, "Requests to resolve by type", float64(req.value), with(labels, label{"type", req.typ})...)
+		}
+		for _, resp := range []struct {
+			status string
+			value  int64
+		}{
+			{"noerror", r.Responses.Noerror},
+			{"formerr", r.Responses.Formerr},
+			{"servfail", r.Responses.Servfail},
+			{"nxdomain", r.Responses.Nxdomain},
+			{"notimp", r.Responses.Notimp},
+			{"refused", r.Responses.Refused},
+			{"timedout", r.Responses.Timedout},
+			{"unknown", r.Responses.Unknown},
+		} {
+			m.add("resolver_responses_total", counter, "Responses by status", float64(resp.value), with(labels, label{"status", resp.status})...)
+		}
+	}
+}
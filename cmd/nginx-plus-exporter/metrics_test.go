@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	t.Parallel()
+	m := newMetrics()
+	m.add("b", counter, "Help of b", 1.5, label{"target", `a "quoted" \ value`})
+	m.add("a", gauge, "Help\nof a", 2)
+	m.add("b", counter, "Help of b", math.Inf(1), label{"target", "line\nbreak"})
+
+	var b strings.Builder
+	if err := m.write(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `# HELP nginxplus_a Help\nof a
+# TYPE nginxplus_a gauge
+nginxplus_a 2
+# HELP nginxplus_b Help of b
+# TYPE nginxplus_b counter
+nginxplus_b{target="a \"quoted\" \\ value"} 1.5
+nginxplus_b{target="line\nbreak"} +Inf
+`
+	if b.String() != expected {
+		t.Fatalf("expected\n%v\ngot\n%v", expected, b.String())
+	}
+}
+
+var labelNameInSample = regexp.MustCompile(`[{,]([a-zA-Z_][a-zA-Z0-9_]*)="`)
+
+func TestMetricsCollect(t *testing.T) {
+	t.Parallel()
+	stats := &client.Stats{
+		NginxInfo:   client.NginxInfo{Version: "1.27.4", Build: "nginx-plus-r34"},
+		Connections: client.Connections{Accepted: 10, Active: 2},
+		ServerZones: client.ServerZones{"api": {Requests: 100, Responses: client.Responses{Responses5xx: 3}}},
+		Upstreams: client.Upstreams{"backend": {Peers: []client.Peer{
+			{ID: 0, Server: "10.0.0.1:80", State: "up", Requests: 40},
+			{ID: 1, Server: "10.0.0.1:80", State: "up", Requests: 2},
+			{ID: 2, Server: "10.0.0.2:80", State: "unhealthy"},
+		}}},
+		StreamUpstreams: client.StreamUpstreams{"dns": {Peers: []client.StreamPeer{{ID: 3, Server: "10.0.0.3:53", State: "draining"}}}},
+		Caches:          client.Caches{"cache": {Size: 1024, Cold: true, Hit: client.CacheStats{Responses: 7}}},
+		Slabs:           client.Slabs{"backend": {Pages: client.Pages{Used: 3, Free: 5}}},
+		StreamZoneSync: &client.StreamZoneSync{Zones: map[string]client.SyncZone{
+			"sessions": {RecordsPending: 4, RecordsTotal: 9},
+		}},
+		LocationZones:          client.LocationZones{"static": {}},
+		StreamServerZones:      client.StreamServerZones{"dns": {}},
+		Resolvers:              client.Resolvers{"dns": {}},
+		HTTPLimitRequests:      client.HTTPLimitRequests{"req": {}},
+		HTTPLimitConnections:   client.HTTPLimitConnections{"conn": {}},
+		StreamLimitConnections: client.StreamLimitConnections{"conn": {}},
+	}
+	m := newMetrics()
+	m.collect(stats, []label{{"target", "edge"}, {"dc", "eu"}})
+	var b strings.Builder
+	if err := m.write(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, line := range []string{
+		`nginxplus_info{target="edge",dc="eu",version="1.27.4",build="nginx-plus-r34"} 1`,
+		`nginxplus_connections_accepted_total{target="edge",dc="eu"} 10`,
+		`nginxplus_server_zone_responses_total{target="edge",dc="eu",server_zone="api",code="5xx"} 3`,
+		`nginxplus_upstream_server_state{target="edge",dc="eu",upstream="backend",server="10.0.0.1:80",id="0"} 1`,
+		`nginxplus_upstream_server_state{target="edge",dc="eu",upstream="backend",server="10.0.0.2:80",id="2"} 6`,
+		`nginxplus_upstream_server_requests_total{target="edge",dc="eu",upstream="backend",server="10.0.0.1:80",id="0"} 40`,
+		`nginxplus_upstream_server_requests_total{target="edge",dc="eu",upstream="backend",server="10.0.0.1:80",id="1"} 2`,
+		`nginxplus_stream_upstream_server_state{target="edge",dc="eu",upstream="dns",server="10.0.0.3:53",id="3"} 2`,
+		`nginxplus_cache_cold{target="edge",dc="eu",cache="cache"} 1`,
+		`nginxplus_cache_responses_total{target="edge",dc="eu",cache="cache",status="hit"} 7`,
+		`nginxplus_slab_pages_free{target="edge",dc="eu",zone="backend"} 5`,
+		`nginxplus_stream_zone_sync_zone_records_pending{target="edge",dc="eu",zone="sessions"} 4`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("expected the line %v", line)
+		}
+	}
+	if t.Failed() {
+		t.Logf("metrics:\n%v", b.String())
+	}
+	// The labels of the targets can't clash with the labels of the metrics.
+	for _, name := range labelNameInSample.FindAllStringSubmatch(b.String(), -1) {
+		if name[1] != "dc" && !slices.Contains(reservedLabels, name[1]) {
+			t.Errorf("expected the label %v to be reserved", name[1])
+		}
+	}
+	if strings.Count(b.String(), "# TYPE nginxplus_upstream_server_state ") != 1 {
+		t.Fatal("expected the samples of a family to be grouped")
+	}
+	for line := range strings.Lines(b.String()) {
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok && strings.HasSuffix(name, " counter\n") && !strings.HasSuffix(name, "_total counter\n") {
+			t.Errorf("expected the counter %v to end with _total", strings.Fields(name)[0])
+		}
+	}
+}
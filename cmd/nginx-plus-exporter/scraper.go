@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// statsReader is the part of the client used by the scrapers.
+type statsReader interface {
+	GetStats(ctx context.Context) (*client.Stats, error)
+}
+
+// status is the result of the last scrape of a target.
+type status struct {
+	Target     string    `json:"target"`
+	LastScrape time.Time `json:"last_scrape,omitzero"`
+	Error      string    `json:"error,omitempty"`
+	Duration   float64   `json:"duration_seconds"`
+	Up         bool      `json:"up"`
+}
+
+// scraper scrapes the stats of a target in the background and keeps the result of the last scrape.
+type scraper struct {
+	reader statsReader
+	logger *slog.Logger
+	now    func() time.Time
+	stats  *client.Stats
+	status status
+	labels []label
+	target target
+	mu     sync.Mutex
+}
+
+func newScraper(t target, reader statsReader, logger *slog.Logger) *scraper {
+	labels := []label{{"target", t.Name}}
+	for _, name := range slices.Sorted(maps.Keys(t.Labels)) {
+		labels = append(labels, label{name, t.Labels[name]})
+	}
+	return &scraper{
+		target: t,
+		reader: reader,
+		logger: logger.With("target", t.Name),
+		now:    time.Now,
+		labels: labels,
+		status: status{Target: t.Name},
+	}
+}
+
+// run scrapes the target at its interval until the context is done.
+func (s *scraper) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(s.target.ScrapeInterval))
+	defer ticker.Stop()
+	for {
+		s.scrape(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrape gets the stats of the target. The stats of a failed scrape are dropped, so that stale metrics aren't served.
+func (s *scraper) scrape(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.target.ScrapeTimeout))
+	defer cancel()
+
+	start := s.now()
+	stats, err := s.reader.GetStats(ctx)
+	st := status{
+		Target:     s.target.Name,
+		LastScrape: start,
+		Duration:   s.now().Sub(start).Seconds(),
+		Up:         err == nil,
+	}
+	if err != nil {
+		st.Error = err.Error()
+		s.logger.Warn("scrape failed", "error", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = stats
+	s.status = st
+}
+
+// last returns the result of the last scrape.
+func (s *scraper) last() (*client.Stats, status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats, s.status
+}
+
+// collect adds the metrics of the last scrape.
+func (s *scraper) collect(m *metrics) {
+	stats, st := s.last()
+	m.add("up", gauge, "Whether the last scrape of the target succeeded", boolValue(st.Up), s.labels...)
+	if st.LastScrape.IsZero() {
+		return
+	}
+	m.add("scrape_duration_seconds", gauge, "Duration of the last scrape of the target", st.Duration, s.labels...)
+	m.add("last_scrape_timestamp_seconds", gauge, "Unix time of the last scrape of the target", float64(st.LastScrape.UnixMilli())/1000, s.labels...)
+	if stats != nil {
+		m.collect(stats, s.labels)
+	}
+}
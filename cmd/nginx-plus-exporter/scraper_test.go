@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+var errFailed = errors.New("failed")
+
+func newTestExporter(t *testing.T, readers map[string]*clientmock.StatsReader) *exporter {
+	t.Helper()
+	cfg, err := loadConfig(strings.NewReader(`{"targets": [
+		{"name": "edge-1", "api": "http://a/api", "labels": {"dc": "eu"}},
+		{"name": "edge-2", "api": "http://b/api"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, err := newExporter(cfg, func(t target) (statsReader, error) {
+		return readers[t.Name], nil
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exp
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+	up := &clientmock.StatsReader{GetStatsFunc: func(context.Context) (*client.Stats, error) {
+		return &client.Stats{NginxInfo: client.NginxInfo{Version: "1.27.4"}, Connections: client.Connections{Accepted: 5}}, nil
+	}}
+	down := &clientmock.StatsReader{GetStatsFunc: func(context.Context) (*client.Stats, error) {
+		return nil, errFailed
+	}}
+	exp := newTestExporter(t, map[string]*clientmock.StatsReader{"edge-1": up, "edge-2": down})
+	h := exp.handler()
+
+	code, body := get(t, h, "/metrics")
+	if code != http.StatusOK || !strings.Contains(body, `nginxplus_up{target="edge-1",dc="eu"} 0`) ||
+		strings.Contains(body, "scrape_duration_seconds") {
+		t.Fatalf("expected the targets down before the first scrape, got %v:\n%v", code, body)
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, s := range exp.scrapers {
+		s.now = func() time.Time { return start }
+		s.scrape(context.Background())
+	}
+	_, body = get(t, h, "/metrics")
+	for _, line := range []string{
+		`nginxplus_up{target="edge-1",dc="eu"} 1`,
+		`nginxplus_up{target="edge-2"} 0`,
+		`nginxplus_last_scrape_timestamp_seconds{target="edge-2"} 1.7672256e+09`,
+		`nginxplus_connections_accepted_total{target="edge-1",dc="eu"} 5`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected the line %v in\n%v", line, body)
+		}
+	}
+	if strings.Contains(body, `nginxplus_connections_accepted_total{target="edge-2"}`) {
+		t.Errorf("expected no stats of the failed target")
+	}
+
+	code, body = get(t, h, "/health")
+	var health struct {
+		Targets []status `json:"targets"`
+	}
+	if err := json.Unmarshal([]byte(body), &health); err != nil {
+		t.Fatalf("failed to decode the health %v: %v", body, err)
+	}
+	if code != http.StatusServiceUnavailable || len(health.Targets) != 2 || !health.Targets[0].Up ||
+		health.Targets[1].Up || health.Targets[1].Error != errFailed.Error() {
+		t.Fatalf("unexpected health %v: %v", code, body)
+	}
+
+	// The stats of the last successful scrape are dropped when a scrape fails.
+	up.GetStatsFunc = down.GetStatsFunc
+	exp.scrapers[0].scrape(context.Background())
+	if _, body = get(t, h, "/metrics"); strings.Contains(body, "nginxplus_connections_accepted_total") {
+		t.Fatalf("expected no stale stats, got\n%v", body)
+	}
+
+	ok := func(context.Context) (*client.Stats, error) { return &client.Stats{}, nil }
+	up.GetStatsFunc, down.GetStatsFunc = ok, ok
+	for _, s := range exp.scrapers {
+		s.scrape(context.Background())
+	}
+	if code, body = get(t, h, "/health"); code != http.StatusOK {
+		t.Fatalf("expected all the targets up, got %v: %v", code, body)
+	}
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	t.Parallel()
+	reader := &clientmock.StatsReader{GetStatsFunc: func(ctx context.Context) (*client.Stats, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	s := newScraper(target{Name: "slow", ScrapeTimeout: duration(time.Millisecond)}, reader, slog.New(slog.DiscardHandler))
+	s.scrape(context.Background())
+	if _, st := s.last(); st.Up || !strings.Contains(st.Error, context.DeadlineExceeded.Error()) {
+		t.Fatalf("expected the scrape to time out, got %+v", st)
+	}
+}
+
+func TestNewExporterError(t *testing.T) {
+	t.Parallel()
+	cfg := &config{Targets: []target{{Name: "a"}, {Name: "b"}}}
+	_, err := newExporter(cfg, func(target) (statsReader, error) { return nil, errFailed }, slog.New(slog.DiscardHandler))
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected %v, got %v", errFailed, err)
+	}
+}
+
+func TestVersionReader(t *testing.T) {
+	t.Parallel()
+	var negotiations int
+	var version int
+	r := &versionReader{
+		negotiate: func(context.Context) (int, error) {
+			negotiations++
+			if negotiations == 1 {
+				return 0, errFailed
+			}
+			return 9, nil
+		},
+		connect: func(v int) (statsReader, error) {
+			version = v
+			return &clientmock.StatsReader{GetStatsFunc: func(context.Context) (*client.Stats, error) {
+				return &client.Stats{}, nil
+			}}, nil
+		},
+		logger: slog.New(slog.DiscardHandler),
+	}
+
+	ctx := context.Background()
+	if _, err := r.GetStats(ctx); !errors.Is(err, errFailed) {
+		t.Fatalf("expected the scrape to fail while the version can't be negotiated, got %v", err)
+	}
+	for range 2 {
+		if _, err := r.GetStats(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if negotiations != 2 || version != 9 {
+		t.Fatalf("expected the version to be negotiated again and kept, got %v negotiations of version %v", negotiations, version)
+	}
+}
+
+func TestConnectPasswordFile(t *testing.T) {
+	t.Parallel()
+	passwords := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		select {
+		case passwords <- password:
+		default:
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte(" secret \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := connect(target{Name: "a", API: ts.URL, APIVersion: 9, Username: "user", PasswordFile: file, ScrapeTimeout: duration(time.Second)},
+		slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = reader.GetStats(context.Background())
+	if password := <-passwords; password != "secret" {
+		t.Fatalf("expected the password without spaces, got %q", password)
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// checkState is the state of a check, which is also its exit code, following the convention of monitoring plugins.
+type checkState int
+
+const (
+	stateOK checkState = iota
+	stateWarning
+	stateCritical
+	stateUnknown
+)
+
+func (s checkState) String() string {
+	switch s {
+	case stateOK:
+		return "OK"
+	case stateWarning:
+		return "WARNING"
+	case stateCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// exitError ends the command line with an exit code, after the command printed its result.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit code %v", e.code)
+}
+
+// limit is an optional threshold of a check.
+type limit struct {
+	value float64
+	set   bool
+}
+
+func (l *limit) String() string {
+	if !l.set {
+		return ""
+	}
+	return strconv.FormatFloat(l.value, 'f', -1, 64)
+}
+
+func (l *limit) Set(s string) error {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return errors.New("invalid number")
+	}
+	l.value, l.set = v, true
+	return nil
+}
+
+// thresholds are the warning and critical thresholds of a check. The state is a problem if the value is above
+// the thresholds or, for checks of minimums, below them.
+type thresholds struct {
+	warning  limit
+	critical limit
+	below    bool
+}
+
+func newThresholds(below bool, warning, critical float64) *thresholds {
+	return &thresholds{
+		warning:  limit{value: warning, set: true},
+		critical: limit{value: critical, set: true},
+		below:    below,
+	}
+}
+
+// register registers the -warning and -critical flags, described by what the value is.
+func (t *thresholds) register(fs *flag.FlagSet, value string) {
+	direction := "above"
+	if t.below {
+		direction = "below"
+	}
+	fs.Var(&t.warning, "warning", fmt.Sprintf("warning if %v is %v this", value, direction))
+	fs.Var(&t.critical, "critical", fmt.Sprintf("critical if %v is %v this", value, direction))
+}
+
+func (t *thresholds) exceeded(l limit, v float64) bool {
+	if !l.set {
+		return false
+	}
+	if t.below {
+		return v < l.value
+	}
+	return v > l.value
+}
+
+// state returns the state of the value and the threshold it exceeds.
+func (t *thresholds) state(v float64) (checkState, limit) {
+	switch {
+	case t.exceeded(t.critical, v):
+		return stateCritical, t.critical
+	case t.exceeded(t.warning, v):
+		return stateWarning, t.warning
+	default:
+		return stateOK, limit{}
+	}
+}
+
+// perfRange formats a threshold as a range of the perfdata, which alerts outside of it.
+func (t *thresholds) perfRange(l limit) string {
+	if !l.set {
+		return ""
+	}
+	if t.below {
+		return l.String() + ":"
+	}
+	return l.String()
+}
+
+// checkResult collects the values of a check and prints them as a monitoring plugin:
+//
+//	CRITICAL - backend: 0 of 2 peers healthy (< 1) | 'backend'=0;;1:;0;2
+type checkResult struct {
+	problems   map[checkState][]string
+	perfdata   []string
+	thresholds *thresholds
+	unit       string
+	values     int
+	state      checkState
+}
+
+func newCheckResult(t *thresholds, unit string) *checkResult {
+	return &checkResult{problems: make(map[checkState][]string), thresholds: t, unit: unit}
+}
+
+// add adds a value. max is the maximum of the value, or NaN if it has none.
+func (r *checkResult) add(name, description string, value, maxValue float64) {
+	r.values++
+	state, l := r.thresholds.state(value)
+	if state != stateOK {
+		sign := ">"
+		if r.thresholds.below {
+			sign = "<"
+		}
+		r.problem(state, fmt.Sprintf("%v: %v (%v %v%v)", name, description, sign, l.String(), r.unit))
+	}
+	maxText := ""
+	if !math.IsNaN(maxValue) {
+		maxText = perfNumber(maxValue)
+	}
+	r.perfdata = append(r.perfdata, fmt.Sprintf("'%v'=%v%v;%v;%v;0;%v",
+		strings.ReplaceAll(name, "'", "''"), perfNumber(value), r.unit,
+		r.thresholds.perfRange(r.thresholds.warning), r.thresholds.perfRange(r.thresholds.critical), maxText))
+}
+
+// problem adds a problem that isn't a value.
+func (r *checkResult) problem(state checkState, message string) {
+	r.problems[state] = append(r.problems[state], message)
+	r.state = max(r.state, state)
+}
+
+// message returns the output of the check: the problems, starting with the worst, or the summary if there are none.
+func (r *checkResult) message(summary string) string {
+	var b strings.Builder
+	b.WriteString(r.state.String())
+	b.WriteString(" - ")
+	if r.state == stateOK {
+		b.WriteString(summary)
+	} else {
+		var problems []string
+		for _, state := range []checkState{stateUnknown, stateCritical, stateWarning} {
+			problems = append(problems, r.problems[state]...)
+		}
+		b.WriteString(strings.Join(problems, ", "))
+	}
+	if len(r.perfdata) > 0 {
+		b.WriteString(" | ")
+		b.WriteString(strings.Join(r.perfdata, " "))
+	}
+	return b.String()
+}
+
+// perfNumber formats a value of a check with at most two decimals.
+func perfNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// runCheck evaluates thresholds against the live stats of NGINX Plus and prints the result as a monitoring plugin,
+// exiting with 0 for OK, 1 for WARNING, 2 for CRITICAL and 3 for UNKNOWN. Errors of the check are UNKNOWN.
+func runCheck(ctx context.Context, c *cli, args []string) error {
+	err := check(ctx, c, args)
+	var exit *exitError
+	if err == nil || errors.Is(err, flag.ErrHelp) || errors.As(err, &exit) {
+		return err
+	}
+	fmt.Fprintf(c.out.w, "%v - %v\n", stateUnknown, err)
+	return &exitError{code: int(stateUnknown)}
+}
+
+func check(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: check: missing check", errUsage)
+	}
+	name, args := "check "+args[0], args[1:]
+	var (
+		result  *checkResult
+		summary string
+		err     error
+	)
+	switch name {
+	case "check upstream":
+		result, summary, err = checkUpstreams(ctx, c, name, args)
+	case "check 5xx":
+		result, summary, err = checkErrors(ctx, c, name, args)
+	case "check slabs":
+		result, summary, err = checkSlabs(ctx, c, name, args)
+	case "check license":
+		result, summary, err = checkLicense(ctx, c, name, args)
+	case "check zone-sync":
+		result, summary, err = checkZoneSync(ctx, c, name, args)
+	default:
+		return fmt.Errorf("%w: %v: unknown check", errUsage, name)
+	}
+	if err != nil {
+		return err
+	}
+	if result.values == 0 && result.state == stateOK {
+		result.problem(stateUnknown, "nothing to check")
+	}
+	fmt.Fprintln(c.out.w, result.message(summary))
+	if result.state != stateOK {
+		return &exitError{code: int(result.state)}
+	}
+	return nil
+}
+
+// selectZones returns the sorted names of the zones, or only the zone if it is set.
+func selectZones[V any](zones map[string]V, zone string) ([]string, error) {
+	if zone == "" {
+		return slices.Sorted(maps.Keys(zones)), nil
+	}
+	if _, ok := zones[zone]; !ok {
+		return nil, fmt.Errorf("%q not found", zone)
+	}
+	return []string{zone}, nil
+}
+
+// checkUpstreams checks the minimum number of healthy peers of upstreams.
+func checkUpstreams(ctx context.Context, c *cli, name string, args []string) (*checkResult, string, error) {
+	fs := c.newFlagSet(name)
+	stream := fs.Bool("stream", false, "check stream upstreams")
+	upstream := fs.String("upstream", "", "upstream to check, all upstreams if it is empty")
+	t := &thresholds{critical: limit{value: 1, set: true}, below: true}
+	t.register(fs, "the number of healthy peers")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return nil, "", err
+	}
+
+	// Only peers that are up take new requests, unlike draining, down and unhealthy peers.
+	states := make(map[string][]string)
+	if *stream {
+		upstreams, err := c.client.GetStreamUpstreams(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		for name, u := range *upstreams {
+			for _, p := range u.Peers {
+				states[name] = append(states[name], p.State)
+			}
+		}
+	} else {
+		upstreams, err := c.client.GetUpstreams(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		for name, u := range *upstreams {
+			for _, p := range u.Peers {
+				states[name] = append(states[name], p.State)
+			}
+		}
+	}
+
+	names, err := selectZones(states, *upstream)
+	if err != nil {
+		return nil, "", fmt.Errorf("upstream %w", err)
+	}
+	result := newCheckResult(t, "")
+	for _, name := range names {
+		up := 0
+		for _, state := range states[name] {
+			if state == "up" {
+				up++
+			}
+		}
+		total := len(states[name])
+		result.add(name, fmt.Sprintf("%v of %v peers healthy", up, total), float64(up), float64(total))
+	}
+	return result, fmt.Sprintf("%v upstreams have enough healthy peers", len(names)), nil
+}
+
+// checkErrors checks the ratio of 5xx responses of server zones, since the start of NGINX or over an interval.
+func checkErrors(ctx context.Context, c *cli, name string, args []string) (*checkResult, string, error) {
+	fs := c.newFlagSet(name)
+	zone := fs.String("zone", "", "server zone to check, all server zones if it is empty")
+	interval := fs.Duration("interval", 0, "measure the ratio over this interval rather than since the start of NGINX")
+	t := newThresholds(false, 5, 10)
+	t.register(fs, "the percentage of 5xx responses")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return nil, "", err
+	}
+	if *interval < 0 {
+		return nil, "", fmt.Errorf("%w: %v: the interval can't be negative", errUsage, name)
+	}
+
+	var prev client.ServerZones
+	if *interval > 0 {
+		zones, err := c.client.GetServerZones(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		prev = *zones
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-time.After(*interval):
+		}
+	}
+	zones, err := c.client.GetServerZones(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	names, err := selectZones(*zones, *zone)
+	if err != nil {
+		return nil, "", fmt.Errorf("server zone %w", err)
+	}
+
+	result := newCheckResult(t, "%")
+	for _, name := range names {
+		cur := (*zones)[name].Responses
+		total, failed := responsesTotal(cur), cur.Responses5xx
+		// The counters of a zone are reset by a reload that changes it, and then the ratio is since the reload.
+		if before, ok := prev[name]; ok && responsesTotal(before.Responses) <= total && before.Responses.Responses5xx <= failed {
+			total -= responsesTotal(before.Responses)
+			failed -= before.Responses.Responses5xx
+		}
+		ratio := 0.0
+		if total > 0 {
+			ratio = float64(failed) / float64(total) * 100
+		}
+		result.add(name, fmt.Sprintf("%v%% 5xx of %v responses", perfNumber(ratio), total), ratio, 100)
+	}
+	return result, fmt.Sprintf("5xx ratio of %v server zones is fine", len(names)), nil
+}
+
+// responsesTotal returns the total of the responses, which older API versions don't report.
+func responsesTotal(r client.Responses) uint64 {
+	if r.Total > 0 {
+		return r.Total
+	}
+	return r.Responses1xx + r.Responses2xx + r.Responses3xx + r.Responses4xx + r.Responses5xx
+}
+
+// checkSlabs checks the utilization of the pages of shared memory zones.
+func checkSlabs(ctx context.Context, c *cli, name string, args []string) (*checkResult, string, error) {
+	fs := c.newFlagSet(name)
+	zone := fs.String("zone", "", "shared memory zone to check, all zones if it is empty")
+	t := newThresholds(false, 80, 90)
+	t.register(fs, "the percentage of used pages")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return nil, "", err
+	}
+
+	slabs, err := c.client.GetSlabs(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	names, err := selectZones(*slabs, *zone)
+	if err != nil {
+		return nil, "", fmt.Errorf("shared memory zone %w", err)
+	}
+	result := newCheckResult(t, "%")
+	for _, name := range names {
+		pages := (*slabs)[name].Pages
+		used := 0.0
+		if total := pages.Used + pages.Free; total > 0 {
+			used = float64(pages.Used) / float64(total) * 100
+		}
+		result.add(name, fmt.Sprintf("%v%% of %v pages used", perfNumber(used), pages.Used+pages.Free), used, 100)
+	}
+	return result, fmt.Sprintf("%v shared memory zones have free pages", len(names)), nil
+}
+
+// checkLicense checks the days remaining of the license and the health of the usage reporting.
+func checkLicense(ctx context.Context, c *cli, name string, args []string) (*checkResult, string, error) {
+	fs := c.newFlagSet(name)
+	t := newThresholds(true, 30, 7)
+	t.register(fs, "the number of days remaining")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return nil, "", err
+	}
+
+	license, err := c.client.GetNginxLicense(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	activeTill := time.Unix(int64(license.ActiveTill), 0).UTC()
+	days := math.Floor(time.Until(activeTill).Hours() / 24)
+	result := newCheckResult(t, "")
+	result.add("license", fmt.Sprintf("%v days remaining until %v", days, activeTill.Format(time.DateOnly)), days, math.NaN())
+	if r := license.Reporting; r != nil && !r.Healthy {
+		result.problem(stateWarning, fmt.Sprintf("usage reporting: unhealthy, %v fails", r.Fails))
+	}
+	return result, fmt.Sprintf("license active until %v, %v days remaining", activeTill.Format(time.DateOnly), days), nil
+}
+
+// checkZoneSync checks the records of shared memory zones that are pending synchronization with the cluster.
+func checkZoneSync(ctx context.Context, c *cli, name string, args []string) (*checkResult, string, error) {
+	fs := c.newFlagSet(name)
+	zone := fs.String("zone", "", "synchronized zone to check, all zones if it is empty")
+	t := newThresholds(false, 100, 1000)
+	t.register(fs, "the number of pending records")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return nil, "", err
+	}
+
+	zoneSync, err := c.client.GetStreamZoneSync(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if zoneSync == nil {
+		return nil, "", errors.New("zone_sync is not configured")
+	}
+	names, err := selectZones(zoneSync.Zones, *zone)
+	if err != nil {
+		return nil, "", fmt.Errorf("synchronized zone %w", err)
+	}
+	result := newCheckResult(t, "")
+	for _, name := range names {
+		z := zoneSync.Zones[name]
+		result.add(name, fmt.Sprintf("%v of %v records pending", z.RecordsPending, z.RecordsTotal), float64(z.RecordsPending), float64(z.RecordsTotal))
+	}
+	return result, fmt.Sprintf("%v synchronized zones are in sync, %v nodes online", len(names), zoneSync.Status.NodesOnline), nil
+}
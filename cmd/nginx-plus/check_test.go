@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+func checkMock() *clientmock.Client {
+	mock := &clientmock.Client{}
+	mock.GetUpstreamsFunc = func(context.Context) (*client.Upstreams, error) {
+		return &client.Upstreams{
+			"backend": {Peers: []client.Peer{{State: "up"}, {State: "up"}, {State: "draining"}}},
+			"api":     {Peers: []client.Peer{{State: "unhealthy"}, {State: "down"}}},
+		}, nil
+	}
+	mock.GetStreamUpstreamsFunc = func(context.Context) (*client.StreamUpstreams, error) {
+		return &client.StreamUpstreams{"dns": {Peers: []client.StreamPeer{{State: "up"}}}}, nil
+	}
+	mock.GetServerZonesFunc = func(context.Context) (*client.ServerZones, error) {
+		return &client.ServerZones{
+			"web": {Responses: client.Responses{Responses2xx: 97, Responses5xx: 3, Total: 100}},
+			"api": {Responses: client.Responses{Responses2xx: 8, Responses5xx: 2}},
+		}, nil
+	}
+	mock.GetSlabsFunc = func(context.Context) (*client.Slabs, error) {
+		return &client.Slabs{"backend": {Pages: client.Pages{Used: 85, Free: 15}}}, nil
+	}
+	mock.GetStreamZoneSyncFunc = func(context.Context) (*client.StreamZoneSync, error) {
+		return &client.StreamZoneSync{
+			Zones:  map[string]client.SyncZone{"sessions": {RecordsPending: 5, RecordsTotal: 50}},
+			Status: client.StreamZoneSyncStatus{NodesOnline: 2},
+		}, nil
+	}
+	mock.GetNginxLicenseFunc = func(context.Context) (*client.NginxLicense, error) {
+		return &client.NginxLicense{
+			ActiveTill: uint64(time.Now().Add(20*24*time.Hour + time.Hour).Unix()),
+			Reporting:  &client.LicenseReporting{Healthy: true},
+		}, nil
+	}
+	return mock
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		output string
+		args   []string
+		code   int
+	}{
+		{
+			name:   "upstreams",
+			args:   []string{"check", "upstream"},
+			code:   2,
+			output: "CRITICAL - api: 0 of 2 peers healthy (< 1) | 'api'=0;;1:;0;2 'backend'=2;;1:;0;3\n",
+		},
+		{
+			name:   "upstream",
+			args:   []string{"check", "upstream", "-upstream", "backend", "-warning", "3"},
+			code:   1,
+			output: "WARNING - backend: 2 of 3 peers healthy (< 3) | 'backend'=2;3:;1:;0;3\n",
+		},
+		{
+			name:   "stream upstreams",
+			args:   []string{"check", "upstream", "-stream"},
+			code:   0,
+			output: "OK - 1 upstreams have enough healthy peers | 'dns'=1;;1:;0;1\n",
+		},
+		{
+			name:   "missing upstream",
+			args:   []string{"check", "upstream", "-upstream", "foo"},
+			code:   3,
+			output: "UNKNOWN - upstream \"foo\" not found\n",
+		},
+		{
+			name:   "5xx",
+			args:   []string{"check", "5xx", "-warning", "2.5"},
+			code:   2,
+			output: "CRITICAL - api: 20% 5xx of 10 responses (> 10%), web: 3% 5xx of 100 responses (> 2.5%) | 'api'=20%;2.5;10;0;100 'web'=3%;2.5;10;0;100\n",
+		},
+		{
+			name:   "slabs",
+			args:   []string{"check", "slabs"},
+			code:   1,
+			output: "WARNING - backend: 85% of 100 pages used (> 80%) | 'backend'=85%;80;90;0;100\n",
+		},
+		{
+			name:   "license",
+			args:   []string{"check", "license"},
+			code:   1,
+			output: "WARNING - license: 20 days remaining until ",
+		},
+		{
+			name:   "license ok",
+			args:   []string{"check", "license", "-warning", "10"},
+			code:   0,
+			output: "OK - license active until ",
+		},
+		{
+			name:   "zone sync",
+			args:   []string{"check", "zone-sync", "-zone", "sessions"},
+			code:   0,
+			output: "OK - 1 synchronized zones are in sync, 2 nodes online | 'sessions'=5;100;1000;0;50\n",
+		},
+		{
+			name:   "unknown check",
+			args:   []string{"check", "foo"},
+			code:   3,
+			output: "UNKNOWN - invalid usage: check foo: unknown check\n",
+		},
+		{
+			name:   "invalid threshold",
+			args:   []string{"check", "slabs", "-warning", "lots"},
+			code:   3,
+			output: "UNKNOWN - invalid value \"lots\" for flag -warning: invalid number\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			stdout, stderr, code := runCLI(t, checkMock(), "", test.args...)
+			if code != test.code {
+				t.Fatalf("expected exit code %v, got %v: %v%v", test.code, code, stdout, stderr)
+			}
+			if !strings.HasPrefix(stdout, test.output) {
+				t.Fatalf("expected output starting with %q, got %q", test.output, stdout)
+			}
+		})
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	t.Parallel()
+	mock := checkMock()
+	mock.GetSlabsFunc = func(context.Context) (*client.Slabs, error) {
+		return nil, errFailed
+	}
+	mock.GetStreamZoneSyncFunc = func(context.Context) (*client.StreamZoneSync, error) {
+		return nil, nil
+	}
+	mock.GetNginxLicenseFunc = func(context.Context) (*client.NginxLicense, error) {
+		return &client.NginxLicense{
+			ActiveTill: uint64(time.Now().Add(100 * 24 * time.Hour).Unix()),
+			Reporting:  &client.LicenseReporting{Fails: 3},
+		}, nil
+	}
+
+	tests := []struct {
+		output string
+		args   []string
+		code   int
+	}{
+		{args: []string{"check", "slabs"}, code: 3, output: "UNKNOWN - failed\n"},
+		{args: []string{"check", "zone-sync"}, code: 3, output: "UNKNOWN - zone_sync is not configured\n"},
+		{args: []string{"check", "license"}, code: 1, output: "WARNING - usage reporting: unhealthy, 3 fails | 'license'="},
+	}
+	for _, test := range tests {
+		stdout, _, code := runCLI(t, mock, "", test.args...)
+		if code != test.code || !strings.HasPrefix(stdout, test.output) {
+			t.Errorf("%v: expected exit code %v and output starting with %q, got %v and %q", test.args, test.code, test.output, code, stdout)
+		}
+	}
+}
+
+func TestCheck5xxInterval(t *testing.T) {
+	t.Parallel()
+	mock := &clientmock.Client{}
+	responses := []client.Responses{
+		{Responses2xx: 1000, Responses5xx: 100, Total: 1100},
+		{Responses2xx: 1099, Responses5xx: 101, Total: 1200},
+	}
+	mock.GetServerZonesFunc = func(context.Context) (*client.ServerZones, error) {
+		r := responses[0]
+		responses = responses[1:]
+		return &client.ServerZones{"web": {Responses: r}}, nil
+	}
+	stdout, _, code := runCLI(t, mock, "", "check", "5xx", "-interval", "1ms")
+	if code != 0 || stdout != "OK - 5xx ratio of 1 server zones is fine | 'web'=1%;5;10;0;100\n" {
+		t.Fatalf("expected the ratio over the interval, got %v: %q", code, stdout)
+	}
+}
+
+func TestCheckSetupErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		env    map[string]string
+		output string
+		args   []string
+	}{
+		{
+			args:   []string{"-api-version", "99", "check", "upstream"},
+			output: "UNKNOWN - failed to create the client: ",
+		},
+		{
+			args:   []string{"-foo", "check", "upstream"},
+			output: "UNKNOWN - flag provided but not defined: -foo\n",
+		},
+		{
+			args:   []string{"-o", "yaml", "check", "upstream"},
+			output: "UNKNOWN - unknown output format \"yaml\"\n",
+		},
+		{
+			args:   []string{"-username", "user", "-token", "token", "check", "upstream"},
+			output: "UNKNOWN - ",
+		},
+		{
+			args:   []string{"check", "upstream"},
+			env:    map[string]string{"NGINX_PLUS_API_VERSION": "nine"},
+			output: "UNKNOWN - invalid NGINX_PLUS_API_VERSION \"nine\": ",
+		},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), test.args, &env{
+			stdin:   strings.NewReader(""),
+			stdout:  &stdout,
+			stderr:  &stderr,
+			getenv:  func(name string) string { return test.env[name] },
+			connect: connect,
+		})
+		if code != 3 || !strings.HasPrefix(stdout.String(), test.output) {
+			t.Errorf("%v: expected exit code 3 and output starting with %q, got %v and %q", test.args, test.output, code, stdout.String())
+		}
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		expected string
+		args     []string
+	}{
+		{args: []string{"check", "upstream"}, expected: "check"},
+		{args: []string{"-api", "http://127.0.0.1/api", "-o=json", "check"}, expected: "check"},
+		{args: []string{"--api-version", "9", "--", "info"}, expected: "info"},
+		{args: []string{"-foo", "check"}, expected: "check"},
+		{args: []string{"-timeout"}, expected: ""},
+	}
+	for _, test := range tests {
+		fs := flag.NewFlagSet("nginx-plus", flag.ContinueOnError)
+		var conn connection
+		conn.register(fs)
+		fs.String("o", "table", "")
+		if name := commandName(fs, test.args); name != test.expected {
+			t.Errorf("%v: expected the command %q, got %q", test.args, test.expected, name)
+		}
+	}
+}
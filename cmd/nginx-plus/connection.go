@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+const defaultEndpoint = "http://127.0.0.1:8080/api"
+
+// connection is how to connect to the NGINX Plus API.
+type connection struct {
+	endpoint   string
+	username   string
+	password   string
+	token      string
+	tokenFile  string
+	apiVersion int
+	timeout    time.Duration
+}
+
+// register registers the flags of the connection.
+func (conn *connection) register(fs *flag.FlagSet) {
+	fs.StringVar(&conn.endpoint, "api", defaultEndpoint, "API endpoint, for example http://127.0.0.1:8080/api or unix:///run/nginx-api.sock:/api (env NGINX_PLUS_API)")
+	fs.StringVar(&conn.username, "username", "", "username for basic authentication (env NGINX_PLUS_USERNAME)")
+	fs.StringVar(&conn.password, "password", "", "password for basic authentication, prefer the environment variable (env NGINX_PLUS_PASSWORD)")
+	fs.StringVar(&conn.token, "token", "", "bearer token, prefer the environment variable (env NGINX_PLUS_TOKEN)")
+	fs.StringVar(&conn.tokenFile, "token-file", "", "file with the bearer token (env NGINX_PLUS_TOKEN_FILE)")
+	fs.IntVar(&conn.apiVersion, "api-version", 0, "API version, 0 for the highest version supported by NGINX Plus and the client (env NGINX_PLUS_API_VERSION)")
+	fs.DurationVar(&conn.timeout, "timeout", 10*time.Second, "timeout of each request to the API")
+}
+
+// loadEnv sets the connection from the environment variables of the flags that were not set.
+// The environment isn't used as the default of the flags, so that secrets don't appear in the usage.
+func (conn *connection) loadEnv(fs *flag.FlagSet, getenv func(string) string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, v := range []struct {
+		value *string
+		flag  string
+		env   string
+	}{
+		{&conn.endpoint, "api", "NGINX_PLUS_API"},
+		{&conn.username, "username", "NGINX_PLUS_USERNAME"},
+		{&conn.password, "password", "NGINX_PLUS_PASSWORD"},
+		{&conn.token, "token", "NGINX_PLUS_TOKEN"},
+		{&conn.tokenFile, "token-file", "NGINX_PLUS_TOKEN_FILE"},
+	} {
+		if env := getenv(v.env); env != "" && !set[v.flag] {
+			*v.value = env
+		}
+	}
+	if env := getenv("NGINX_PLUS_API_VERSION"); env != "" && !set["api-version"] {
+		version, err := strconv.Atoi(env)
+		if err != nil {
+			return fmt.Errorf("invalid NGINX_PLUS_API_VERSION %q: %w", env, err)
+		}
+		conn.apiVersion = version
+	}
+	return nil
+}
+
+// validate checks that at most one kind of authentication is set.
+func (conn *connection) validate() error {
+	kinds := 0
+	for _, set := range []bool{conn.username != "" || conn.password != "", conn.token != "", conn.tokenFile != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return errors.New("only one of -username and -password, -token and -token-file can be set")
+	}
+	return nil
+}
+
+// connect creates the client of the API.
+func connect(conn connection) (client.Client, error) {
+	opts := []client.Option{client.WithHTTPClient(&http.Client{Timeout: conn.timeout})}
+	if conn.apiVersion == 0 {
+		opts = append(opts, client.WithMaxAPIVersion())
+	} else {
+		opts = append(opts, client.WithAPIVersion(conn.apiVersion))
+	}
+	switch {
+	case conn.username != "" || conn.password != "":
+		opts = append(opts, client.WithAuthenticator(client.BasicAuth(conn.username, conn.password)))
+	case conn.token != "":
+		opts = append(opts, client.WithAuthenticator(client.BearerToken(client.StaticToken(conn.token))))
+	case conn.tokenFile != "":
+		opts = append(opts, client.WithAuthenticator(client.BearerToken(client.NewFileTokenSource(conn.tokenFile))))
+	}
+
+	c, err := client.NewNginxClient(conn.endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the client: %w", err)
+	}
+	return c, nil
+}
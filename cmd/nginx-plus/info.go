@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// runInfo prints the version and build of NGINX and the API version in use.
+func runInfo(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("info")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	info, err := c.client.GetNginxInfo(ctx)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		*client.NginxInfo
+		APIVersion int `json:"api_version"`
+	}{NginxInfo: info, APIVersion: c.client.Version()}
+
+	return c.out.print(result, []string{"FIELD", "VALUE"}, [][]string{
+		{"Version", info.Version},
+		{"Build", info.Build},
+		{"Address", info.Address},
+		{"Generation", strconv.FormatUint(info.Generation, 10)},
+		{"Load timestamp", info.LoadTimestamp},
+		{"Timestamp", info.Timestamp},
+		{"PID", strconv.FormatUint(info.ProcessID, 10)},
+		{"Parent PID", strconv.FormatUint(info.ParentProcessID, 10)},
+		{"API version", strconv.Itoa(c.client.Version())},
+	})
+}
+
+// runLicense prints the license of NGINX Plus.
+func runLicense(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("license")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	license, err := c.client.GetNginxLicense(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{
+		{"Active till", time.Unix(int64(license.ActiveTill), 0).UTC().Format(time.RFC3339)},
+		{"Eval", yesNo(license.Eval)},
+	}
+	if r := license.Reporting; r != nil {
+		rows = append(rows,
+			[]string{"Reporting healthy", yesNo(r.Healthy)},
+			[]string{"Reporting fails", strconv.FormatUint(r.Fails, 10)},
+			[]string{"Reporting grace", strconv.FormatUint(r.Grace, 10)},
+		)
+	}
+	return c.out.print(license, []string{"FIELD", "VALUE"}, rows)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/state"
+)
+
+// keyValAPI holds the methods of the client for one kind of keyval zone.
+type keyValAPI struct {
+	get       func(ctx context.Context, zone string) (client.KeyValPairs, error)
+	getAll    func(ctx context.Context) (client.KeyValPairsByZone, error)
+	add       func(ctx context.Context, zone string, key string, val string) error
+	modify    func(ctx context.Context, zone string, key string, val string) error
+	delete    func(ctx context.Context, zone string, key string) error
+	deleteAll func(ctx context.Context, zone string) error
+	name      string
+	stream    bool
+}
+
+func httpKeyVals(c client.Client) keyValAPI {
+	return keyValAPI{
+		get:       c.GetKeyValPairs,
+		getAll:    c.GetAllKeyValPairs,
+		add:       c.AddKeyValPair,
+		modify:    c.ModifyKeyValPair,
+		delete:    c.DeleteKeyValuePair,
+		deleteAll: c.DeleteKeyValPairs,
+		name:      "keyval",
+	}
+}
+
+func streamKeyVals(c client.Client) keyValAPI {
+	return keyValAPI{
+		get:       c.GetStreamKeyValPairs,
+		getAll:    c.GetAllStreamKeyValPairs,
+		add:       c.AddStreamKeyValPair,
+		modify:    c.ModifyStreamKeyValPair,
+		delete:    c.DeleteStreamKeyValuePair,
+		deleteAll: c.DeleteStreamKeyValPairs,
+		name:      "stream-keyval",
+		stream:    true,
+	}
+}
+
+func runKeyVal(ctx context.Context, c *cli, api keyValAPI, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: %v: missing subcommand", errUsage, api.name)
+	}
+	subcommand, args := args[0], args[1:]
+	name := api.name + " " + subcommand
+	switch subcommand {
+	case "get":
+		return getKeyVals(ctx, c, api, name, args)
+	case "set":
+		return setKeyVal(ctx, c, api, name, args)
+	case "delete":
+		return deleteKeyVal(ctx, c, api, name, args)
+	case "import":
+		return importKeyVals(ctx, c, api, name, args)
+	default:
+		return fmt.Errorf("%w: %v: unknown subcommand", errUsage, name)
+	}
+}
+
+// getKeyVals prints the pairs of all zones, of a zone or one pair.
+func getKeyVals(ctx context.Context, c *cli, api keyValAPI, name string, args []string) error {
+	fs := c.newFlagSet(name)
+	args, err := parseArgs(fs, args, 0, 2)
+	if err != nil {
+		return err
+	}
+
+	var zones client.KeyValPairsByZone
+	if len(args) == 0 {
+		zones, err = api.getAll(ctx)
+	} else {
+		var pairs client.KeyValPairs
+		pairs, err = api.get(ctx, args[0])
+		zones = client.KeyValPairsByZone{args[0]: pairs}
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		zone, key := args[0], args[1]
+		val, ok := zones[zone][key]
+		if !ok {
+			return fmt.Errorf("key %v not found in zone %v", key, zone)
+		}
+		return c.out.print(client.KeyValPairs{key: val}, []string{"KEY", "VALUE"}, [][]string{{key, val}})
+	}
+
+	var rows [][]string
+	for _, zone := range slices.Sorted(maps.Keys(zones)) {
+		for _, key := range slices.Sorted(maps.Keys(zones[zone])) {
+			rows = append(rows, []string{zone, key, zones[zone][key]})
+		}
+	}
+	if len(args) == 1 {
+		pairs := zones[args[0]]
+		if pairs == nil {
+			pairs = client.KeyValPairs{}
+		}
+		return c.out.print(pairs, []string{"ZONE", "KEY", "VALUE"}, rows)
+	}
+	return c.out.print(zones, []string{"ZONE", "KEY", "VALUE"}, rows)
+}
+
+// setKeyVal adds the pair to the zone or, if the key exists, modifies its value.
+func setKeyVal(ctx context.Context, c *cli, api keyValAPI, name string, args []string) error {
+	fs := c.newFlagSet(name)
+	args, err := parseArgs(fs, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	zone, key, val := args[0], args[1], args[2]
+	pairs, err := api.get(ctx, zone)
+	if err != nil {
+		return err
+	}
+	action := "add"
+	if _, ok := pairs[key]; ok {
+		action = "modify"
+		err = api.modify(ctx, zone, key, val)
+	} else {
+		err = api.add(ctx, zone, key, val)
+	}
+	if err != nil {
+		return err
+	}
+	return c.out.done(change{Action: action, Zone: zone, Key: key}, fmt.Sprintf("set %v in %v", key, zone))
+}
+
+// deleteKeyVal deletes a key, or all keys with -all, from the zone.
+func deleteKeyVal(ctx context.Context, c *cli, api keyValAPI, name string, args []string) error {
+	fs := c.newFlagSet(name)
+	all := fs.Bool("all", false, "delete all keys of the zone")
+	args, err := parseArgs(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+	zone := args[0]
+	switch {
+	case *all && len(args) == 1:
+		if err := api.deleteAll(ctx, zone); err != nil {
+			return err
+		}
+		return c.out.done(change{Action: "delete", Zone: zone}, fmt.Sprintf("deleted all keys of %v", zone))
+	case !*all && len(args) == 2:
+		key := args[1]
+		if err := api.delete(ctx, zone, key); err != nil {
+			return err
+		}
+		return c.out.done(change{Action: "delete", Zone: zone, Key: key}, fmt.Sprintf("deleted %v from %v", key, zone))
+	default:
+		return fmt.Errorf("%w: %v: expects either a key or -all", errUsage, name)
+	}
+}
+
+// importKeyVals sets the pairs of a JSON object in the zone with the reconcile engine of the state package.
+func importKeyVals(ctx context.Context, c *cli, api keyValAPI, name string, args []string) error {
+	fs := c.newFlagSet(name)
+	prune := fs.Bool("prune", false, "delete the keys of the zone that are not imported")
+	dryRun := fs.Bool("dry-run", false, "only print the changes")
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	zone := args[0]
+
+	f, err := c.open(args[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var pairs client.KeyValPairs
+	if err := json.NewDecoder(f).Decode(&pairs); err != nil {
+		return fmt.Errorf("failed to read the key-value pairs from %v: %w", args[1], err)
+	}
+
+	s := &state.State{Version: state.SchemaVersion}
+	zones := map[string]state.KeyValZone{zone: {Pairs: pairs, Prune: prune}}
+	if api.stream {
+		s.StreamKeyValZones = zones
+	} else {
+		s.KeyValZones = zones
+	}
+	var plan *state.Plan
+	if *dryRun {
+		plan, err = state.NewPlan(ctx, c.client, s)
+	} else {
+		plan, err = state.Reconcile(ctx, c.client, s)
+	}
+	if plan != nil {
+		if printErr := printPlan(c.out, plan); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// printPlan prints the changes of a plan of the state package.
+func printPlan(p *printer, plan *state.Plan) error {
+	var rows [][]string
+	for _, r := range plan.Resources {
+		for _, group := range []struct {
+			change string
+			items  []string
+		}{{"add", r.Add}, {"update", r.Update}, {"delete", r.Delete}} {
+			for _, item := range group.items {
+				rows = append(rows, []string{group.change, item})
+			}
+		}
+	}
+	return p.print(plan, []string{"CHANGE", "KEY"}, rows)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+// newKeyValMock returns a mock with the HTTP keyval zones in memory.
+func newKeyValMock(zones client.KeyValPairsByZone) *clientmock.Client {
+	var mu sync.Mutex
+	mock := &clientmock.Client{}
+	mock.GetKeyValPairsFunc = func(_ context.Context, zone string) (client.KeyValPairs, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return zones[zone], nil
+	}
+	mock.GetAllKeyValPairsFunc = func(context.Context) (client.KeyValPairsByZone, error) {
+		return zones, nil
+	}
+	set := func(_ context.Context, zone, key, val string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if zones[zone] == nil {
+			zones[zone] = client.KeyValPairs{}
+		}
+		zones[zone][key] = val
+		return nil
+	}
+	mock.AddKeyValPairFunc = set
+	mock.ModifyKeyValPairFunc = set
+	mock.DeleteKeyValuePairFunc = func(_ context.Context, zone, key string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(zones[zone], key)
+		return nil
+	}
+	mock.DeleteKeyValPairsFunc = func(_ context.Context, zone string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(zones, zone)
+		return nil
+	}
+	return mock
+}
+
+func TestKeyValGet(t *testing.T) {
+	t.Parallel()
+	mock := newKeyValMock(client.KeyValPairsByZone{"zone": {"b": "2", "a": "1"}, "other": {"c": "3"}})
+
+	stdout, stderr, code := runCLI(t, mock, "", "keyval", "get")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "other") || !strings.HasPrefix(lines[2], "zone   a") {
+		t.Fatalf("unexpected table\n%v", stdout)
+	}
+
+	stdout, _, _ = runCLI(t, mock, "", "-o", "json", "keyval", "get", "zone")
+	var pairs client.KeyValPairs
+	if err := json.Unmarshal([]byte(stdout), &pairs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pairs, client.KeyValPairs{"a": "1", "b": "2"}) {
+		t.Fatalf("unexpected pairs %v", pairs)
+	}
+
+	stdout, _, _ = runCLI(t, mock, "", "-o", "json", "keyval", "get", "zone", "b")
+	if strings.Join(strings.Fields(stdout), "") != `{"b":"2"}` {
+		t.Fatalf("unexpected pair %v", stdout)
+	}
+	if _, _, code := runCLI(t, mock, "", "keyval", "get", "zone", "missing"); code != 1 {
+		t.Fatalf("expected a missing key to fail, got exit code %v", code)
+	}
+}
+
+func TestKeyValChanges(t *testing.T) {
+	t.Parallel()
+	zones := client.KeyValPairsByZone{"zone": {"a": "1", "b": "2"}}
+	mock := newKeyValMock(zones)
+
+	for _, args := range [][]string{
+		{"keyval", "set", "zone", "a", "changed"},
+		{"keyval", "set", "zone", "c", "3"},
+		{"keyval", "delete", "zone", "b"},
+	} {
+		if _, stderr, code := runCLI(t, mock, "", args...); code != 0 {
+			t.Fatalf("unexpected exit code %v of %v: %v", code, args, stderr)
+		}
+	}
+	if !reflect.DeepEqual(zones["zone"], client.KeyValPairs{"a": "changed", "c": "3"}) {
+		t.Fatalf("unexpected pairs %v", zones["zone"])
+	}
+	if len(mock.KeyValStore.CallsOf("ModifyKeyValPair")) != 1 || len(mock.KeyValStore.CallsOf("AddKeyValPair")) != 1 {
+		t.Fatalf("expected existing keys to be modified and new keys to be added, got %+v", mock.KeyValStore.Calls())
+	}
+
+	if _, _, code := runCLI(t, mock, "", "keyval", "delete", "-all", "zone", "a"); code != 2 {
+		t.Fatalf("expected -all with a key to be a usage error, got exit code %v", code)
+	}
+	if _, stderr, code := runCLI(t, mock, "", "keyval", "delete", "-all", "zone"); code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	if _, ok := zones["zone"]; ok {
+		t.Fatal("expected all keys to be deleted")
+	}
+}
+
+func TestKeyValImport(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		expected client.KeyValPairs
+	}{
+		{
+			name:     "merge",
+			args:     []string{"keyval", "import", "zone", "-"},
+			expected: client.KeyValPairs{"a": "changed", "b": "2", "c": "3"},
+		},
+		{
+			name:     "prune",
+			args:     []string{"keyval", "import", "-prune", "zone", "-"},
+			expected: client.KeyValPairs{"a": "changed", "c": "3"},
+		},
+		{
+			name:     "dry run",
+			args:     []string{"keyval", "import", "-prune", "-dry-run", "zone", "-"},
+			expected: client.KeyValPairs{"a": "1", "b": "2"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			zones := client.KeyValPairsByZone{"zone": {"a": "1", "b": "2"}}
+			mock := newKeyValMock(zones)
+			stdout, stderr, code := runCLI(t, mock, `{"a": "changed", "c": "3"}`, test.args...)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %v: %v", code, stderr)
+			}
+			if !strings.Contains(stdout, "update  a") || !strings.Contains(stdout, "add     c") {
+				t.Fatalf("unexpected plan\n%v", stdout)
+			}
+			if !reflect.DeepEqual(zones["zone"], test.expected) {
+				t.Fatalf("expected pairs %v, got %v", test.expected, zones["zone"])
+			}
+		})
+	}
+}
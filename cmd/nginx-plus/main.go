@@ -0,0 +1,267 @@
+// Command nginx-plus manages the upstream servers and keyval zones of NGINX Plus and shows information about it
+// through the NGINX Plus API.
+//
+// Usage:
+//
+//	nginx-plus [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	upstream         list, add, remove, update, drain and reconcile the servers of HTTP upstreams
+//	stream-upstream  list, add, remove, update and reconcile the servers of stream upstreams
+//	keyval           get, set, delete and import the key-value pairs of HTTP keyval zones
+//	stream-keyval    get, set, delete and import the key-value pairs of stream keyval zones
+//	info             show the version and build of NGINX
+//	license          show the license of NGINX Plus
+//	top              show live stats of NGINX Plus
+//	check            check thresholds against live stats as a monitoring plugin, exiting with 0 for OK, 1 for WARNING,
+//	                 2 for CRITICAL and 3 for UNKNOWN
+//
+// The API endpoint and the credentials are read from flags or, if the flags are not set, from the environment variables
+// NGINX_PLUS_API, NGINX_PLUS_USERNAME, NGINX_PLUS_PASSWORD, NGINX_PLUS_TOKEN and NGINX_PLUS_TOKEN_FILE.
+// Without -api-version, the highest API version supported by both NGINX Plus and the client is used.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// errUsage is returned for invalid commands and arguments, after which the usage is printed.
+var errUsage = errors.New("invalid usage")
+
+const usage = `Usage: nginx-plus [flags] <command> [arguments]
+
+Commands:
+  upstream list <upstream>
+  upstream add <upstream> <server> [server flags]
+  upstream remove <upstream> <server|id>
+  upstream update <upstream> <server|id> [server flags]
+  upstream drain <upstream> <server|id>
+  upstream down <upstream> <server|id>
+  upstream up <upstream> <server|id>
+  upstream reconcile <upstream> <file|-> [-dry-run] [-order order] [-parallelism n]
+  stream-upstream <list|add|remove|update|down|up|reconcile> ...
+  keyval get <zone> [key]
+  keyval set <zone> <key> <value>
+  keyval delete <zone> <key>
+  keyval delete -all <zone>
+  keyval import <zone> <file|-> [-prune]
+  stream-keyval <get|set|delete|import> ...
+  info
+  license
+  top [-interval duration]
+  check upstream [-stream] [-upstream name] [-warning n] [-critical n]
+  check 5xx [-zone name] [-interval duration] [-warning percent] [-critical percent]
+  check slabs [-zone name] [-warning percent] [-critical percent]
+  check license [-warning days] [-critical days]
+  check zone-sync [-zone name] [-warning n] [-critical n]
+
+Run "nginx-plus <command> <subcommand> -h" for the flags of a subcommand.
+
+Flags:
+`
+
+// env is the environment a command runs in.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(key string) string
+	// connect creates the client of the API. It is replaced in tests.
+	connect func(conn connection) (client.Client, error)
+}
+
+// cli is the state shared by the commands.
+type cli struct {
+	client client.Client
+	out    *printer
+	env    *env
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], &env{
+		stdin:   os.Stdin,
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+		getenv:  os.Getenv,
+		connect: connect,
+	})
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command line and returns the exit code: 0 on success, 1 on errors and 2 on invalid usage, or the
+// state of a check.
+func run(ctx context.Context, args []string, e *env) int {
+	fs := flag.NewFlagSet("nginx-plus", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprint(e.stderr, usage)
+		fs.PrintDefaults()
+	}
+	var conn connection
+	conn.register(fs)
+	output := fs.String("o", "table", "output format, table or json")
+	// Errors before a check runs are reported as its UNKNOWN state, as monitoring systems only read its output and exit code.
+	checking := commandName(fs, args) == "check"
+	fail := func(code int, err error) int {
+		if checking {
+			fmt.Fprintf(e.stdout, "%v - %v\n", stateUnknown, err)
+			return int(stateUnknown)
+		}
+		fmt.Fprintf(e.stderr, "nginx-plus: %v\n", err)
+		return code
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if checking {
+			return fail(2, err)
+		}
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		return fail(2, fmt.Errorf("unknown output format %q", *output))
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	command, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(e.stderr, "nginx-plus: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	if err := conn.loadEnv(fs, e.getenv); err != nil {
+		return fail(2, err)
+	}
+	if err := conn.validate(); err != nil {
+		return fail(2, err)
+	}
+	c, err := e.connect(conn)
+	if err != nil {
+		return fail(1, err)
+	}
+
+	err = command(ctx, &cli{client: c, out: &printer{w: e.stdout, json: *output == "json"}, env: e}, fs.Args()[1:])
+	var exit *exitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exit):
+		return exit.code
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(e.stderr, "nginx-plus: %v\n", err)
+		fmt.Fprint(e.stderr, usage)
+		return 2
+	default:
+		fmt.Fprintf(e.stderr, "nginx-plus: %v\n", err)
+		return 1
+	}
+}
+
+// commandName returns the command of the arguments, skipping the global flags and their values, before they are parsed.
+func commandName(fs *flag.FlagSet, args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return arg
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) {
+			i++
+		}
+	}
+	return ""
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+type commandFunc func(ctx context.Context, c *cli, args []string) error
+
+var commands = map[string]commandFunc{
+	"upstream": func(ctx context.Context, c *cli, args []string) error {
+		return runUpstream(ctx, c, httpUpstreams(c.client), args)
+	},
+	"stream-upstream": func(ctx context.Context, c *cli, args []string) error {
+		return runUpstream(ctx, c, streamUpstreams(c.client), args)
+	},
+	"keyval": func(ctx context.Context, c *cli, args []string) error {
+		return runKeyVal(ctx, c, httpKeyVals(c.client), args)
+	},
+	"stream-keyval": func(ctx context.Context, c *cli, args []string) error {
+		return runKeyVal(ctx, c, streamKeyVals(c.client), args)
+	},
+	"info":    runInfo,
+	"license": runLicense,
+	"top":     runTop,
+	"check":   runCheck,
+}
+
+// parseArgs parses the flags of a subcommand, which may come before, between or after the positional arguments,
+// and returns the positional arguments. It fails unless there are between lo and hi of them.
+func parseArgs(fs *flag.FlagSet, args []string, lo, hi int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	switch {
+	case len(positional) < lo:
+		return nil, fmt.Errorf("%w: %v: missing arguments", errUsage, fs.Name())
+	case len(positional) > hi:
+		return nil, fmt.Errorf("%w: %v: too many arguments", errUsage, fs.Name())
+	}
+	return positional, nil
+}
+
+// newFlagSet creates the flag set of a subcommand.
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.env.stderr)
+	return fs
+}
+
+// open opens the file, or standard input for "-".
+func (c *cli) open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(c.env.stdin), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %v: %w", name, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+var errFailed = errors.New("failed")
+
+// runCLI runs the command line against the mock and returns the output and the exit code.
+func runCLI(t *testing.T, mock *clientmock.Client, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &env{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(string) string { return "" },
+		connect: func(connection) (client.Client, error) {
+			return mock, nil
+		},
+	})
+	return stdout.String(), stderr.String(), code
+}
+
+func TestRunUsage(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no command", args: nil, code: 2},
+		{name: "unknown command", args: []string{"foo"}, code: 2},
+		{name: "unknown output", args: []string{"-o", "yaml", "info"}, code: 2},
+		{name: "unknown subcommand", args: []string{"upstream", "foo"}, code: 2},
+		{name: "missing arguments", args: []string{"upstream", "add", "backend"}, code: 2},
+		{name: "too many arguments", args: []string{"info", "foo"}, code: 2},
+		{name: "conflicting authentication", args: []string{"-username", "user", "-token", "token", "info"}, code: 2},
+		{name: "help", args: []string{"-h"}, code: 0},
+		{name: "subcommand help", args: []string{"upstream", "add", "-h"}, code: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, stderr, code := runCLI(t, &clientmock.Client{}, "", test.args...)
+			if code != test.code {
+				t.Fatalf("expected exit code %v, got %v: %v", test.code, code, stderr)
+			}
+		})
+	}
+}
+
+func TestConnectionEnv(t *testing.T) {
+	t.Parallel()
+	environment := map[string]string{
+		"NGINX_PLUS_API":         "http://nginx/api",
+		"NGINX_PLUS_TOKEN":       "secret",
+		"NGINX_PLUS_API_VERSION": "8",
+	}
+	var got connection
+	var stderr bytes.Buffer
+	code := run(context.Background(), []string{"-api", "http://other/api", "-h"}, &env{
+		stderr: &stderr,
+		getenv: func(key string) string { return environment[key] },
+	})
+	if code != 0 {
+		t.Fatalf("unexpected exit code %v", code)
+	}
+	if strings.Contains(stderr.String(), "secret") {
+		t.Fatal("expected the token not to be printed in the usage")
+	}
+
+	code = run(context.Background(), []string{"-api", "http://other/api", "info"}, &env{
+		stdout: &bytes.Buffer{},
+		stderr: &stderr,
+		getenv: func(key string) string { return environment[key] },
+		connect: func(conn connection) (client.Client, error) {
+			got = conn
+			return nil, errFailed
+		},
+	})
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %v", code)
+	}
+	if got.endpoint != "http://other/api" || got.token != "secret" || got.apiVersion != 8 {
+		t.Fatalf("unexpected connection %+v", got)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+	mock := &clientmock.Client{}
+	mock.GetNginxInfoFunc = func(context.Context) (*client.NginxInfo, error) {
+		return &client.NginxInfo{Version: "1.27.4", Build: "nginx-plus-r34", ProcessID: 42}, nil
+	}
+	mock.VersionFunc = func() int { return 9 }
+
+	stdout, stderr, code := runCLI(t, mock, "", "info")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	if !strings.Contains(stdout, "nginx-plus-r34") || !strings.Contains(stdout, "API version") {
+		t.Fatalf("unexpected output\n%v", stdout)
+	}
+
+	stdout, _, _ = runCLI(t, mock, "", "-o", "json", "info")
+	var info struct {
+		Version    string
+		APIVersion int `json:"api_version"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "1.27.4" || info.APIVersion != 9 {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	mock.GetNginxLicenseFunc = func(context.Context) (*client.NginxLicense, error) {
+		return nil, errFailed
+	}
+	_, stderr, code = runCLI(t, mock, "", "license")
+	if code != 1 || !strings.Contains(stderr, errFailed.Error()) {
+		t.Fatalf("expected the error, got exit code %v: %v", code, stderr)
+	}
+}
+
+func TestLicense(t *testing.T) {
+	t.Parallel()
+	mock := &clientmock.Client{}
+	mock.GetNginxLicenseFunc = func(context.Context) (*client.NginxLicense, error) {
+		return &client.NginxLicense{ActiveTill: 1767225600, Reporting: &client.LicenseReporting{Healthy: true, Grace: 100}}, nil
+	}
+	stdout, stderr, code := runCLI(t, mock, "", "license")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	for _, expected := range []string{"2026-01-01T00:00:00Z", "Reporting healthy", "100"} {
+		if !strings.Contains(stdout, expected) {
+			t.Fatalf("expected %q in the output\n%v", expected, stdout)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// printer prints the results of the commands as tables or as JSON.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// print prints the value as JSON, or the rows as a table with the header.
+func (p *printer) print(v any, header []string, rows [][]string) error {
+	if p.json {
+		return p.printJSON(v)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, cell)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// done prints the result of a change, as the message or, in JSON, as the value.
+func (p *printer) done(v any, message string) error {
+	if p.json {
+		return p.printJSON(v)
+	}
+	_, err := fmt.Fprintln(p.w, message)
+	return err
+}
+
+func (p *printer) printJSON(v any) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// cell formats an optional value of a table, with "-" if it is not set.
+func cell[T any](v *T) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprint(*v)
+}
+
+// text formats a string of a table, with "-" if it is empty.
+func text(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// yesNo formats a boolean of a table.
+func yesNo(b bool) string {
+	return strconv.FormatBool(b)
+}
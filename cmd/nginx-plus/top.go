@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// errNotTerminal is returned by top if standard input or output is not a terminal.
+var errNotTerminal = errors.New("top needs a terminal")
+
+// runTop shows live stats of NGINX Plus until q is pressed or the context is done.
+func runTop(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("top")
+	interval := fs.Duration("interval", 2*time.Second, "interval between updates")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("%w: top: the interval must be positive", errUsage)
+	}
+
+	in, inOK := c.env.stdin.(*os.File)
+	out, outOK := c.env.stdout.(*os.File)
+	if !inOK || !outOK || !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errNotTerminal
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer func() {
+		_ = term.Restore(int(in.Fd()), state)
+	}()
+
+	// Switch to the alternate screen and hide the cursor, and back on exit.
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	keys := make(chan []key)
+	go readKeys(ctx, in, keys)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	v := newView()
+	var prev sample
+	draw := func() {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		fmt.Fprint(out, v.render(width, height))
+	}
+	refresh := func() {
+		cur := sample{time: time.Now()}
+		cur.stats, v.err = c.client.GetStats(ctx)
+		if v.err == nil {
+			v.header = fmt.Sprintf("NGINX Plus %v (%v) - updated %v every %v", cur.stats.NginxInfo.Version, cur.stats.NginxInfo.Build, cur.time.Format(time.TimeOnly), *interval)
+			v.update(buildSections(prev, cur))
+			prev = cur
+		}
+		draw()
+	}
+
+	refresh()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			refresh()
+		case pressed, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range pressed {
+				if v.handle(k) {
+					return nil
+				}
+			}
+			draw()
+		}
+	}
+}
+
+// readKeys sends the keys read from the terminal until it can't be read or the context is done.
+// A read can't be interrupted, so once the context is done, the reader is abandoned:
+// readKeys returns with the next read, or never if nothing is typed before the program exits.
+func readKeys(ctx context.Context, r io.Reader, keys chan<- []key) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			select {
+			case keys <- parseKeys(buf[:n]):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
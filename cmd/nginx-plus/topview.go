@@ -0,0 +1,472 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// column is a column of a section of top.
+type column struct {
+	name string
+	// numeric columns are right-aligned and sorted in descending order by default.
+	numeric bool
+}
+
+// row is a row of a section. values are the numbers the numeric cells are sorted by.
+type row struct {
+	cells  []string
+	values []float64
+}
+
+// section is a table of top, for example the server zones.
+type section struct {
+	name    string
+	columns []column
+	rows    []row
+	// sortBy is the column the rows are sorted by when the section is first shown.
+	sortBy int
+}
+
+// sample is the stats read from NGINX Plus at a time.
+type sample struct {
+	time  time.Time
+	stats *client.Stats
+}
+
+// rates computes the per-second rates of counters between two samples.
+// Counters that went down, because NGINX was reloaded or a zone was recreated, have a rate of 0.
+type rates struct {
+	seconds float64
+}
+
+func newRates(prev, cur sample) rates {
+	if prev.stats == nil {
+		return rates{}
+	}
+	return rates{seconds: cur.time.Sub(prev.time).Seconds()}
+}
+
+func (r rates) rate(prev, cur uint64, ok bool) float64 {
+	if !ok || r.seconds <= 0 || cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / r.seconds
+}
+
+// buildSections returns the sections of top for the current sample and the rates since the previous sample.
+func buildSections(prev, cur sample) []section {
+	r := newRates(prev, cur)
+	var prevStats client.Stats
+	if prev.stats != nil {
+		prevStats = *prev.stats
+	}
+	return []section{
+		serverZonesSection(r, prevStats.ServerZones, cur.stats.ServerZones),
+		locationZonesSection(r, prevStats.LocationZones, cur.stats.LocationZones),
+		peersSection(r, prevStats.Upstreams, cur.stats.Upstreams),
+		cachesSection(r, prevStats.Caches, cur.stats.Caches),
+		limitReqsSection(r, prevStats.HTTPLimitRequests, cur.stats.HTTPLimitRequests),
+	}
+}
+
+func serverZonesSection(r rates, prev, cur client.ServerZones) section {
+	s := section{
+		name:    "Server zones",
+		columns: []column{{name: "ZONE"}, {name: "PROCESSING", numeric: true}, {name: "REQ/S", numeric: true}, {name: "4XX/S", numeric: true}, {name: "5XX/S", numeric: true}, {name: "RECV/S", numeric: true}, {name: "SENT/S", numeric: true}},
+		sortBy:  2,
+	}
+	for _, name := range slices.Sorted(maps.Keys(cur)) {
+		z := cur[name]
+		p, ok := prev[name]
+		s.rows = append(s.rows, newRow([]string{name},
+			float64(z.Processing),
+			r.rate(p.Requests, z.Requests, ok),
+			r.rate(p.Responses.Responses4xx, z.Responses.Responses4xx, ok),
+			r.rate(p.Responses.Responses5xx, z.Responses.Responses5xx, ok),
+			r.rate(p.Received, z.Received, ok),
+			r.rate(p.Sent, z.Sent, ok),
+		))
+	}
+	return s
+}
+
+func locationZonesSection(r rates, prev, cur client.LocationZones) section {
+	s := section{
+		name:    "Location zones",
+		columns: []column{{name: "ZONE"}, {name: "REQ/S", numeric: true}, {name: "4XX/S", numeric: true}, {name: "5XX/S", numeric: true}, {name: "RECV/S", numeric: true}, {name: "SENT/S", numeric: true}},
+		sortBy:  1,
+	}
+	for _, name := range slices.Sorted(maps.Keys(cur)) {
+		z := cur[name]
+		p, ok := prev[name]
+		s.rows = append(s.rows, newRow([]string{name},
+			r.rate(uint64(p.Requests), uint64(z.Requests), ok),
+			r.rate(p.Responses.Responses4xx, z.Responses.Responses4xx, ok),
+			r.rate(p.Responses.Responses5xx, z.Responses.Responses5xx, ok),
+			r.rate(uint64(p.Received), uint64(z.Received), ok),
+			r.rate(uint64(p.Sent), uint64(z.Sent), ok),
+		))
+	}
+	return s
+}
+
+func peersSection(r rates, prev, cur client.Upstreams) section {
+	s := section{
+		name: "Upstream peers",
+		columns: []column{
+			{name: "UPSTREAM"}, {name: "SERVER"}, {name: "STATE"}, {name: "ACTIVE", numeric: true}, {name: "REQ/S", numeric: true},
+			{name: "5XX/S", numeric: true}, {name: "RESP_MS", numeric: true}, {name: "FAILS/S", numeric: true},
+		},
+		sortBy: 4,
+	}
+	for _, name := range slices.Sorted(maps.Keys(cur)) {
+		prevPeers := make(map[int]client.Peer)
+		for _, peer := range prev[name].Peers {
+			prevPeers[peer.ID] = peer
+		}
+		for _, peer := range cur[name].Peers {
+			p, ok := prevPeers[peer.ID]
+			ok = ok && p.Server == peer.Server
+			s.rows = append(s.rows, newRow([]string{name, peer.Server, peer.State},
+				float64(peer.Active),
+				r.rate(p.Requests, peer.Requests, ok),
+				r.rate(p.Responses.Responses5xx, peer.Responses.Responses5xx, ok),
+				float64(peer.ResponseTime),
+				r.rate(p.Fails, peer.Fails, ok),
+			))
+		}
+	}
+	return s
+}
+
+func cachesSection(r rates, prev, cur client.Caches) section {
+	s := section{
+		name: "Caches",
+		columns: []column{
+			{name: "CACHE"}, {name: "SIZE", numeric: true}, {name: "HIT_RATIO", numeric: true}, {name: "HITS/S", numeric: true}, {name: "MISSES/S", numeric: true},
+		},
+		sortBy: 3,
+	}
+	for _, name := range slices.Sorted(maps.Keys(cur)) {
+		c := cur[name]
+		p, ok := prev[name]
+		hits, misses := cacheHits(c), cacheMisses(c)
+		hitsPerSecond := r.rate(cacheHits(p), hits, ok)
+		missesPerSecond := r.rate(cacheMisses(p), misses, ok)
+
+		// The ratio is of the interval if there was traffic, and of all time otherwise.
+		ratio := -1.0
+		switch {
+		case hitsPerSecond+missesPerSecond > 0:
+			ratio = hitsPerSecond / (hitsPerSecond + missesPerSecond)
+		case hits+misses > 0:
+			ratio = float64(hits) / float64(hits+misses)
+		}
+		cacheRow := newRow([]string{name}, float64(c.Size), ratio, hitsPerSecond, missesPerSecond)
+		cacheRow.cells[1] = formatBytes(c.Size)
+		if ratio < 0 {
+			cacheRow.cells[2] = "-"
+		} else {
+			cacheRow.cells[2] = strconv.FormatFloat(ratio*100, 'f', 1, 64) + "%"
+		}
+		s.rows = append(s.rows, cacheRow)
+	}
+	return s
+}
+
+// cacheHits returns the responses served from the cache.
+func cacheHits(c client.HTTPCache) uint64 {
+	return c.Hit.Responses + c.Stale.Responses + c.Updating.Responses + c.Revalidated.Responses
+}
+
+// cacheMisses returns the responses not served from the cache.
+func cacheMisses(c client.HTTPCache) uint64 {
+	return c.Miss.Responses + c.Expired.Responses + c.Bypass.Responses
+}
+
+func limitReqsSection(r rates, prev, cur client.HTTPLimitRequests) section {
+	s := section{
+		name: "Limit req",
+		columns: []column{
+			{name: "ZONE"}, {name: "PASSED/S", numeric: true}, {name: "DELAYED/S", numeric: true}, {name: "REJECTED/S", numeric: true},
+			{name: "REJECTED_DRY_RUN/S", numeric: true},
+		},
+		sortBy: 3,
+	}
+	for _, name := range slices.Sorted(maps.Keys(cur)) {
+		l := cur[name]
+		p, ok := prev[name]
+		s.rows = append(s.rows, newRow([]string{name},
+			r.rate(p.Passed, l.Passed, ok),
+			r.rate(p.Delayed, l.Delayed, ok),
+			r.rate(p.Rejected, l.Rejected, ok),
+			r.rate(p.RejectedDryRun, l.RejectedDryRun, ok),
+		))
+	}
+	return s
+}
+
+// newRow returns a row with the text cells followed by the numeric cells.
+func newRow(labels []string, numbers ...float64) row {
+	r := row{cells: slices.Clone(labels), values: make([]float64, len(labels), len(labels)+len(numbers))}
+	for _, n := range numbers {
+		r.cells = append(r.cells, formatNumber(n))
+		r.values = append(r.values, n)
+	}
+	return r
+}
+
+func formatNumber(n float64) string {
+	if n == float64(int64(n)) {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64)
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// view is the state of the screen of top: the sections, the selected section, the sort order and the scroll position.
+type view struct {
+	sections  []section
+	sortBy    map[string]int
+	ascending map[string]bool
+	header    string
+	err       error
+	current   int
+	offset    int
+}
+
+func newView() *view {
+	return &view{sortBy: make(map[string]int), ascending: make(map[string]bool)}
+}
+
+// update replaces the sections with those of a new sample, keeping the selected section and sort order.
+func (v *view) update(sections []section) {
+	v.sections = sections
+	v.current = min(v.current, max(len(sections)-1, 0))
+}
+
+// key is a key pressed in top.
+type key int
+
+const (
+	keyQuit key = iota
+	keyNextSection
+	keyPrevSection
+	keyNextSort
+	keyReverse
+	keyUp
+	keyDown
+	keySection1
+)
+
+// handle changes the view for the key and reports whether top should quit.
+func (v *view) handle(k key) bool {
+	if len(v.sections) == 0 {
+		return k == keyQuit
+	}
+	s := v.sections[v.current]
+	switch {
+	case k == keyQuit:
+		return true
+	case k == keyNextSection:
+		v.current = (v.current + 1) % len(v.sections)
+		v.offset = 0
+	case k == keyPrevSection:
+		v.current = (v.current + len(v.sections) - 1) % len(v.sections)
+		v.offset = 0
+	case k == keyNextSort:
+		v.sortBy[s.name] = (v.sortColumn(s) + 1) % len(s.columns)
+		v.ascending[s.name] = !s.columns[v.sortBy[s.name]].numeric
+	case k == keyReverse:
+		v.ascending[s.name] = !v.isAscending(s)
+	case k == keyUp:
+		v.offset = max(v.offset-1, 0)
+	case k == keyDown:
+		v.offset = min(v.offset+1, max(len(s.rows)-1, 0))
+	case k >= keySection1 && int(k-keySection1) < len(v.sections):
+		v.current = int(k - keySection1)
+		v.offset = 0
+	}
+	return false
+}
+
+func (v *view) sortColumn(s section) int {
+	if c, ok := v.sortBy[s.name]; ok {
+		return c
+	}
+	return s.sortBy
+}
+
+func (v *view) isAscending(s section) bool {
+	if a, ok := v.ascending[s.name]; ok {
+		return a
+	}
+	return !s.columns[v.sortColumn(s)].numeric
+}
+
+// sorted returns the rows of the section in the sort order of the view.
+func (v *view) sorted(s section) []row {
+	rows := slices.Clone(s.rows)
+	c, ascending := v.sortColumn(s), v.isAscending(s)
+	slices.SortStableFunc(rows, func(a, b row) int {
+		var result int
+		if s.columns[c].numeric {
+			result = cmp.Compare(a.values[c], b.values[c])
+		} else {
+			result = strings.Compare(a.cells[c], b.cells[c])
+		}
+		if !ascending {
+			result = -result
+		}
+		return result
+	})
+	return rows
+}
+
+// render returns the screen of the view for a terminal of the width and height.
+// Lines end with "\r\n", because the terminal is in raw mode.
+func (v *view) render(width, height int) string {
+	var lines []string
+	add := func(line string) {
+		lines = append(lines, truncate(line, width))
+	}
+
+	add(v.header)
+	if v.err != nil {
+		add("error: " + v.err.Error())
+	} else {
+		add("")
+	}
+
+	var tabs strings.Builder
+	for i, s := range v.sections {
+		label := fmt.Sprintf(" %d %s ", i+1, s.name)
+		if i == v.current {
+			label = "\x1b[7m" + label + "\x1b[0m"
+		}
+		tabs.WriteString(label)
+	}
+	lines = append(lines, tabs.String(), "")
+
+	if len(v.sections) > 0 {
+		s := v.sections[v.current]
+		rows := v.sorted(s)
+		widths := make([]int, len(s.columns))
+		for i, c := range s.columns {
+			widths[i] = len(c.name) + 2
+			for _, r := range rows {
+				widths[i] = max(widths[i], len(r.cells[i]))
+			}
+		}
+
+		header := make([]string, len(s.columns))
+		sortBy, ascending := v.sortColumn(s), v.isAscending(s)
+		for i, c := range s.columns {
+			name := c.name
+			if i == sortBy {
+				if ascending {
+					name += " ^"
+				} else {
+					name += " v"
+				}
+			}
+			header[i] = pad(name, widths[i], c.numeric)
+		}
+		add("\x1b[1m" + strings.Join(header, "  ") + "\x1b[0m")
+
+		footer := 1
+		visible := max(height-len(lines)-footer, 0)
+		offset := min(v.offset, max(len(rows)-visible, 0))
+		for _, r := range rows[offset:min(offset+visible, len(rows))] {
+			cells := make([]string, len(r.cells))
+			for i, cell := range r.cells {
+				cells[i] = pad(cell, widths[i], s.columns[i].numeric)
+			}
+			add(strings.Join(cells, "  "))
+		}
+		if len(rows) == 0 {
+			add("no " + strings.ToLower(s.name))
+		}
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, truncate("tab/arrows: section  1-5: jump  s: sort  r: reverse  up/down: scroll  q: quit", width))
+	return "\x1b[H\x1b[2J" + strings.Join(lines, "\r\n")
+}
+
+func pad(s string, width int, right bool) string {
+	if right {
+		return fmt.Sprintf("%*s", width, s)
+	}
+	return fmt.Sprintf("%-*s", width, s)
+}
+
+// truncate truncates the line to the width. Lines with escape sequences are not truncated.
+func truncate(line string, width int) string {
+	if width <= 0 || len(line) <= width || strings.Contains(line, "\x1b") {
+		return line
+	}
+	return line[:width]
+}
+
+// parseKeys returns the keys in the input read from the terminal.
+func parseKeys(input []byte) []key {
+	var keys []key
+	for i := 0; i < len(input); i++ {
+		b := input[i]
+		switch {
+		case b == 0x1b && i+2 < len(input) && input[i+1] == '[':
+			switch input[i+2] {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			case 'C':
+				keys = append(keys, keyNextSection)
+			case 'D':
+				keys = append(keys, keyPrevSection)
+			case 'Z':
+				keys = append(keys, keyPrevSection)
+			}
+			i += 2
+		case b == 'q' || b == 0x03 || b == 0x04:
+			keys = append(keys, keyQuit)
+		case b == '\t' || b == 'l':
+			keys = append(keys, keyNextSection)
+		case b == 'h':
+			keys = append(keys, keyPrevSection)
+		case b == 's':
+			keys = append(keys, keyNextSort)
+		case b == 'r':
+			keys = append(keys, keyReverse)
+		case b == 'k':
+			keys = append(keys, keyUp)
+		case b == 'j':
+			keys = append(keys, keyDown)
+		case b >= '1' && b <= '9':
+			keys = append(keys, keySection1+key(b-'1'))
+		}
+	}
+	return keys
+}
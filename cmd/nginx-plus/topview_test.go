@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+func topSamples() (sample, sample) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := sample{time: start, stats: &client.Stats{
+		ServerZones: client.ServerZones{
+			"api": {Requests: 100, Responses: client.Responses{Responses5xx: 10}},
+			"web": {Requests: 1000},
+		},
+		Upstreams: client.Upstreams{"backend": {Peers: []client.Peer{
+			{ID: 0, Server: "10.0.0.1:80", Requests: 100},
+			{ID: 1, Server: "10.0.0.2:80", Requests: 500},
+		}}},
+		Caches:            client.Caches{"cache": {Hit: client.CacheStats{Responses: 10}, Miss: client.CacheStats{Responses: 10}}},
+		HTTPLimitRequests: client.HTTPLimitRequests{"limit": {Passed: 10, Rejected: 4}},
+	}}
+	cur := sample{time: start.Add(2 * time.Second), stats: &client.Stats{
+		ServerZones: client.ServerZones{
+			"api": {Processing: 3, Requests: 300, Responses: client.Responses{Responses5xx: 20}},
+			"web": {Requests: 1010},
+			"new": {Requests: 50},
+		},
+		Upstreams: client.Upstreams{"backend": {Peers: []client.Peer{
+			{ID: 0, Server: "10.0.0.1:80", State: "up", Active: 2, Requests: 120, ResponseTime: 15},
+			// The peer was replaced and its counters started again.
+			{ID: 1, Server: "10.0.0.3:80", State: "up", Requests: 5},
+		}}},
+		Caches:            client.Caches{"cache": {Size: 2048, Hit: client.CacheStats{Responses: 40}, Miss: client.CacheStats{Responses: 20}}},
+		HTTPLimitRequests: client.HTTPLimitRequests{"limit": {Passed: 30, Rejected: 10}},
+	}}
+	return prev, cur
+}
+
+func TestBuildSections(t *testing.T) {
+	t.Parallel()
+	prev, cur := topSamples()
+	sections := buildSections(prev, cur)
+	if len(sections) != 5 {
+		t.Fatalf("expected 5 sections, got %v", len(sections))
+	}
+	cells := func(s section) [][]string {
+		var result [][]string
+		for _, r := range s.rows {
+			result = append(result, r.cells)
+		}
+		return result
+	}
+
+	tests := []struct {
+		section  section
+		expected [][]string
+	}{
+		{
+			section: sections[0],
+			expected: [][]string{
+				{"api", "3", "100", "0", "5", "0", "0"},
+				{"new", "0", "0", "0", "0", "0", "0"},
+				{"web", "0", "5", "0", "0", "0", "0"},
+			},
+		},
+		{
+			section: sections[2],
+			expected: [][]string{
+				{"backend", "10.0.0.1:80", "up", "2", "10", "0", "15", "0"},
+				{"backend", "10.0.0.3:80", "up", "0", "0", "0", "0", "0"},
+			},
+		},
+		{
+			section:  sections[3],
+			expected: [][]string{{"cache", "2.0KB", "75.0%", "15", "5"}},
+		},
+		{
+			section:  sections[4],
+			expected: [][]string{{"limit", "10", "0", "3", "0"}},
+		},
+	}
+	for _, test := range tests {
+		if got := cells(test.section); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%v: expected rows %v, got %v", test.section.name, test.expected, got)
+		}
+	}
+
+	first := buildSections(sample{}, cur)
+	if first[0].rows[0].cells[2] != "0" || first[3].rows[0].cells[2] != "66.7%" {
+		t.Fatalf("expected no rates and the hit ratio of all time for the first sample, got %v and %v", first[0].rows[0].cells, first[3].rows[0].cells)
+	}
+}
+
+func TestViewKeysAndRender(t *testing.T) {
+	t.Parallel()
+	prev, cur := topSamples()
+	v := newView()
+	v.header = "NGINX Plus"
+	v.update(buildSections(prev, cur))
+
+	firstColumn := func() []string {
+		var result []string
+		for _, r := range v.sorted(v.sections[v.current]) {
+			result = append(result, r.cells[0])
+		}
+		return result
+	}
+	if got := firstColumn(); !reflect.DeepEqual(got, []string{"api", "web", "new"}) {
+		t.Fatalf("expected the zones sorted by requests, got %v", got)
+	}
+	v.handle(keyReverse)
+	if got := firstColumn(); !reflect.DeepEqual(got, []string{"new", "web", "api"}) {
+		t.Fatalf("expected the reverse order, got %v", got)
+	}
+	for range 5 {
+		v.handle(keyNextSort)
+	}
+	if got := firstColumn(); !reflect.DeepEqual(got, []string{"api", "new", "web"}) {
+		t.Fatalf("expected the zones sorted by name, got %v", got)
+	}
+
+	for _, k := range parseKeys([]byte("\t\x1b[C")) {
+		v.handle(k)
+	}
+	if v.current != 2 {
+		t.Fatalf("expected the peers section, got %v", v.current)
+	}
+	v.handle(keyPrevSection)
+	v.handle(keyPrevSection)
+	v.handle(keyPrevSection)
+	if v.current != 4 {
+		t.Fatalf("expected to wrap around to the last section, got %v", v.current)
+	}
+	v.handle(keySection1 + 3)
+	screen := v.render(100, 12)
+	if !strings.Contains(screen, "\x1b[7m 4 Caches \x1b[0m") || !strings.Contains(screen, "75.0%") || !strings.Contains(screen, "HITS/S v") {
+		t.Fatalf("unexpected screen\n%q", screen)
+	}
+	if lines := strings.Count(screen, "\r\n") + 1; lines != 12 {
+		t.Fatalf("expected 12 lines, got %v", lines)
+	}
+	if !v.handle(parseKeys([]byte("q"))[0]) {
+		t.Fatal("expected q to quit")
+	}
+}
+
+func TestReadKeysStopsWhenDone(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	keys := make(chan []key)
+	done := make(chan struct{})
+	go func() {
+		readKeys(ctx, strings.NewReader("jk"), keys)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected readKeys to return when the context is done and nobody receives the keys")
+	}
+}
+
+func TestTopNeedsTerminal(t *testing.T) {
+	t.Parallel()
+	var stderr bytes.Buffer
+	code := run(context.Background(), []string{"top"}, &env{
+		stdin:  strings.NewReader(""),
+		stdout: &bytes.Buffer{},
+		stderr: &stderr,
+		getenv: func(string) string { return "" },
+		connect: func(connection) (client.Client, error) {
+			return &clientmock.Client{}, nil
+		},
+	})
+	if code != 1 || !strings.Contains(stderr.String(), errNotTerminal.Error()) {
+		t.Fatalf("expected %v, got exit code %v: %v", errNotTerminal, code, stderr.String())
+	}
+}
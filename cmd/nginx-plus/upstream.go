@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+)
+
+// upstreamAPI holds the methods of the client for one kind of upstream,
+// and the conversions of its servers to and from HTTP upstream servers, which have all the parameters.
+type upstreamAPI[S client.Server] struct {
+	list       func(ctx context.Context, upstream string) ([]S, error)
+	add        func(ctx context.Context, upstream string, server S) error
+	delete     func(ctx context.Context, upstream string, server string) error
+	deleteByID func(ctx context.Context, upstream string, id int) error
+	update     func(ctx context.Context, upstream string, server S) error
+	reconcile  func(ctx context.Context, upstream string, servers []S, opts ...client.ReconcileOption) (client.ReconcileResult[S], error)
+	toHTTP     func(server S) client.UpstreamServer
+	fromHTTP   func(server client.UpstreamServer) S
+	name       string
+	stream     bool
+}
+
+func httpUpstreams(c client.Client) upstreamAPI[client.UpstreamServer] {
+	return upstreamAPI[client.UpstreamServer]{
+		list:       c.GetHTTPServers,
+		add:        c.AddHTTPServer,
+		delete:     c.DeleteHTTPServer,
+		deleteByID: c.DeleteHTTPServerByID,
+		update:     c.UpdateHTTPServer,
+		reconcile:  c.ReconcileHTTPServers,
+		toHTTP:     func(s client.UpstreamServer) client.UpstreamServer { return s },
+		fromHTTP:   func(s client.UpstreamServer) client.UpstreamServer { return s },
+		name:       "upstream",
+	}
+}
+
+func streamUpstreams(c client.Client) upstreamAPI[client.StreamUpstreamServer] {
+	return upstreamAPI[client.StreamUpstreamServer]{
+		list:       c.GetStreamServers,
+		add:        c.AddStreamServer,
+		delete:     c.DeleteStreamServer,
+		deleteByID: c.DeleteStreamServerByID,
+		update:     c.UpdateStreamServer,
+		reconcile:  c.ReconcileStreamServers,
+		toHTTP: func(s client.StreamUpstreamServer) client.UpstreamServer {
+			return client.UpstreamServer{
+				MaxConns: s.MaxConns, MaxFails: s.MaxFails, Backup: s.Backup, Down: s.Down, Weight: s.Weight, Server: s.Server,
+				FailTimeout: s.FailTimeout, SlowStart: s.SlowStart, Service: s.Service, ID: s.ID,
+			}
+		},
+		fromHTTP: func(s client.UpstreamServer) client.StreamUpstreamServer {
+			return client.StreamUpstreamServer{
+				MaxConns: s.MaxConns, MaxFails: s.MaxFails, Backup: s.Backup, Down: s.Down, Weight: s.Weight, Server: s.Server,
+				FailTimeout: s.FailTimeout, SlowStart: s.SlowStart, Service: s.Service, ID: s.ID,
+			}
+		},
+		name:   "stream-upstream",
+		stream: true,
+	}
+}
+
+// change is the result of a change of a server or a key in JSON.
+type change struct {
+	Action   string `json:"action"`
+	Upstream string `json:"upstream,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+	Server   string `json:"server,omitempty"`
+	Key      string `json:"key,omitempty"`
+}
+
+func runUpstream[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: %v: missing subcommand", errUsage, api.name)
+	}
+	subcommand, args := args[0], args[1:]
+	name := api.name + " " + subcommand
+	switch subcommand {
+	case "list":
+		return listServers(ctx, c, api, name, args)
+	case "add":
+		return addServer(ctx, c, api, name, args)
+	case "remove":
+		return removeServer(ctx, c, api, name, args)
+	case "update":
+		return updateServer(ctx, c, api, name, args)
+	case "drain":
+		if api.stream {
+			return fmt.Errorf("%w: %v: stream servers can't be drained", errUsage, name)
+		}
+		return setServer(ctx, c, api, name, args, "drain", func(s *client.UpstreamServer) { s.Drain = true })
+	case "down":
+		return setServer(ctx, c, api, name, args, "down", func(s *client.UpstreamServer) { s.Down = ptr(true) })
+	case "up":
+		return setServer(ctx, c, api, name, args, "up", func(s *client.UpstreamServer) { s.Down = ptr(false) })
+	case "reconcile":
+		return reconcileServers(ctx, c, api, name, args)
+	default:
+		return fmt.Errorf("%w: %v: unknown subcommand", errUsage, name)
+	}
+}
+
+func listServers[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], name string, args []string) error {
+	fs := c.newFlagSet(name)
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	servers, err := api.list(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	header := []string{"ID", "SERVER", "WEIGHT", "MAX_CONNS", "MAX_FAILS", "FAIL_TIMEOUT", "SLOW_START", "BACKUP", "DOWN", "SERVICE"}
+	if !api.stream {
+		header = append(header, "ROUTE", "DRAIN")
+	}
+	rows := make([][]string, 0, len(servers))
+	for _, server := range servers {
+		s := api.toHTTP(server)
+		row := []string{
+			strconv.Itoa(s.ID), s.Server, cell(s.Weight), cell(s.MaxConns), cell(s.MaxFails), text(s.FailTimeout), text(s.SlowStart),
+			cell(s.Backup), cell(s.Down), text(s.Service),
+		}
+		if !api.stream {
+			row = append(row, text(s.Route), yesNo(s.Drain))
+		}
+		rows = append(rows, row)
+	}
+	if servers == nil {
+		servers = []S{}
+	}
+	return c.out.print(servers, header, rows)
+}
+
+func addServer[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], name string, args []string) error {
+	fs := c.newFlagSet(name)
+	var flags serverFlags
+	flags.register(fs, api.stream)
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	upstream := args[0]
+	server := client.UpstreamServer{Server: args[1]}
+	flags.apply(fs, &server)
+	if err := api.add(ctx, upstream, api.fromHTTP(server)); err != nil {
+		return err
+	}
+	return c.out.done(change{Action: "add", Upstream: upstream, Server: server.Server}, fmt.Sprintf("added %v to %v", server.Server, upstream))
+}
+
+func removeServer[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], name string, args []string) error {
+	fs := c.newFlagSet(name)
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	upstream, server := args[0], args[1]
+	if id, err := strconv.Atoi(server); err == nil {
+		err = api.deleteByID(ctx, upstream, id)
+	} else {
+		err = api.delete(ctx, upstream, server)
+	}
+	if err != nil {
+		return err
+	}
+	return c.out.done(change{Action: "remove", Upstream: upstream, Server: server}, fmt.Sprintf("removed %v from %v", server, upstream))
+}
+
+func updateServer[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], name string, args []string) error {
+	fs := c.newFlagSet(name)
+	var flags serverFlags
+	flags.register(fs, api.stream)
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	return changeServer(ctx, c, api, args[0], args[1], "update", func(s *client.UpstreamServer) { flags.apply(fs, s) })
+}
+
+func setServer[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], name string, args []string, action string, set func(*client.UpstreamServer)) error {
+	fs := c.newFlagSet(name)
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	return changeServer(ctx, c, api, args[0], args[1], action, set)
+}
+
+// changeServer finds the server of the upstream by its address or ID, changes its parameters and updates it.
+func changeServer[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], upstream, ref, action string, set func(*client.UpstreamServer)) error {
+	found, err := findServer(ctx, api, upstream, ref)
+	if err != nil {
+		return err
+	}
+	s := api.toHTTP(found)
+	set(&s)
+	if err := api.update(ctx, upstream, api.fromHTTP(s)); err != nil {
+		return err
+	}
+	return c.out.done(change{Action: action, Upstream: upstream, Server: s.Server}, fmt.Sprintf("%v %v of %v", past(action), s.Server, upstream))
+}
+
+// findServer returns the server of the upstream with the ID or the address.
+func findServer[S client.Server](ctx context.Context, api upstreamAPI[S], upstream, ref string) (S, error) {
+	servers, err := api.list(ctx, upstream)
+	if err != nil {
+		var zero S
+		return zero, err
+	}
+	id, err := strconv.Atoi(ref)
+	isID := err == nil
+	for _, server := range servers {
+		if (isID && server.ServerID() == id) || (!isID && addressKey(server.Address()) == addressKey(ref)) {
+			return server, nil
+		}
+	}
+	var zero S
+	return zero, fmt.Errorf("server %v of upstream %v: %w", ref, upstream, client.ErrServerNotFound)
+}
+
+// addressKey returns the same key for all spellings of an address, assuming port 80 if no port is set.
+func addressKey(address string) string {
+	a, err := client.ParseServerAddress(address)
+	if err != nil {
+		return address
+	}
+	return a.WithDefaultPort(80).String()
+}
+
+func past(action string) string {
+	switch action {
+	case "drain":
+		return "draining"
+	case "down":
+		return "marked down"
+	case "up":
+		return "marked up"
+	default:
+		return action + "d"
+	}
+}
+
+var orders = map[string]client.UpdateOrder{
+	"add-delete-update":       client.OrderAddDeleteUpdate,
+	"update-add-delete":       client.OrderUpdateAddDelete,
+	"add-wait-healthy-delete": client.OrderAddWaitHealthyDelete,
+}
+
+// outcome is the outcome of the change of a server by reconcile in JSON.
+type outcome struct {
+	Change string `json:"change"`
+	Server string `json:"server"`
+	Error  string `json:"error,omitempty"`
+}
+
+func reconcileServers[S client.Server](ctx context.Context, c *cli, api upstreamAPI[S], name string, args []string) error {
+	fs := c.newFlagSet(name)
+	dryRun := fs.Bool("dry-run", false, "only print the changes")
+	order := fs.String("order", "add-delete-update", "order of the changes: add-delete-update, update-add-delete or add-wait-healthy-delete")
+	parallelism := fs.Int("parallelism", 1, "number of changes made at the same time")
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	updateOrder, ok := orders[*order]
+	if !ok {
+		return fmt.Errorf("%w: %v: unknown order %q", errUsage, name, *order)
+	}
+	upstream := args[0]
+
+	f, err := c.open(args[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	var servers []S
+	if err := decoder.Decode(&servers); err != nil {
+		return fmt.Errorf("failed to read the servers from %v: %w", args[1], err)
+	}
+
+	var outcomes []outcome
+	var reconcileErr error
+	if *dryRun {
+		actual, err := api.list(ctx, upstream)
+		if err != nil {
+			return err
+		}
+		diff := client.DiffServers(servers, actual)
+		for _, group := range []struct {
+			change  client.ServerChange
+			servers []S
+		}{{client.ServerAdded, diff.Added}, {client.ServerDeleted, diff.Deleted}, {client.ServerUpdated, diff.Updated}} {
+			for _, s := range group.servers {
+				outcomes = append(outcomes, outcome{Change: string(group.change), Server: s.Address()})
+			}
+		}
+	} else {
+		var result client.ReconcileResult[S]
+		result, reconcileErr = api.reconcile(ctx, upstream, servers, client.WithUpdateOrder(updateOrder), client.WithParallelism(*parallelism))
+		for _, o := range result.Outcomes {
+			out := outcome{Change: string(o.Change), Server: o.Server.Address()}
+			if o.Err != nil {
+				out.Error = o.Err.Error()
+			}
+			outcomes = append(outcomes, out)
+		}
+	}
+
+	if outcomes == nil {
+		outcomes = []outcome{}
+	}
+	rows := make([][]string, 0, len(outcomes))
+	for _, o := range outcomes {
+		rows = append(rows, []string{o.Change, o.Server, text(o.Error)})
+	}
+	if err := c.out.print(outcomes, []string{"CHANGE", "SERVER", "ERROR"}, rows); err != nil {
+		return err
+	}
+	return reconcileErr
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// serverFlags are the flags of the parameters of a server.
+type serverFlags struct {
+	route       string
+	failTimeout string
+	slowStart   string
+	service     string
+	weight      int
+	maxConns    int
+	maxFails    int
+	backup      bool
+	down        bool
+	drain       bool
+}
+
+func (f *serverFlags) register(fs *flag.FlagSet, stream bool) {
+	fs.IntVar(&f.weight, "weight", 1, "weight of the server")
+	fs.IntVar(&f.maxConns, "max-conns", 0, "maximum number of connections to the server, 0 for no limit")
+	fs.IntVar(&f.maxFails, "max-fails", 1, "number of failed attempts after which the server is unavailable")
+	fs.StringVar(&f.failTimeout, "fail-timeout", "", "time during which max-fails attempts must fail, and for which the server is then unavailable")
+	fs.StringVar(&f.slowStart, "slow-start", "", "time during which the weight of a recovered server grows")
+	fs.StringVar(&f.service, "service", "", "service name of the SRV records of the server")
+	fs.BoolVar(&f.backup, "backup", false, "mark the server as a backup server")
+	fs.BoolVar(&f.down, "down", false, "mark the server as down")
+	if !stream {
+		fs.StringVar(&f.route, "route", "", "route of the server")
+		fs.BoolVar(&f.drain, "drain", false, "drain the server")
+	}
+}
+
+// apply sets the parameters of the flags that were set on the command line.
+func (f *serverFlags) apply(fs *flag.FlagSet, s *client.UpstreamServer) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "weight":
+			s.Weight = ptr(f.weight)
+		case "max-conns":
+			s.MaxConns = ptr(f.maxConns)
+		case "max-fails":
+			s.MaxFails = ptr(f.maxFails)
+		case "fail-timeout":
+			s.FailTimeout = f.failTimeout
+		case "slow-start":
+			s.SlowStart = f.slowStart
+		case "service":
+			s.Service = f.service
+		case "backup":
+			s.Backup = ptr(f.backup)
+		case "down":
+			s.Down = ptr(f.down)
+		case "route":
+			s.Route = f.route
+		case "drain":
+			s.Drain = f.drain
+		}
+	})
+}
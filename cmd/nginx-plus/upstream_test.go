@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nginx/nginx-plus-go-client/v3/client"
+	"github.com/nginx/nginx-plus-go-client/v3/client/clientmock"
+)
+
+func newUpstreamMock(servers []client.UpstreamServer) *clientmock.Client {
+	mock := &clientmock.Client{}
+	mock.GetHTTPServersFunc = func(context.Context, string) ([]client.UpstreamServer, error) {
+		return servers, nil
+	}
+	mock.AddHTTPServerFunc = func(context.Context, string, client.UpstreamServer) error { return nil }
+	mock.DeleteHTTPServerFunc = func(context.Context, string, string) error { return nil }
+	mock.DeleteHTTPServerByIDFunc = func(context.Context, string, int) error { return nil }
+	mock.UpdateHTTPServerFunc = func(context.Context, string, client.UpstreamServer) error { return nil }
+	return mock
+}
+
+func TestUpstreamList(t *testing.T) {
+	t.Parallel()
+	weight := 2
+	mock := newUpstreamMock([]client.UpstreamServer{{ID: 1, Server: "10.0.0.1:80", Weight: &weight}, {ID: 2, Server: "10.0.0.2:80", Drain: true}})
+
+	stdout, stderr, code := runCLI(t, mock, "", "upstream", "list", "backend")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[2], "true") {
+		t.Fatalf("unexpected table\n%v", stdout)
+	}
+
+	stdout, _, _ = runCLI(t, mock, "", "-o", "json", "upstream", "list", "backend")
+	var servers []client.UpstreamServer
+	if err := json.Unmarshal([]byte(stdout), &servers); err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 2 || *servers[0].Weight != 2 {
+		t.Fatalf("unexpected servers %+v", servers)
+	}
+
+	mock.GetStreamServersFunc = func(context.Context, string) ([]client.StreamUpstreamServer, error) {
+		return nil, nil
+	}
+	stdout, _, _ = runCLI(t, mock, "", "-o", "json", "stream-upstream", "list", "dns")
+	if strings.TrimSpace(stdout) != "[]" {
+		t.Fatalf("expected an empty list, got %v", stdout)
+	}
+}
+
+func TestUpstreamChanges(t *testing.T) {
+	t.Parallel()
+	weight := 3
+	tests := []struct {
+		name     string
+		args     []string
+		expected client.UpstreamServer
+	}{
+		{
+			name:     "add",
+			args:     []string{"upstream", "add", "backend", "10.0.0.3:80", "-weight", "3", "-route", "a"},
+			expected: client.UpstreamServer{Server: "10.0.0.3:80", Weight: &weight, Route: "a"},
+		},
+		{
+			name:     "update by address",
+			args:     []string{"upstream", "update", "-weight", "3", "backend", "10.0.0.1"},
+			expected: client.UpstreamServer{ID: 1, Server: "10.0.0.1:80", Weight: &weight, SlowStart: "10s"},
+		},
+		{
+			name:     "drain by ID",
+			args:     []string{"upstream", "drain", "backend", "2"},
+			expected: client.UpstreamServer{ID: 2, Server: "10.0.0.2:80", Drain: true},
+		},
+		{
+			name:     "down",
+			args:     []string{"upstream", "down", "backend", "10.0.0.2:80"},
+			expected: client.UpstreamServer{ID: 2, Server: "10.0.0.2:80", Down: ptr(true)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			mock := newUpstreamMock([]client.UpstreamServer{{ID: 1, Server: "10.0.0.1:80", SlowStart: "10s"}, {ID: 2, Server: "10.0.0.2:80"}})
+			_, stderr, code := runCLI(t, mock, "", test.args...)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %v: %v", code, stderr)
+			}
+			calls := append(mock.UpstreamManager.CallsOf("AddHTTPServer"), mock.UpstreamManager.CallsOf("UpdateHTTPServer")...)
+			if len(calls) != 1 {
+				t.Fatalf("expected one change, got %+v", calls)
+			}
+			if server := calls[0].Args[1]; !reflect.DeepEqual(server, test.expected) {
+				t.Fatalf("expected server %+v, got %+v", test.expected, server)
+			}
+		})
+	}
+}
+
+func TestUpstreamRemove(t *testing.T) {
+	t.Parallel()
+	mock := newUpstreamMock(nil)
+	if _, stderr, code := runCLI(t, mock, "", "upstream", "remove", "backend", "10.0.0.1:80"); code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	if _, stderr, code := runCLI(t, mock, "", "upstream", "remove", "backend", "7"); code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	if len(mock.UpstreamManager.CallsOf("DeleteHTTPServer")) != 1 || len(mock.UpstreamManager.CallsOf("DeleteHTTPServerByID")) != 1 {
+		t.Fatalf("unexpected calls %+v", mock.UpstreamManager.Calls())
+	}
+
+	_, stderr, code := runCLI(t, mock, "", "upstream", "update", "backend", "10.0.0.9:80", "-weight", "2")
+	if code != 1 || !strings.Contains(stderr, client.ErrServerNotFound.Error()) {
+		t.Fatalf("expected the server not to be found, got exit code %v: %v", code, stderr)
+	}
+	if _, _, code := runCLI(t, mock, "", "stream-upstream", "drain", "dns", "1"); code != 2 {
+		t.Fatalf("expected stream servers not to be drained, got exit code %v", code)
+	}
+}
+
+func TestUpstreamReconcile(t *testing.T) {
+	t.Parallel()
+	mock := newUpstreamMock([]client.UpstreamServer{{ID: 1, Server: "10.0.0.1:80"}, {ID: 2, Server: "10.0.0.2:80"}})
+	var order []client.ReconcileOption
+	mock.ReconcileHTTPServersFunc = func(_ context.Context, _ string, servers []client.UpstreamServer, opts ...client.ReconcileOption) (client.ReconcileResult[client.UpstreamServer], error) {
+		order = opts
+		return client.ReconcileResult[client.UpstreamServer]{Outcomes: []client.ServerOutcome[client.UpstreamServer]{
+			{Server: servers[0], Change: client.ServerAdded},
+			{Server: client.UpstreamServer{Server: "10.0.0.2:80"}, Change: client.ServerDeleted, Err: errFailed},
+		}}, errFailed
+	}
+	input := `[{"server": "10.0.0.1:80"}, {"server": "10.0.0.3:80"}]`
+
+	stdout, stderr, code := runCLI(t, mock, input, "upstream", "reconcile", "-dry-run", "backend", "-")
+	if code != 0 {
+		t.Fatalf("unexpected exit code %v: %v", code, stderr)
+	}
+	if !strings.Contains(stdout, "add     10.0.0.3:80") || !strings.Contains(stdout, "delete  10.0.0.2:80") {
+		t.Fatalf("unexpected plan\n%v", stdout)
+	}
+	if len(mock.UpstreamManager.CallsOf("ReconcileHTTPServers")) != 0 {
+		t.Fatal("expected a dry run not to reconcile")
+	}
+
+	stdout, stderr, code = runCLI(t, mock, input, "-o", "json", "upstream", "reconcile", "backend", "-", "-order", "add-wait-healthy-delete")
+	if code != 1 || !strings.Contains(stderr, errFailed.Error()) {
+		t.Fatalf("expected the error, got exit code %v: %v", code, stderr)
+	}
+	if len(order) != 2 {
+		t.Fatalf("expected the order and parallelism options, got %v", len(order))
+	}
+	var outcomes []outcome
+	if err := json.Unmarshal([]byte(stdout), &outcomes); err != nil {
+		t.Fatal(err)
+	}
+	expected := []outcome{{Change: "add", Server: "10.0.0.1:80"}, {Change: "delete", Server: "10.0.0.2:80", Error: errFailed.Error()}}
+	if !reflect.DeepEqual(outcomes, expected) {
+		t.Fatalf("expected outcomes %+v, got %+v", expected, outcomes)
+	}
+
+	_, _, code = runCLI(t, mock, `[{"server": "10.0.0.1:80", "wieght": 2}]`, "upstream", "reconcile", "backend", "-")
+	if code != 1 {
+		t.Fatalf("expected unknown fields to fail, got exit code %v", code)
+	}
+	if _, _, code := runCLI(t, mock, input, "upstream", "reconcile", "backend", "-", "-order", "random"); code != 2 {
+		t.Fatalf("expected an unknown order to be a usage error, got exit code %v", code)
+	}
+}
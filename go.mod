@@ -2,4 +2,9 @@ module github.com/nginx/nginx-plus-go-client/v3
 
 go 1.25.0
 
-require golang.org/x/sync v0.22.0
+require (
+	golang.org/x/sync v0.22.0
+	golang.org/x/term v0.45.0
+)
+
+require golang.org/x/sys v0.47.0 // indirect